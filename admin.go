@@ -0,0 +1,96 @@
+package session
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+// adminSession is the session info in admin handler
+type adminSession struct {
+	ID         string    `json:"id"`
+	CreatedAt  time.Time `json:"createdAt"`
+	LastSeenAt time.Time `json:"lastSeenAt"`
+	IP         string    `json:"ip"`
+	UserAgent  string    `json:"userAgent"`
+}
+
+// AdminHandler returns handler to manage user's sessions in user's session index,
+// GET ?user=id lists user's sessions as json,
+// DELETE ?user=id&session=id revokes the session
+//
+// AdminHandler does not check permission, application must protect the handler
+func (m *Manager) AdminHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userID := r.FormValue("user")
+		if userID == "" {
+			http.Error(w, "Bad Request", http.StatusBadRequest)
+			return
+		}
+
+		switch r.Method {
+		case http.MethodGet:
+			m.adminList(w, r, userID)
+		case http.MethodDelete:
+			m.adminRevoke(w, r, userID)
+		default:
+			w.Header().Set("Allow", "GET, DELETE")
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		}
+	})
+}
+
+func (m *Manager) adminList(w http.ResponseWriter, r *http.Request, userID string) {
+	ctx := r.Context()
+
+	keys, err := userSessions(ctx, m.store, userID)
+	if err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	list := make([]adminSession, 0, len(keys))
+	for _, k := range keys {
+		data, err := m.config.Store.Get(ctx, k)
+		if err == ErrNotFound {
+			continue
+		}
+		if err != nil {
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+		meta := dataMeta(data)
+		list = append(list, adminSession{
+			ID:         k,
+			CreatedAt:  meta.CreatedAt,
+			LastSeenAt: meta.LastSeenAt,
+			IP:         meta.IP,
+			UserAgent:  meta.UserAgent,
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	json.NewEncoder(w).Encode(list)
+}
+
+func (m *Manager) adminRevoke(w http.ResponseWriter, r *http.Request, userID string) {
+	ctx := r.Context()
+
+	key := r.FormValue("session")
+	ok, err := removeUserSession(ctx, m.store, userID, key, m.config.MaxAge)
+	if err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	err = delUserSession(ctx, m.config.Store, key)
+	if err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,78 @@
+package session_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/moonrhythm/session"
+	"github.com/moonrhythm/session/store"
+)
+
+func TestAdminHandler(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	st := new(store.Memory)
+	m := session.New(session.Config{
+		Store:          st,
+		EncryptionKeys: [][]byte{[]byte("0123456789abcdef")},
+	})
+
+	login := func(user string) *session.Session {
+		s, _ := m.Get(httptest.NewRequest(http.MethodGet, "/", nil), sessName)
+		s.Set("user", user)
+		assert.NoError(t, m.Save(ctx, httptest.NewRecorder(), s))
+		assert.NoError(t, session.AddUserSession(ctx, st, user, s))
+		return s
+	}
+	s1 := login("u1")
+	s2 := login("u1")
+	s3 := login("u2")
+
+	h := m.AdminHandler()
+
+	list := func(user string) []map[string]interface{} {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/?user="+user, nil))
+		assert.Equal(t, http.StatusOK, w.Code)
+		var r []map[string]interface{}
+		assert.NoError(t, json.NewDecoder(w.Body).Decode(&r))
+		return r
+	}
+
+	r := list("u1")
+	if assert.Len(t, r, 2) {
+		assert.Equal(t, s1.ID(), r[0]["id"])
+		assert.Equal(t, s2.ID(), r[1]["id"])
+		assert.NotEmpty(t, r[0]["createdAt"])
+		assert.NotEmpty(t, r[0]["lastSeenAt"])
+	}
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/?user=u1&session="+s3.ID(), nil))
+	assert.Equal(t, http.StatusNotFound, w.Code, "expected can not revoke other user's session")
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/?user=u1&session="+s1.ID(), nil))
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	_, err := st.Get(ctx, s1.ID())
+	assert.Equal(t, session.ErrNotFound, err)
+
+	r = list("u1")
+	if assert.Len(t, r, 1) {
+		assert.Equal(t, s2.ID(), r[0]["id"])
+	}
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodPut, "/?user=u1", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+}
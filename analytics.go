@@ -0,0 +1,67 @@
+package session
+
+import (
+	"net/http"
+	"time"
+)
+
+// AnalyticsID returns the anonymous analytics id,
+// analytics id is stable across session rotation and never used as store key
+//
+// returns empty string if Config.AnalyticsCookie is not set
+func (s *Session) AnalyticsID() string {
+	return s.analyticsID
+}
+
+// RotateAnalyticsID issues a new analytics id,
+// e.g. when user opts out of tracking
+func (s *Session) RotateAnalyticsID() {
+	if s.analyticsGen == nil {
+		return
+	}
+	s.analyticsID = s.analyticsGen()
+	s.analyticsChanged = true
+}
+
+func (m *Manager) loadAnalyticsID(r *http.Request, s *Session) {
+	if m.config.AnalyticsCookie == "" {
+		return
+	}
+
+	s.analyticsGen = m.config.GenerateID
+	if cookie, err := r.Cookie(m.analyticsCookieName(s)); err == nil && len(cookie.Value) > 0 {
+		s.analyticsID = cookie.Value
+		return
+	}
+	s.RotateAnalyticsID()
+}
+
+// analyticsCookieName returns analytics cookie name namespaced by session name,
+// so stacked middlewares with the same config do not overwrite each other
+func (m *Manager) analyticsCookieName(s *Session) string {
+	return m.cookieName(m.config.AnalyticsCookie + "_" + s.Name)
+}
+
+func (m *Manager) setAnalyticsCookie(w http.ResponseWriter, s *Session) {
+	if !s.analyticsChanged {
+		return
+	}
+	s.analyticsChanged = false
+
+	cs := http.Cookie{
+		Name:     m.analyticsCookieName(s),
+		Domain:   s.Domain,
+		Path:     s.Path,
+		HttpOnly: s.HTTPOnly,
+		Value:    s.analyticsID,
+		Secure:   s.Secure,
+		SameSite: s.SameSite,
+	}
+	if maxAge := m.config.AnalyticsMaxAge; maxAge > 0 {
+		cs.MaxAge = int(maxAge / time.Second)
+		cs.Expires = time.Now().Add(maxAge)
+	}
+	m.config.CookiePrefix.apply(&cs)
+
+	addCookie(w, &cs, s.Partitioned)
+}
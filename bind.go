@@ -0,0 +1,109 @@
+package session
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"net"
+	"strings"
+)
+
+// BindIP config
+type BindIP int
+
+// BindIP values
+const (
+	NoBindIP     BindIP = iota
+	BindIPExact         // client ip address must be the same
+	BindIPSubnet        // client ip address must be in the same /24 (IPv4) or /64 (IPv6) subnet
+)
+
+// bind records client ip address and user agent fingerprint to session data if not bound
+func (m *Manager) bind(s *Session) {
+	if m.config.BindIP != NoBindIP && s.ip != "" {
+		if _, ok := s.data[boundIPKey]; !ok {
+			s.Set(boundIPKey, s.ip)
+		}
+	}
+	if m.config.BindUserAgent {
+		if _, ok := s.data[boundUAKey]; !ok {
+			s.Set(boundUAKey, m.userAgentFingerprint(s.userAgent))
+		}
+	}
+	if m.config.Fingerprint != nil {
+		if _, ok := s.data[boundFPKey]; !ok {
+			s.Set(boundFPKey, s.fingerprint)
+		}
+	}
+}
+
+// bound checks is client match the bound client in session data,
+// session without bound client always match
+func (m *Manager) bound(s *Session, data Data) bool {
+	if !m.config.BindIP.match(data, s.ip) {
+		return false
+	}
+	if m.config.BindUserAgent {
+		if fp, ok := data[boundUAKey].(string); ok && fp != m.userAgentFingerprint(s.userAgent) {
+			return false
+		}
+	}
+	if m.config.Fingerprint != nil {
+		if fp, ok := data[boundFPKey].(string); ok && fp != s.fingerprint {
+			return false
+		}
+	}
+	return true
+}
+
+// match checks is client ip address match the bound ip address in session data
+func (b BindIP) match(data Data, ip string) bool {
+	if b == NoBindIP {
+		return true
+	}
+	bound, _ := data[boundIPKey].(string)
+	if bound == "" {
+		return true
+	}
+	if bound == ip {
+		return true
+	}
+	if b != BindIPSubnet {
+		return false
+	}
+
+	x, y := net.ParseIP(bound), net.ParseIP(ip)
+	if x == nil || y == nil {
+		return false
+	}
+	mask := net.CIDRMask(64, 128)
+	if x4, y4 := x.To4(), y.To4(); x4 != nil || y4 != nil {
+		x, y = x4, y4
+		mask = net.CIDRMask(24, 32)
+	}
+	if x == nil || y == nil {
+		return false
+	}
+	return x.Mask(mask).Equal(y.Mask(mask))
+}
+
+// userAgentFingerprint returns hash of normalized user agent
+func (m *Manager) userAgentFingerprint(ua string) string {
+	if m.config.NormalizeUserAgent != nil {
+		ua = m.config.NormalizeUserAgent(ua)
+	} else {
+		ua = normalizeUserAgent(ua)
+	}
+	h := sha256.Sum256([]byte(ua))
+	return base64.RawURLEncoding.EncodeToString(h[:16])
+}
+
+// normalizeUserAgent removes version numbers from user agent,
+// e.g. "Mozilla/5.0 Chrome/120.0.1" => "Mozilla/ Chrome/"
+func normalizeUserAgent(ua string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= '0' && r <= '9' || r == '.' || r == '_' {
+			return -1
+		}
+		return r
+	}, ua)
+}
@@ -0,0 +1,129 @@
+package session_test
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/moonrhythm/session"
+	"github.com/moonrhythm/session/store"
+)
+
+func TestBindIP(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		Mode    session.BindIP
+		Created string
+		Next    string
+		Valid   bool
+	}{
+		{session.NoBindIP, "10.0.0.1", "10.0.1.1", true},
+		{session.BindIPExact, "10.0.0.1", "10.0.0.1", true},
+		{session.BindIPExact, "10.0.0.1", "10.0.0.2", false},
+		{session.BindIPSubnet, "10.0.0.1", "10.0.0.2", true},
+		{session.BindIPSubnet, "10.0.0.1", "10.0.1.1", false},
+		{session.BindIPSubnet, "2001:db8::1", "2001:db8::ffff", true},
+		{session.BindIPSubnet, "2001:db8::1", "2001:db8:0:1::1", false},
+		{session.BindIPSubnet, "10.0.0.1", "2001:db8::1", false},
+	}
+
+	for _, c := range cases {
+		m := session.New(session.Config{
+			Store:  new(store.Memory),
+			BindIP: c.Mode,
+		})
+
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.RemoteAddr = net.JoinHostPort(c.Created, "1234")
+		s, _ := m.Get(r, sessName)
+		s.Set("a", 1)
+		w := httptest.NewRecorder()
+		assert.NoError(t, m.Save(r.Context(), w, s))
+
+		r = httptest.NewRequest(http.MethodGet, "/", nil)
+		r.RemoteAddr = net.JoinHostPort(c.Next, "1234")
+		r.Header.Set("Cookie", w.Result().Cookies()[0].String())
+		s, _ = m.Get(r, sessName)
+		assert.Equal(t, c.Valid, !s.IsNew(), "%v %s => %s", c.Mode, c.Created, c.Next)
+		if !c.Valid {
+			assert.Nil(t, s.Get("a"))
+		}
+	}
+}
+
+func TestBindUserAgent(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		Normalize func(string) string
+		Created   string
+		Next      string
+		Valid     bool
+	}{
+		{nil, "Mozilla/5.0 Chrome/120.0.1", "Mozilla/5.0 Chrome/120.0.1", true},
+		{nil, "Mozilla/5.0 Chrome/120.0.1", "Mozilla/5.0 Chrome/121.0.3", true},
+		{nil, "Mozilla/5.0 Chrome/120.0.1", "Mozilla/5.0 Firefox/120.0", false},
+		{nil, "Mozilla/5.0 Chrome/120.0.1", "", false},
+		{strings.ToLower, "Mozilla/5.0", "mozilla/5.0", true},
+		{strings.ToLower, "Mozilla/5.0", "mozilla/5.1", false},
+	}
+
+	for _, c := range cases {
+		m := session.New(session.Config{
+			Store:              new(store.Memory),
+			BindUserAgent:      true,
+			NormalizeUserAgent: c.Normalize,
+		})
+
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Set("User-Agent", c.Created)
+		s, _ := m.Get(r, sessName)
+		s.Set("a", 1)
+		w := httptest.NewRecorder()
+		assert.NoError(t, m.Save(r.Context(), w, s))
+
+		r = httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Set("User-Agent", c.Next)
+		r.Header.Set("Cookie", w.Result().Cookies()[0].String())
+		s, _ = m.Get(r, sessName)
+		assert.Equal(t, c.Valid, !s.IsNew(), "%s => %s", c.Created, c.Next)
+	}
+}
+
+func TestFingerprint(t *testing.T) {
+	t.Parallel()
+
+	m := session.New(session.Config{
+		Store: new(store.Memory),
+		Fingerprint: func(r *http.Request) string {
+			return r.Header.Get("X-Device-ID")
+		},
+	})
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("X-Device-ID", "device1")
+	s, _ := m.Get(r, sessName)
+	s.Set("a", 1)
+	w := httptest.NewRecorder()
+	assert.NoError(t, m.Save(r.Context(), w, s))
+	cookie := w.Result().Cookies()[0].String()
+
+	r = httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("X-Device-ID", "device1")
+	r.Header.Set("Cookie", cookie)
+	s, _ = m.Get(r, sessName)
+	assert.False(t, s.IsNew())
+	assert.Equal(t, 1, s.Get("a"))
+
+	r = httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("X-Device-ID", "device2")
+	r.Header.Set("Cookie", cookie)
+	s, _ = m.Get(r, sessName)
+	assert.True(t, s.IsNew(), "expected session with different fingerprint rejected")
+	assert.Nil(t, s.Get("a"))
+}
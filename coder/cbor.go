@@ -0,0 +1,29 @@
+package coder
+
+import (
+	"io"
+
+	"github.com/fxamacker/cbor/v2"
+
+	"github.com/moonrhythm/session"
+)
+
+// cborEncMode encodes map keys in canonical order,
+// so the same data always encodes to the same bytes
+var cborEncMode, _ = cbor.EncOptions{Sort: cbor.SortCanonical}.EncMode()
+
+// CBOR encodes session data using CBOR (RFC 8949)
+//
+// integers decode as int64 or uint64, and nested maps decode as
+// map[interface{}]interface{}
+type CBOR struct{}
+
+// NewEncoder implements session.StoreCoder
+func (CBOR) NewEncoder(w io.Writer) session.StoreEncoder {
+	return cborEncMode.NewEncoder(w)
+}
+
+// NewDecoder implements session.StoreCoder
+func (CBOR) NewDecoder(r io.Reader) session.StoreDecoder {
+	return cbor.NewDecoder(r)
+}
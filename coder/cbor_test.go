@@ -0,0 +1,48 @@
+package coder
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/moonrhythm/session"
+)
+
+func TestCBOR(t *testing.T) {
+	t.Parallel()
+
+	var c session.StoreCoder = CBOR{}
+
+	var buf bytes.Buffer
+	err := c.NewEncoder(&buf).Encode(session.Data{
+		"str":   "value",
+		"int":   1,
+		"neg":   -2,
+		"bool":  true,
+		"bytes": []byte("abc"),
+		"list":  []interface{}{"a", "b"},
+	})
+	assert.NoError(t, err)
+
+	var d session.Data
+	err = c.NewDecoder(&buf).Decode(&d)
+	assert.NoError(t, err)
+	assert.Equal(t, "value", d["str"])
+	assert.EqualValues(t, 1, d["int"])
+	assert.EqualValues(t, -2, d["neg"])
+	assert.Equal(t, true, d["bool"])
+	assert.Equal(t, []byte("abc"), d["bytes"])
+	assert.Equal(t, []interface{}{"a", "b"}, d["list"])
+}
+
+func TestCBORDeterministic(t *testing.T) {
+	t.Parallel()
+
+	data := session.Data{"a": 1, "bb": 2, "c": 3, "dd": 4}
+
+	var b1, b2 bytes.Buffer
+	assert.NoError(t, CBOR{}.NewEncoder(&b1).Encode(data))
+	assert.NoError(t, CBOR{}.NewEncoder(&b2).Encode(data))
+	assert.Equal(t, b1.Bytes(), b2.Bytes())
+}
@@ -0,0 +1,2 @@
+// Package coder contains store coder implementations
+package coder
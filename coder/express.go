@@ -0,0 +1,106 @@
+package coder
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/moonrhythm/session"
+	"github.com/moonrhythm/session/internal/interop"
+)
+
+// ConnectRedisPrefix is the redis key prefix of connect-redis,
+// use as redis store prefix to share session with express-session
+const ConnectRedisPrefix = "sess:"
+
+// expressCookieKey is the key of express-session cookie data,
+// express-session requires it in stored session
+const expressCookieKey = "cookie"
+
+// Express encodes session data as JSON object in express-session (connect-redis) payload shape,
+// to share session with Node application
+//
+// "cookie" object is added if session data does not have it,
+// integers decode as int64, floats decode as float64,
+// and objects decode as map[string]interface{},
+// reserved session data (e.g. flash and quota key order) keeps its type
+//
+// To share session with express-session, disable hash id, and use redis store with ConnectRedisPrefix, e.g.
+//
+//	session.Config{
+//		Name:          "connect.sid",
+//		Store:         &store.GoRedis{Client: client, Prefix: coder.ConnectRedisPrefix, Coder: coder.Express{}},
+//		DisableHashID: true,
+//	}
+//
+// express-session signs cookie value as "s:" + id + "." + signature,
+// use Config.IDExtractor and Config.IDWriter to read and write signed cookie
+type Express struct{}
+
+// NewEncoder implements session.StoreCoder
+func (Express) NewEncoder(w io.Writer) session.StoreEncoder {
+	return &expressEncoder{w}
+}
+
+// NewDecoder implements session.StoreCoder
+func (Express) NewDecoder(r io.Reader) session.StoreDecoder {
+	return &expressDecoder{r}
+}
+
+type expressEncoder struct {
+	w io.Writer
+}
+
+func (enc *expressEncoder) Encode(e interface{}) error {
+	var data map[string]interface{}
+	switch e := e.(type) {
+	case session.Data:
+		data = e
+	case *session.Data:
+		data = *e
+	case map[string]interface{}:
+		data = e
+	default:
+		return fmt.Errorf("coder/express: unsupported type %T", e)
+	}
+
+	if _, ok := data[expressCookieKey]; !ok {
+		// copy to not modify session data
+		m := make(map[string]interface{}, len(data)+1)
+		for k, v := range data {
+			m[k] = v
+		}
+		m[expressCookieKey] = map[string]interface{}{
+			"originalMaxAge": nil,
+			"expires":        nil,
+			"httpOnly":       true,
+			"path":           "/",
+		}
+		data = m
+	}
+	return json.NewEncoder(enc.w).Encode(data)
+}
+
+type expressDecoder struct {
+	r io.Reader
+}
+
+func (dec *expressDecoder) Decode(e interface{}) error {
+	p, ok := e.(*session.Data)
+	if !ok {
+		return fmt.Errorf("coder/express: unsupported type %T", e)
+	}
+
+	data, err := interop.DecodeJSON(dec.r)
+	if err != nil {
+		return err
+	}
+	// encoding/json encodes []byte as base64 string
+	err = restoreTypes(data, base64.StdEncoding.DecodeString)
+	if err != nil {
+		return err
+	}
+	*p = data
+	return nil
+}
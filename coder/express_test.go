@@ -0,0 +1,61 @@
+package coder
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/moonrhythm/session"
+)
+
+func TestExpress(t *testing.T) {
+	t.Parallel()
+
+	var c session.StoreCoder = Express{}
+
+	data := session.Data{
+		"user":  "alice",
+		"count": 1,
+		"ratio": 0.5,
+		"list":  []interface{}{"a", 2},
+	}
+	var buf bytes.Buffer
+	assert.NoError(t, c.NewEncoder(&buf).Encode(data))
+	assert.NotContains(t, data, "cookie", "expected session data not modified")
+
+	var m map[string]interface{}
+	assert.NoError(t, json.Unmarshal(buf.Bytes(), &m))
+	assert.Equal(t, map[string]interface{}{
+		"originalMaxAge": nil,
+		"expires":        nil,
+		"httpOnly":       true,
+		"path":           "/",
+	}, m["cookie"], "expected express-session cookie added")
+
+	var d session.Data
+	assert.NoError(t, c.NewDecoder(&buf).Decode(&d))
+	assert.Equal(t, "alice", d["user"])
+	assert.Equal(t, int64(1), d["count"])
+	assert.Equal(t, 0.5, d["ratio"])
+	assert.Equal(t, []interface{}{"a", int64(2)}, d["list"])
+}
+
+func TestExpressDecode(t *testing.T) {
+	t.Parallel()
+
+	// written by connect-redis
+	payload := `{"cookie":{"originalMaxAge":86400000,"expires":"2030-01-01T00:00:00.000Z","secure":false,"httpOnly":true,"path":"/"},"userId":42}`
+
+	var d session.Data
+	assert.NoError(t, Express{}.NewDecoder(strings.NewReader(payload)).Decode(&d))
+	assert.Equal(t, int64(42), d["userId"])
+
+	var buf bytes.Buffer
+	assert.NoError(t, Express{}.NewEncoder(&buf).Encode(d))
+	assert.JSONEq(t, payload, buf.String(), "expected cookie kept")
+
+	assert.Error(t, Express{}.NewDecoder(strings.NewReader("invalid")).Decode(&d))
+}
@@ -0,0 +1,448 @@
+package coder
+
+import (
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+
+	"github.com/moonrhythm/session"
+)
+
+// PHP session serialize handlers
+const (
+	PHPHandler          = "php"           // key|value key|value ...
+	PHPSerializeHandler = "php_serialize" // serialized array
+)
+
+// PHPRedisPrefix is the redis key prefix of phpredis session handler,
+// use as redis store prefix to share session with PHP
+const PHPRedisPrefix = "PHPREDIS_SESSION:"
+
+// PHP encodes session data using PHP session serialization format,
+// to share session with PHP application
+//
+// integers decode as int64, floats decode as float64, arrays with keys 0..n-1 decode as
+// []interface{} and other arrays decode as map[string]interface{},
+// reserved session data (e.g. flash and quota key order) keeps its type,
+// objects and references are not supported
+//
+// To share session with PHP, disable hash id, set cookie name to PHP session.name,
+// and generate id using PHPSessionID, e.g.
+//
+//	session.Config{
+//		Name:          "PHPSESSID",
+//		Store:         &store.GoRedis{Client: client, Prefix: coder.PHPRedisPrefix, Coder: coder.PHP{}},
+//		DisableHashID: true,
+//		GenerateID:    coder.PHPSessionID,
+//	}
+type PHP struct {
+	// Handler is the PHP session.serialize_handler, default is PHPHandler
+	Handler string
+}
+
+// NewEncoder implements session.StoreCoder
+func (c PHP) NewEncoder(w io.Writer) session.StoreEncoder {
+	return &phpEncoder{w, c.Handler}
+}
+
+// NewDecoder implements session.StoreCoder
+func (c PHP) NewDecoder(r io.Reader) session.StoreDecoder {
+	return &phpDecoder{r, c.Handler}
+}
+
+// PHPSessionID generates session id in PHP default format,
+// 32 characters of 0-9 and a-v (session.sid_bits_per_character = 5)
+func PHPSessionID() string {
+	const chars = "0123456789abcdefghijklmnopqrstuv"
+
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		panic("coder/php: can not generate id; " + err.Error())
+	}
+	for i := range b {
+		b[i] = chars[b[i]&31]
+	}
+	return string(b)
+}
+
+var errPHPInvalid = errors.New("coder/php: invalid payload")
+
+type phpEncoder struct {
+	w       io.Writer
+	handler string
+}
+
+func (enc *phpEncoder) Encode(e interface{}) error {
+	var data map[string]interface{}
+	switch e := e.(type) {
+	case session.Data:
+		data = e
+	case *session.Data:
+		data = *e
+	case map[string]interface{}:
+		data = e
+	default:
+		return fmt.Errorf("coder/php: unsupported type %T", e)
+	}
+
+	var b []byte
+	var err error
+	if enc.handler == PHPSerializeHandler {
+		b, err = appendPHPValue(b, data)
+	} else {
+		for _, k := range sortedKeys(data) {
+			if bytes.IndexByte([]byte(k), '|') >= 0 {
+				return fmt.Errorf("coder/php: key %q contains |", k)
+			}
+			b = append(b, k...)
+			b = append(b, '|')
+			b, err = appendPHPValue(b, data[k])
+			if err != nil {
+				break
+			}
+		}
+	}
+	if err != nil {
+		return err
+	}
+	_, err = enc.w.Write(b)
+	return err
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func appendPHPString(b []byte, s string) []byte {
+	b = append(b, "s:"...)
+	b = strconv.AppendInt(b, int64(len(s)), 10)
+	b = append(b, ":\""...)
+	b = append(b, s...)
+	return append(b, "\";"...)
+}
+
+func appendPHPInt(b []byte, i int64) []byte {
+	b = append(b, "i:"...)
+	b = strconv.AppendInt(b, i, 10)
+	return append(b, ';')
+}
+
+func appendPHPFloat(b []byte, f float64) []byte {
+	b = append(b, "d:"...)
+	switch {
+	case math.IsInf(f, 1):
+		b = append(b, "INF"...)
+	case math.IsInf(f, -1):
+		b = append(b, "-INF"...)
+	case math.IsNaN(f):
+		b = append(b, "NAN"...)
+	default:
+		b = strconv.AppendFloat(b, f, 'g', -1, 64)
+	}
+	return append(b, ';')
+}
+
+func appendPHPValue(b []byte, v interface{}) ([]byte, error) {
+	switch v := v.(type) {
+	case nil:
+		return append(b, "N;"...), nil
+	case bool:
+		if v {
+			return append(b, "b:1;"...), nil
+		}
+		return append(b, "b:0;"...), nil
+	case int:
+		return appendPHPInt(b, int64(v)), nil
+	case int8:
+		return appendPHPInt(b, int64(v)), nil
+	case int16:
+		return appendPHPInt(b, int64(v)), nil
+	case int32:
+		return appendPHPInt(b, int64(v)), nil
+	case int64:
+		return appendPHPInt(b, v), nil
+	case uint8:
+		return appendPHPInt(b, int64(v)), nil
+	case uint16:
+		return appendPHPInt(b, int64(v)), nil
+	case uint32:
+		return appendPHPInt(b, int64(v)), nil
+	case uint:
+		if uint64(v) > math.MaxInt64 {
+			return nil, fmt.Errorf("coder/php: integer overflow %d", v)
+		}
+		return appendPHPInt(b, int64(v)), nil
+	case uint64:
+		if v > math.MaxInt64 {
+			return nil, fmt.Errorf("coder/php: integer overflow %d", v)
+		}
+		return appendPHPInt(b, int64(v)), nil
+	case float32:
+		return appendPHPFloat(b, float64(v)), nil
+	case float64:
+		return appendPHPFloat(b, v), nil
+	case string:
+		return appendPHPString(b, v), nil
+	case []byte:
+		return appendPHPString(b, string(v)), nil
+	case []string:
+		b = append(b, "a:"...)
+		b = strconv.AppendInt(b, int64(len(v)), 10)
+		b = append(b, ":{"...)
+		for i, x := range v {
+			b = appendPHPInt(b, int64(i))
+			b = appendPHPString(b, x)
+		}
+		return append(b, '}'), nil
+	case []interface{}:
+		b = append(b, "a:"...)
+		b = strconv.AppendInt(b, int64(len(v)), 10)
+		b = append(b, ":{"...)
+		var err error
+		for i, x := range v {
+			b = appendPHPInt(b, int64(i))
+			b, err = appendPHPValue(b, x)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return append(b, '}'), nil
+	case session.Data:
+		return appendPHPValue(b, map[string]interface{}(v))
+	case map[string]interface{}:
+		b = append(b, "a:"...)
+		b = strconv.AppendInt(b, int64(len(v)), 10)
+		b = append(b, ":{"...)
+		var err error
+		for _, k := range sortedKeys(v) {
+			b = appendPHPString(b, k)
+			b, err = appendPHPValue(b, v[k])
+			if err != nil {
+				return nil, err
+			}
+		}
+		return append(b, '}'), nil
+	}
+	return nil, fmt.Errorf("coder/php: unsupported value type %T", v)
+}
+
+type phpDecoder struct {
+	r       io.Reader
+	handler string
+}
+
+func (dec *phpDecoder) Decode(e interface{}) error {
+	p, ok := e.(*session.Data)
+	if !ok {
+		return fmt.Errorf("coder/php: unsupported type %T", e)
+	}
+
+	b, err := io.ReadAll(dec.r)
+	if err != nil {
+		return err
+	}
+
+	data := make(session.Data)
+	d := phpParser{b: b}
+	if dec.handler == PHPSerializeHandler {
+		v, err := d.value()
+		if err != nil {
+			return err
+		}
+		switch v := v.(type) {
+		case map[string]interface{}:
+			data = v
+		case []interface{}:
+			for i, x := range v {
+				data[strconv.Itoa(i)] = x
+			}
+		default:
+			return errPHPInvalid
+		}
+	} else {
+		for d.i < len(d.b) {
+			n := bytes.IndexByte(d.b[d.i:], '|')
+			if n < 0 {
+				return errPHPInvalid
+			}
+			k := string(d.b[d.i : d.i+n])
+			d.i += n + 1
+			data[k], err = d.value()
+			if err != nil {
+				return err
+			}
+		}
+	}
+	if d.i != len(d.b) {
+		return errPHPInvalid
+	}
+	// PHP strings are binary, []byte encodes as string
+	err = restoreTypes(data, func(s string) ([]byte, error) { return []byte(s), nil })
+	if err != nil {
+		return err
+	}
+	*p = data
+	return nil
+}
+
+type phpParser struct {
+	b []byte
+	i int
+}
+
+// expect consumes s
+func (d *phpParser) expect(s string) error {
+	if !bytes.HasPrefix(d.b[d.i:], []byte(s)) {
+		return errPHPInvalid
+	}
+	d.i += len(s)
+	return nil
+}
+
+// until returns bytes until c, and consumes c
+func (d *phpParser) until(c byte) ([]byte, error) {
+	n := bytes.IndexByte(d.b[d.i:], c)
+	if n < 0 {
+		return nil, errPHPInvalid
+	}
+	r := d.b[d.i : d.i+n]
+	d.i += n + 1
+	return r, nil
+}
+
+func (d *phpParser) int(end byte) (int64, error) {
+	p, err := d.until(end)
+	if err != nil {
+		return 0, err
+	}
+	i, err := strconv.ParseInt(string(p), 10, 64)
+	if err != nil {
+		return 0, errPHPInvalid
+	}
+	return i, nil
+}
+
+func (d *phpParser) value() (interface{}, error) {
+	if len(d.b)-d.i < 2 {
+		return nil, errPHPInvalid
+	}
+	t := d.b[d.i]
+	if t == 'N' {
+		return nil, d.expect("N;")
+	}
+	if err := d.expect(string([]byte{t, ':'})); err != nil {
+		return nil, err
+	}
+
+	switch t {
+	case 'b':
+		i, err := d.int(';')
+		if err != nil {
+			return nil, err
+		}
+		return i != 0, nil
+	case 'i':
+		return d.int(';')
+	case 'd':
+		p, err := d.until(';')
+		if err != nil {
+			return nil, err
+		}
+		switch string(p) {
+		case "INF":
+			return math.Inf(1), nil
+		case "-INF":
+			return math.Inf(-1), nil
+		case "NAN":
+			return math.NaN(), nil
+		}
+		f, err := strconv.ParseFloat(string(p), 64)
+		if err != nil {
+			return nil, errPHPInvalid
+		}
+		return f, nil
+	case 's':
+		n, err := d.int(':')
+		if err != nil {
+			return nil, err
+		}
+		if err := d.expect("\""); err != nil {
+			return nil, err
+		}
+		if n < 0 || int64(len(d.b)-d.i) < n {
+			return nil, errPHPInvalid
+		}
+		s := string(d.b[d.i : d.i+int(n)])
+		d.i += int(n)
+		return s, d.expect("\";")
+	case 'a':
+		n, err := d.int(':')
+		if err != nil {
+			return nil, err
+		}
+		if n < 0 || int64(len(d.b)-d.i) < n {
+			return nil, errPHPInvalid
+		}
+		if err := d.expect("{"); err != nil {
+			return nil, err
+		}
+		return d.array(int(n))
+	}
+	return nil, fmt.Errorf("coder/php: unsupported value type %q", t)
+}
+
+// array decodes n array elements and closing brace
+func (d *phpParser) array(n int) (interface{}, error) {
+	list := make([]interface{}, 0, n)
+	var m map[string]interface{}
+	for i := 0; i < n; i++ {
+		k, err := d.value()
+		if err != nil {
+			return nil, err
+		}
+		v, err := d.value()
+		if err != nil {
+			return nil, err
+		}
+
+		var key string
+		switch k := k.(type) {
+		case int64:
+			if m == nil && k == int64(i) {
+				list = append(list, v)
+				continue
+			}
+			key = strconv.FormatInt(k, 10)
+		case string:
+			key = k
+		default:
+			return nil, errPHPInvalid
+		}
+
+		// not a list, convert to map
+		if m == nil {
+			m = make(map[string]interface{}, n)
+			for j, x := range list {
+				m[strconv.Itoa(j)] = x
+			}
+		}
+		m[key] = v
+	}
+	if err := d.expect("}"); err != nil {
+		return nil, err
+	}
+	if m != nil {
+		return m, nil
+	}
+	return list, nil
+}
@@ -0,0 +1,96 @@
+package coder
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/moonrhythm/session"
+)
+
+func TestPHP(t *testing.T) {
+	t.Parallel()
+
+	data := session.Data{
+		"str":   "สวัสดี",
+		"int":   1,
+		"neg":   int64(-2),
+		"float": 1.5,
+		"bool":  true,
+		"nil":   nil,
+		"list":  []interface{}{"a", 1},
+		"map":   map[string]interface{}{"x": "y"},
+	}
+
+	cases := []struct {
+		Handler string
+		Payload string
+	}{
+		{PHPHandler, `bool|b:1;float|d:1.5;int|i:1;list|a:2:{i:0;s:1:"a";i:1;i:1;}map|a:1:{s:1:"x";s:1:"y";}neg|i:-2;nil|N;str|s:18:"สวัสดี";`},
+		{PHPSerializeHandler, `a:8:{s:4:"bool";b:1;s:5:"float";d:1.5;s:3:"int";i:1;s:4:"list";a:2:{i:0;s:1:"a";i:1;i:1;}s:3:"map";a:1:{s:1:"x";s:1:"y";}s:3:"neg";i:-2;s:3:"nil";N;s:3:"str";s:18:"สวัสดี";}`},
+	}
+
+	for _, c := range cases {
+		var coder session.StoreCoder = PHP{Handler: c.Handler}
+
+		var buf bytes.Buffer
+		assert.NoError(t, coder.NewEncoder(&buf).Encode(data))
+		assert.Equal(t, c.Payload, buf.String())
+
+		var d session.Data
+		assert.NoError(t, coder.NewDecoder(&buf).Decode(&d))
+		assert.Equal(t, session.Data{
+			"str":   "สวัสดี",
+			"int":   int64(1),
+			"neg":   int64(-2),
+			"float": 1.5,
+			"bool":  true,
+			"nil":   nil,
+			"list":  []interface{}{"a", int64(1)},
+			"map":   map[string]interface{}{"x": "y"},
+		}, d)
+	}
+}
+
+func TestPHPDecode(t *testing.T) {
+	t.Parallel()
+
+	// written by PHP
+	var d session.Data
+	err := PHP{}.NewDecoder(strings.NewReader(`user|s:5:"alice";cart|a:2:{i:3;s:1:"a";s:1:"b";d:0.5;}`)).Decode(&d)
+	assert.NoError(t, err)
+	assert.Equal(t, "alice", d["user"])
+	assert.Equal(t, map[string]interface{}{"3": "a", "b": 0.5}, d["cart"])
+
+	for _, p := range []string{
+		`user`,
+		`user|s:10:"alice";`,
+		`user|s:5:"alice"`,
+		`user|i:x;`,
+		`user|a:1:{i:0;s:1:"a";`,
+		`user|O:8:"stdClass":0:{}`,
+	} {
+		var d session.Data
+		err := PHP{}.NewDecoder(strings.NewReader(p)).Decode(&d)
+		assert.Error(t, err, p)
+	}
+}
+
+func TestPHPEncodeInvalid(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+	assert.Error(t, PHP{}.NewEncoder(&buf).Encode(session.Data{"a|b": 1}))
+	assert.Error(t, PHP{}.NewEncoder(&buf).Encode(session.Data{"a": struct{}{}}))
+}
+
+func TestPHPSessionID(t *testing.T) {
+	t.Parallel()
+
+	id := PHPSessionID()
+	assert.Len(t, id, 32)
+	assert.Empty(t, strings.Trim(id, "0123456789abcdefghijklmnopqrstuv"))
+	assert.NotEqual(t, id, PHPSessionID())
+}
@@ -0,0 +1,430 @@
+package coder
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protowire"
+
+	"github.com/moonrhythm/session"
+)
+
+// protobufVersion is the envelope version written by Protobuf coder
+const protobufVersion = 1
+
+// Protobuf encodes session data into protobuf envelope,
+// see protobuf.proto for the schema
+//
+// entries are sorted by key, so the same data always encodes to the same bytes,
+// supported value types are string, bool, int, int32, int64, uint, uint32, uint64,
+// float32, float64, []byte, time.Time, []string, []interface{} and map[string]interface{}
+type Protobuf struct{}
+
+// NewEncoder implements session.StoreCoder
+func (Protobuf) NewEncoder(w io.Writer) session.StoreEncoder {
+	return &protobufEncoder{w}
+}
+
+// NewDecoder implements session.StoreCoder
+func (Protobuf) NewDecoder(r io.Reader) session.StoreDecoder {
+	return &protobufDecoder{r}
+}
+
+var errProtobufInvalid = errors.New("coder/protobuf: invalid payload")
+
+// field numbers, see protobuf.proto
+const (
+	fieldVersion    protowire.Number = 1
+	fieldEntries    protowire.Number = 2
+	fieldMapEntries protowire.Number = 1
+	fieldListValues protowire.Number = 1
+	fieldStrings    protowire.Number = 1
+	fieldEntryKey   protowire.Number = 1
+	fieldEntryValue protowire.Number = 2
+
+	// google.protobuf.Timestamp
+	fieldTimestampSeconds protowire.Number = 1
+	fieldTimestampNanos   protowire.Number = 2
+)
+
+// Value oneof field numbers
+const (
+	fieldValueString protowire.Number = iota + 1
+	fieldValueBool
+	fieldValueInt
+	fieldValueInt32
+	fieldValueInt64
+	fieldValueUint
+	fieldValueUint32
+	fieldValueUint64
+	fieldValueFloat32
+	fieldValueFloat64
+	fieldValueBytes
+	fieldValueTime
+	fieldValueList
+	fieldValueMap
+	fieldValueStrings
+)
+
+type protobufEncoder struct {
+	w io.Writer
+}
+
+func (enc *protobufEncoder) Encode(e interface{}) error {
+	var data map[string]interface{}
+	switch e := e.(type) {
+	case session.Data:
+		data = e
+	case *session.Data:
+		data = *e
+	case map[string]interface{}:
+		data = e
+	default:
+		return fmt.Errorf("coder/protobuf: unsupported type %T", e)
+	}
+
+	var b []byte
+	b = protowire.AppendTag(b, fieldVersion, protowire.VarintType)
+	b = protowire.AppendVarint(b, protobufVersion)
+	b, err := appendEntries(b, fieldEntries, data)
+	if err != nil {
+		return err
+	}
+	_, err = enc.w.Write(b)
+	return err
+}
+
+func appendEntries(b []byte, num protowire.Number, m map[string]interface{}) ([]byte, error) {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		var entry []byte
+		entry = protowire.AppendTag(entry, fieldEntryKey, protowire.BytesType)
+		entry = protowire.AppendString(entry, k)
+		v, err := appendValue(nil, m[k])
+		if err != nil {
+			return nil, err
+		}
+		entry = protowire.AppendTag(entry, fieldEntryValue, protowire.BytesType)
+		entry = protowire.AppendBytes(entry, v)
+
+		b = protowire.AppendTag(b, num, protowire.BytesType)
+		b = protowire.AppendBytes(b, entry)
+	}
+	return b, nil
+}
+
+func appendValue(b []byte, v interface{}) ([]byte, error) {
+	switch v := v.(type) {
+	case nil:
+		// empty value
+	case string:
+		b = protowire.AppendTag(b, fieldValueString, protowire.BytesType)
+		b = protowire.AppendString(b, v)
+	case bool:
+		b = protowire.AppendTag(b, fieldValueBool, protowire.VarintType)
+		b = protowire.AppendVarint(b, protowire.EncodeBool(v))
+	case int:
+		b = protowire.AppendTag(b, fieldValueInt, protowire.VarintType)
+		b = protowire.AppendVarint(b, protowire.EncodeZigZag(int64(v)))
+	case int32:
+		b = protowire.AppendTag(b, fieldValueInt32, protowire.VarintType)
+		b = protowire.AppendVarint(b, protowire.EncodeZigZag(int64(v)))
+	case int64:
+		b = protowire.AppendTag(b, fieldValueInt64, protowire.VarintType)
+		b = protowire.AppendVarint(b, protowire.EncodeZigZag(v))
+	case uint:
+		b = protowire.AppendTag(b, fieldValueUint, protowire.VarintType)
+		b = protowire.AppendVarint(b, uint64(v))
+	case uint32:
+		b = protowire.AppendTag(b, fieldValueUint32, protowire.VarintType)
+		b = protowire.AppendVarint(b, uint64(v))
+	case uint64:
+		b = protowire.AppendTag(b, fieldValueUint64, protowire.VarintType)
+		b = protowire.AppendVarint(b, v)
+	case float32:
+		b = protowire.AppendTag(b, fieldValueFloat32, protowire.Fixed32Type)
+		b = protowire.AppendFixed32(b, math.Float32bits(v))
+	case float64:
+		b = protowire.AppendTag(b, fieldValueFloat64, protowire.Fixed64Type)
+		b = protowire.AppendFixed64(b, math.Float64bits(v))
+	case []byte:
+		b = protowire.AppendTag(b, fieldValueBytes, protowire.BytesType)
+		b = protowire.AppendBytes(b, v)
+	case time.Time:
+		var ts []byte
+		if sec := v.Unix(); sec != 0 {
+			ts = protowire.AppendTag(ts, fieldTimestampSeconds, protowire.VarintType)
+			ts = protowire.AppendVarint(ts, uint64(sec))
+		}
+		if nsec := v.Nanosecond(); nsec != 0 {
+			ts = protowire.AppendTag(ts, fieldTimestampNanos, protowire.VarintType)
+			ts = protowire.AppendVarint(ts, uint64(nsec))
+		}
+		b = protowire.AppendTag(b, fieldValueTime, protowire.BytesType)
+		b = protowire.AppendBytes(b, ts)
+	case []interface{}:
+		var list []byte
+		for _, x := range v {
+			p, err := appendValue(nil, x)
+			if err != nil {
+				return nil, err
+			}
+			list = protowire.AppendTag(list, fieldListValues, protowire.BytesType)
+			list = protowire.AppendBytes(list, p)
+		}
+		b = protowire.AppendTag(b, fieldValueList, protowire.BytesType)
+		b = protowire.AppendBytes(b, list)
+	case []string:
+		var list []byte
+		for _, x := range v {
+			list = protowire.AppendTag(list, fieldStrings, protowire.BytesType)
+			list = protowire.AppendString(list, x)
+		}
+		b = protowire.AppendTag(b, fieldValueStrings, protowire.BytesType)
+		b = protowire.AppendBytes(b, list)
+	case map[string]interface{}:
+		m, err := appendEntries(nil, fieldMapEntries, v)
+		if err != nil {
+			return nil, err
+		}
+		b = protowire.AppendTag(b, fieldValueMap, protowire.BytesType)
+		b = protowire.AppendBytes(b, m)
+	default:
+		return nil, fmt.Errorf("coder/protobuf: unsupported value type %T", v)
+	}
+	return b, nil
+}
+
+type protobufDecoder struct {
+	r io.Reader
+}
+
+func (dec *protobufDecoder) Decode(e interface{}) error {
+	p, ok := e.(*session.Data)
+	if !ok {
+		return fmt.Errorf("coder/protobuf: unsupported type %T", e)
+	}
+
+	b, err := io.ReadAll(dec.r)
+	if err != nil {
+		return err
+	}
+
+	data := make(session.Data)
+	err = consumeFields(b, func(num protowire.Number, typ protowire.Type, b []byte) (int, error) {
+		switch {
+		case num == fieldVersion && typ == protowire.VarintType:
+			v, n := protowire.ConsumeVarint(b)
+			if n < 0 {
+				return n, nil
+			}
+			if v > protobufVersion {
+				return 0, fmt.Errorf("coder/protobuf: unsupported version %d", v)
+			}
+			return n, nil
+		case num == fieldEntries && typ == protowire.BytesType:
+			return consumeEntry(b, data)
+		}
+		return protowire.ConsumeFieldValue(num, typ, b), nil
+	})
+	if err != nil {
+		return err
+	}
+	*p = data
+	return nil
+}
+
+// consumeFields calls f for each field in b,
+// f returns the length of consumed field value
+func consumeFields(b []byte, f func(num protowire.Number, typ protowire.Type, b []byte) (int, error)) error {
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return errProtobufInvalid
+		}
+		b = b[n:]
+
+		n, err := f(num, typ, b)
+		if err != nil {
+			return err
+		}
+		if n < 0 {
+			return errProtobufInvalid
+		}
+		b = b[n:]
+	}
+	return nil
+}
+
+func consumeEntry(b []byte, m map[string]interface{}) (int, error) {
+	p, n := protowire.ConsumeBytes(b)
+	if n < 0 {
+		return n, nil
+	}
+
+	var (
+		key   string
+		value interface{}
+	)
+	err := consumeFields(p, func(num protowire.Number, typ protowire.Type, b []byte) (int, error) {
+		switch {
+		case num == fieldEntryKey && typ == protowire.BytesType:
+			v, n := protowire.ConsumeString(b)
+			key = v
+			return n, nil
+		case num == fieldEntryValue && typ == protowire.BytesType:
+			v, n := protowire.ConsumeBytes(b)
+			if n < 0 {
+				return n, nil
+			}
+			var err error
+			value, err = decodeValue(v)
+			return n, err
+		}
+		return protowire.ConsumeFieldValue(num, typ, b), nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	m[key] = value
+	return n, nil
+}
+
+func decodeValue(b []byte) (value interface{}, err error) {
+	err = consumeFields(b, func(num protowire.Number, typ protowire.Type, b []byte) (n int, err error) {
+		switch typ {
+		case protowire.VarintType:
+			var v uint64
+			v, n = protowire.ConsumeVarint(b)
+			switch num {
+			case fieldValueBool:
+				value = protowire.DecodeBool(v)
+			case fieldValueInt:
+				value = int(protowire.DecodeZigZag(v))
+			case fieldValueInt32:
+				value = int32(protowire.DecodeZigZag(v))
+			case fieldValueInt64:
+				value = protowire.DecodeZigZag(v)
+			case fieldValueUint:
+				value = uint(v)
+			case fieldValueUint32:
+				value = uint32(v)
+			case fieldValueUint64:
+				value = v
+			}
+		case protowire.Fixed32Type:
+			var v uint32
+			v, n = protowire.ConsumeFixed32(b)
+			if num == fieldValueFloat32 {
+				value = math.Float32frombits(v)
+			}
+		case protowire.Fixed64Type:
+			var v uint64
+			v, n = protowire.ConsumeFixed64(b)
+			if num == fieldValueFloat64 {
+				value = math.Float64frombits(v)
+			}
+		case protowire.BytesType:
+			var v []byte
+			v, n = protowire.ConsumeBytes(b)
+			if n < 0 {
+				return
+			}
+			switch num {
+			case fieldValueString:
+				value = string(v)
+			case fieldValueBytes:
+				value = append([]byte{}, v...)
+			case fieldValueList:
+				value, err = decodeList(v)
+			case fieldValueMap:
+				value, err = decodeMap(v)
+			case fieldValueStrings:
+				value, err = decodeStrings(v)
+			case fieldValueTime:
+				value, err = decodeTimestamp(v)
+			}
+		default:
+			n = protowire.ConsumeFieldValue(num, typ, b)
+		}
+		return
+	})
+	return
+}
+
+func decodeTimestamp(b []byte) (time.Time, error) {
+	var sec, nsec int64
+	err := consumeFields(b, func(num protowire.Number, typ protowire.Type, b []byte) (int, error) {
+		if typ != protowire.VarintType {
+			return protowire.ConsumeFieldValue(num, typ, b), nil
+		}
+		v, n := protowire.ConsumeVarint(b)
+		switch num {
+		case fieldTimestampSeconds:
+			sec = int64(v)
+		case fieldTimestampNanos:
+			nsec = int64(int32(v))
+		}
+		return n, nil
+	})
+	if err != nil {
+		return time.Time{}, err
+	}
+	if nsec < 0 || nsec >= int64(time.Second) {
+		return time.Time{}, errProtobufInvalid
+	}
+	return time.Unix(sec, nsec), nil
+}
+
+func decodeList(b []byte) ([]interface{}, error) {
+	list := []interface{}{}
+	err := consumeFields(b, func(num protowire.Number, typ protowire.Type, b []byte) (int, error) {
+		if num != fieldListValues || typ != protowire.BytesType {
+			return protowire.ConsumeFieldValue(num, typ, b), nil
+		}
+		v, n := protowire.ConsumeBytes(b)
+		if n < 0 {
+			return n, nil
+		}
+		x, err := decodeValue(v)
+		list = append(list, x)
+		return n, err
+	})
+	return list, err
+}
+
+func decodeStrings(b []byte) ([]string, error) {
+	list := []string{}
+	err := consumeFields(b, func(num protowire.Number, typ protowire.Type, b []byte) (int, error) {
+		if num != fieldStrings || typ != protowire.BytesType {
+			return protowire.ConsumeFieldValue(num, typ, b), nil
+		}
+		v, n := protowire.ConsumeBytes(b)
+		if n < 0 {
+			return n, nil
+		}
+		list = append(list, string(v))
+		return n, nil
+	})
+	return list, err
+}
+
+func decodeMap(b []byte) (map[string]interface{}, error) {
+	m := make(map[string]interface{})
+	err := consumeFields(b, func(num protowire.Number, typ protowire.Type, b []byte) (int, error) {
+		if num != fieldMapEntries || typ != protowire.BytesType {
+			return protowire.ConsumeFieldValue(num, typ, b), nil
+		}
+		return consumeEntry(b, m)
+	})
+	return m, err
+}
@@ -0,0 +1,99 @@
+package coder
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/moonrhythm/session"
+)
+
+func TestProtobuf(t *testing.T) {
+	t.Parallel()
+
+	now := time.Unix(0, time.Now().UnixNano())
+	data := session.Data{
+		"string":  "value",
+		"bool":    true,
+		"int":     -1,
+		"int32":   int32(2),
+		"int64":   int64(-3),
+		"uint":    uint(4),
+		"uint32":  uint32(5),
+		"uint64":  uint64(6),
+		"float32": float32(1.5),
+		"float64": 2.5,
+		"bytes":   []byte("abc"),
+		"time":    now,
+		"nil":     nil,
+		"list":    []interface{}{"a", 1},
+		"strings": []string{"a", "b"},
+		"empty":   []string{},
+		"map":     map[string]interface{}{"k": "v", "n": map[string]interface{}{}},
+	}
+
+	var c session.StoreCoder = Protobuf{}
+
+	var buf bytes.Buffer
+	err := c.NewEncoder(&buf).Encode(data)
+	assert.NoError(t, err)
+
+	var d session.Data
+	err = c.NewDecoder(&buf).Decode(&d)
+	assert.NoError(t, err)
+	assert.True(t, now.Equal(d["time"].(time.Time)))
+	delete(d, "time")
+	delete(data, "time")
+	assert.Equal(t, data, d)
+}
+
+func TestProtobufTime(t *testing.T) {
+	t.Parallel()
+
+	for _, tm := range []time.Time{
+		time.Unix(0, 0),
+		time.Unix(-1, 5),
+		time.Date(1000, 1, 2, 3, 4, 5, 6, time.UTC),
+		time.Date(3000, 1, 2, 3, 4, 5, 999999999, time.UTC),
+	} {
+		var buf bytes.Buffer
+		assert.NoError(t, Protobuf{}.NewEncoder(&buf).Encode(session.Data{"t": tm}))
+
+		var d session.Data
+		assert.NoError(t, Protobuf{}.NewDecoder(&buf).Decode(&d))
+		assert.True(t, tm.Equal(d["t"].(time.Time)), "expected %v; got %v", tm, d["t"])
+	}
+}
+
+func TestProtobufDeterministic(t *testing.T) {
+	t.Parallel()
+
+	data := session.Data{"a": 1, "b": "2", "c": map[string]interface{}{"x": 1, "y": 2}}
+
+	var b1, b2 bytes.Buffer
+	assert.NoError(t, Protobuf{}.NewEncoder(&b1).Encode(data))
+	assert.NoError(t, Protobuf{}.NewEncoder(&b2).Encode(data))
+	assert.Equal(t, b1.Bytes(), b2.Bytes())
+}
+
+func TestProtobufUnsupported(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+	err := Protobuf{}.NewEncoder(&buf).Encode(session.Data{"a": struct{}{}})
+	assert.Error(t, err)
+}
+
+func TestProtobufInvalid(t *testing.T) {
+	t.Parallel()
+
+	var d session.Data
+	err := Protobuf{}.NewDecoder(bytes.NewReader([]byte{0x12, 0xff})).Decode(&d)
+	assert.Error(t, err)
+
+	// unsupported future version
+	err = Protobuf{}.NewDecoder(bytes.NewReader([]byte{0x08, 0x02})).Decode(&d)
+	assert.Error(t, err)
+}
@@ -0,0 +1,53 @@
+package coder
+
+import (
+	"github.com/moonrhythm/session"
+)
+
+// reserved keys that must keep Go types, coders that do not keep types
+// restore them after decode
+var (
+	// bytesKeys hold []byte, e.g. encoded flash
+	bytesKeys = []string{
+		"_session/flash",
+		"_session/payload",
+		"_session/encrypted",
+		"_session/compressed",
+	}
+
+	// stringsKeys hold []string, e.g. key order for quota
+	stringsKeys = []string{
+		"_session/keys",
+		"_session/alias",
+	}
+)
+
+// restoreTypes converts reserved values back to []byte and []string,
+// decodeBytes converts string that encoded from []byte back to []byte
+func restoreTypes(data session.Data, decodeBytes func(s string) ([]byte, error)) error {
+	for _, k := range bytesKeys {
+		s, ok := data[k].(string)
+		if !ok {
+			continue
+		}
+		b, err := decodeBytes(s)
+		if err != nil {
+			return err
+		}
+		data[k] = b
+	}
+	for _, k := range stringsKeys {
+		list, ok := data[k].([]interface{})
+		if !ok {
+			continue
+		}
+		r := make([]string, 0, len(list))
+		for _, x := range list {
+			if x, ok := x.(string); ok {
+				r = append(r, x)
+			}
+		}
+		data[k] = r
+	}
+	return nil
+}
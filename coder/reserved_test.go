@@ -0,0 +1,56 @@
+package coder
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/moonrhythm/session"
+	"github.com/moonrhythm/session/store"
+)
+
+func TestReservedTypes(t *testing.T) {
+	t.Parallel()
+
+	for name, c := range map[string]session.StoreCoder{
+		"Express":      Express{},
+		"PHP":          PHP{},
+		"PHPSerialize": PHP{Handler: PHPSerializeHandler},
+		"Protobuf":     Protobuf{},
+	} {
+		c := c
+		t.Run(name, func(t *testing.T) {
+			m := session.New(session.Config{
+				Store: new(store.Memory),
+				Coder: c,
+				Quota: session.Quota{MaxKeys: 2, Policy: session.QuotaEvictOldest},
+			})
+
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			s, _ := m.Get(r, "sess")
+			s.Set("a", "1")
+			s.Set("b", "2")
+			s.Set("a", "3")
+			s.Flash().Add("msg", "hello")
+			w := httptest.NewRecorder()
+			if !assert.NoError(t, m.Save(r.Context(), w, s)) {
+				return
+			}
+
+			r = httptest.NewRequest(http.MethodGet, "/", nil)
+			for _, cookie := range w.Result().Cookies() {
+				r.AddCookie(cookie)
+			}
+			s, err := m.Get(r, "sess")
+			assert.NoError(t, err)
+			assert.False(t, s.IsNew())
+			assert.Equal(t, "hello", s.Flash().Get("msg"), "expected flash kept")
+
+			s.Set("c", "4")
+			assert.Nil(t, s.Get("b"), "expected oldest key evicted")
+			assert.Equal(t, "3", s.Get("a"))
+		})
+	}
+}
@@ -1,6 +1,7 @@
 package session
 
 import (
+	"context"
 	"net/http"
 	"time"
 )
@@ -9,10 +10,29 @@ import (
 type Config struct {
 	Store Store
 
+	// Name is the session name handled by middleware and loaded by Manager.Load,
+	// Get resolves session name to the nearest middleware with the same name,
+	// or the nearest middleware without name,
+	// use to stack multiple middlewares with different config in single request
+	Name string
+
+	// RegisterDefault registers middleware as the default session for GetDefault,
+	// default session name is Name, or DefaultName if empty
+	RegisterDefault bool
+
 	// Secret is the salt for hash session id before put to store
 	Secret []byte
 
-	// Keys is the keys to sign session id
+	// Secrets is the salts for hash session id, overrides Secret,
+	// the first secret is used to hash session id,
+	// all secrets are used to lookup session, session found with old secret
+	// will be saved with the first secret,
+	// Destroy and Regenerate delete session stored with all secrets
+	Secrets [][]byte
+
+	// Keys is the keys to sign session id in cookie value as id.signature (HMAC-SHA1),
+	// the first key is used to sign, all keys are used to verify,
+	// unsigned or forged session id is rejected without store lookup
 	Keys [][]byte
 
 	// Cookie config
@@ -23,33 +43,262 @@ type Config struct {
 	Secure   Secure
 	SameSite http.SameSite
 
+	// SecureFunc returns cookie secure flag for the request, overrides Secure,
+	// cookie with CookiePrefix or Partitioned is always secure
+	SecureFunc func(r *http.Request) bool
+
+	// DomainFunc returns cookie domain for the request,
+	// if DomainFunc is nil, Domain will be used
+	DomainFunc func(r *http.Request) string
+
+	// Partitioned sets cookie partitioned by top-level site (CHIPS),
+	// for session in embedded third-party context, partitioned cookie is always secure
+	Partitioned bool
+
+	// CookiePrefix prepends prefix to cookie name,
+	// and enforces cookie attributes required by the prefix
+	CookiePrefix CookiePrefix
+
+	// Transport is the way session id is sent, default is CookieTransport,
+	// BearerTransport supports only single session name
+	Transport Transport
+
+	// TokenHeader is the response header for session id when use BearerTransport,
+	// default is DefaultTokenHeader
+	TokenHeader string
+
+	// IDExtractor reads session id value from request, overrides Transport,
+	// returns empty string if request does not have session id
+	IDExtractor func(r *http.Request) string
+
+	// IDWriter sends session id value (Session.Token) to client, overrides Transport
+	IDWriter func(w http.ResponseWriter, s *Session)
+
 	// IdleTimeout is the ttl for storage,
 	// if IdleTimeout is zero, it will use MaxAge
+	//
+	// if IdleTimeout is set, session not saved longer than IdleTimeout is rejected
+	// even if store does not expire it, session is resaved after half of IdleTimeout
+	// to keep active session alive
 	IdleTimeout time.Duration
 
+	// AbsoluteLifetime is the max lifetime since session created,
+	// session older than AbsoluteLifetime is rejected even if still active
+	AbsoluteLifetime time.Duration
+
 	// DeleteOldSession deletes the old session from store when regenerate,
 	// better not to delete old session to avoid user loss session when unstable network
 	DeleteOldSession bool
 
+	// RegenerateOldTTL is the ttl for the old session in store after regenerate,
+	// to serve in-flight requests that still use the old session id,
+	// if RegenerateOldTTL is zero, it will use IdleTimeout,
+	// use DeleteOldSession to delete the old session immediately
+	RegenerateOldTTL time.Duration
+
 	// Resave forces session to save to store even if session was not modified
 	Resave bool
 
 	// ResaveAfter is the time to wait before resave since last timestamp
 	ResaveAfter time.Duration
 
-	// Rolling, set cookie every responses
+	// SaveUninitialized saves new session to store and set cookie even if session was not modified
+	SaveUninitialized bool
+
+	// RevocationList is consulted on each load when store keeps session data in cookie,
+	// revoked session is treated as not found, Destroy revokes the session
+	RevocationList RevocationList
+
+	// Rolling, set cookie every responses,
+	// and refresh store ttl using Touch if store implements Toucher
 	Rolling bool
 
-	// Proxy, also checks X-Forwarded-Proto when use prefer secure
+	// Skipper skips middleware for the request, e.g. static assets and health checks,
+	// session can not be get from skipped request
+	Skipper func(r *http.Request) bool
+
+	// FailureMode is the behavior when store failed
+	FailureMode FailureMode
+
+	// ErrorHandler handles error when middleware failed to save session with Return500 mode,
+	// response written by handler after the error is discarded,
+	// if ErrorHandler is nil, middleware panics
+	ErrorHandler func(w http.ResponseWriter, r *http.Request, err error)
+
+	// PanicHandler handles panic while middleware saves session,
+	// e.g. store or coder panics, or save failed without ErrorHandler,
+	// response written by handler after the panic is discarded,
+	// if PanicHandler is nil, the panic is propagated,
+	// panic from handler is not recovered, session is not saved
+	PanicHandler func(w http.ResponseWriter, r *http.Request, v interface{})
+
+	// RenewPolicy decides to refresh session expiration on the response,
+	// like Session.Touch
+	RenewPolicy RenewPolicy
+
+	// BindIP binds session to client ip address when session created,
+	// session presented from different address is rejected and new session is started,
+	// see Proxy and TrustedProxies for how client ip address is resolved behind proxy
+	BindIP BindIP
+
+	// BindUserAgent binds session to normalized user agent fingerprint when session created,
+	// session presented from different user agent is rejected and new session is started
+	BindUserAgent bool
+
+	// NormalizeUserAgent normalizes user agent before fingerprint when BindUserAgent enabled,
+	// if NormalizeUserAgent is nil, version numbers are removed to keep session after browser updated
+	NormalizeUserAgent func(ua string) string
+
+	// Fingerprint returns device fingerprint of the request, e.g. TLS JA3, client hints or app's device id,
+	// the fingerprint is bound when session created,
+	// session presented with different fingerprint is rejected and new session is started
+	Fingerprint func(r *http.Request) string
+
+	// Proxy, also checks ProxyHeader when use prefer secure,
+	// and uses X-Forwarded-For address added by trusted proxy as client ip address
 	Proxy bool
 
-	// DisablaHashID disables hash session id when save to store
+	// TrustedProxies is the number of proxies in front of server when Proxy enabled,
+	// client ip address is the X-Forwarded-For address counted from the right,
+	// addresses on the left are set by client and can be spoofed,
+	// default is 1
+	TrustedProxies int
+
+	// ProxyHeader is the header for request protocol when Proxy enabled,
+	// e.g. X-Forwarded-Scheme, or Forwarded (RFC 7239) to use its proto parameter,
+	// default is X-Forwarded-Proto
+	ProxyHeader string
+
+	// IsTLS reports whether request is https when use prefer secure,
+	// if nil, checks request.TLS and ProxyHeader when Proxy enabled
+	IsTLS func(r *http.Request) bool
+
+	// DisablaHashID disables hash session id when save to store,
+	// session id with prefix "_session/" is rejected since the store keys are reserved
 	DisableHashID bool
 
-	// GenerateID is session id generator
+	// HashFunc hashes session id before put to store, overrides Secret and Secrets,
+	// if HashFunc is nil, SHA-256 of session id and secret will be used
+	HashFunc func(id string) string
+
+	// GenerateID is session id generator, overrides IDFormat
 	GenerateID func() string
+
+	// IDFormat is the format of generated session id, default is RandomBase64,
+	// store key is sortable by creation time only when DisableHashID
+	IDFormat IDFormat
+
+	// Coder encodes session data in manager before pass to store,
+	// store receives only the encoded payload prefixed with PayloadVersion,
+	// if Coder, PayloadVersion, Migrate, EncryptionKeys, SignPayload and MaxDataSize are not set,
+	// store receives session data as is,
+	// these options can not be used with CookieStore
+	Coder StoreCoder
+
+	// PayloadVersion is the version of session data layout,
+	// bump it when session data layout changed
+	PayloadVersion uint8
+
+	// Migrate is called when loaded session data has different payload version,
+	// data saved before manager encodes payload has version 0,
+	// returns ErrNotFound to discard the session
+	Migrate func(version uint8, data Data) (Data, error)
+
+	// EncryptionKeys is the AES keys (16, 24 or 32 bytes) to encrypt session data
+	// using AES-GCM before pass to store,
+	// the first key is used to encrypt, all keys are used to decrypt,
+	// session data that can not be decrypted by any key is treated as not found
+	EncryptionKeys [][]byte
+
+	// SignPayload appends HMAC-SHA256 keyed by Secret or Secrets to session data before pass to store,
+	// session data that fails verification is treated as not found
+	SignPayload bool
+
+	// DetectConflict increments session data version on each save,
+	// and saves using CASStore to detect conflicting writes from concurrent requests
+	// of the same session, Save returns ErrConflict when session data was changed
+	// by other request after loaded,
+	// New panics if any store in the chain does not implement CASStore
+	DetectConflict bool
+
+	// Quota limits keys and size of each session data
+	Quota Quota
+
+	// MaxDataSize is the maximum size of encoded session data to save, zero means no limit,
+	// session data is encoded into payload (see Coder) and measured by the payload saved to store,
+	// or by the cookie value for CookieStore, save fails with ErrDataTooLarge when session data exceeds the limit
+	MaxDataSize int
+
+	// OnDataTooLarge is called when session data exceeds MaxDataSize,
+	// it can delete data from session to fit the limit before save fails
+	OnDataTooLarge func(s *Session, size int)
+
+	// BeforeSave is called right before session is saved to store,
+	// BeforeSave can modify session data,
+	// returns ErrSkipSave to skip the save, or other error to fail the save
+	BeforeSave func(s *Session) error
+
+	// AfterLoad is called right after session is loaded from store,
+	// AfterLoad can modify session data,
+	// returns ErrNotFound to discard the session, or other error to fail the load
+	AfterLoad func(s *Session) error
+
+	// OnLateWrite is called when session data or flash is changed after middleware saved session,
+	// the change will not be saved, key is the changed session key or flash key
+	OnLateWrite func(s *Session, key string)
+
+	// OnCreate is called after new session was first saved to store successfully,
+	// data set in OnCreate is saved on the next save
+	OnCreate func(ctx context.Context, s *Session)
+
+	// OnRegenerate is called after session id was regenerated,
+	// oldID is the hashed id before regenerate
+	OnRegenerate func(ctx context.Context, oldID string, s *Session)
+
+	// OnDestroy is called after session was destroyed, before start new session
+	OnDestroy func(ctx context.Context, s *Session)
+
+	// AnalyticsCookie is the cookie name prefix for anonymous analytics id,
+	// the cookie name is AnalyticsCookie + "_" + session name,
+	// analytics id is not rotated with session id,
+	// if AnalyticsCookie is empty, analytics id is disabled
+	AnalyticsCookie string
+
+	// AnalyticsMaxAge is the max age for analytics id cookie
+	AnalyticsMaxAge time.Duration
+}
+
+// FailureMode is the behavior when store failed
+type FailureMode int
+
+// FailureMode values
+const (
+	// Return500 returns store error from Get, and calls Config.ErrorHandler when save failed
+	Return500 FailureMode = iota
+
+	// ServeWithEmptySession serves request with new empty session when load failed,
+	// and ignores error when save failed,
+	// the empty session is not saved and does not replace client's session cookie,
+	// so the client keeps its session after store recovered
+	ServeWithEmptySession
+)
+
+// RenewPolicy decides to refresh session expiration
+type RenewPolicy interface {
+	ShouldRenew(s *Session) bool
 }
 
+// RenewPolicyFunc is the adapter to use function as RenewPolicy
+type RenewPolicyFunc func(s *Session) bool
+
+// ShouldRenew calls f(s)
+func (f RenewPolicyFunc) ShouldRenew(s *Session) bool {
+	return f(s)
+}
+
+// DefaultName is the session name used by Manager.Load and GetDefault when Config.Name is empty
+const DefaultName = "session"
+
 // Secure config
 type Secure int
 
@@ -60,6 +309,61 @@ const (
 	ForceSecure         // always set secure cookie
 )
 
+// CookiePrefix config
+type CookiePrefix int
+
+// CookiePrefix values
+const (
+	NoCookiePrefix     CookiePrefix = iota
+	SecureCookiePrefix              // __Secure-, requires secure cookie
+	HostCookiePrefix                // __Host-, requires secure cookie, path "/" and no domain
+)
+
+// String returns cookie name prefix
+func (p CookiePrefix) String() string {
+	switch p {
+	case SecureCookiePrefix:
+		return "__Secure-"
+	case HostCookiePrefix:
+		return "__Host-"
+	}
+	return ""
+}
+
+// validate adjusts config to satisfy prefix requirements,
+// panics if config conflicts with prefix
+func (p CookiePrefix) validate(config *Config) {
+	if p == NoCookiePrefix {
+		return
+	}
+	config.Secure = ForceSecure
+
+	if p == HostCookiePrefix {
+		if config.Domain != "" || config.DomainFunc != nil {
+			panic("session: __Host- cookie prefix must not have domain")
+		}
+		if config.Path == "" {
+			config.Path = "/"
+		}
+		if config.Path != "/" {
+			panic("session: __Host- cookie prefix requires path \"/\"")
+		}
+	}
+}
+
+// apply enforces cookie attributes required by prefix
+func (p CookiePrefix) apply(cs *http.Cookie) {
+	if p == NoCookiePrefix {
+		return
+	}
+	cs.Secure = true
+
+	if p == HostCookiePrefix {
+		cs.Domain = ""
+		cs.Path = "/"
+	}
+}
+
 // Global Session Config
 var (
 	HijackedTime = 5 * time.Minute
@@ -0,0 +1,143 @@
+// Package django provides decoder for Django signed cookie session,
+// to share login state with Django application
+package django
+
+import (
+	"bytes"
+	"compress/zlib"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"hash"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/moonrhythm/session"
+	"github.com/moonrhythm/session/internal/interop"
+)
+
+// ErrInvalid is the error when cookie can not be verified or decoded
+var ErrInvalid = errors.New("session/django: invalid cookie")
+
+// DefaultSalt is the salt of Django signed cookie session backend
+const DefaultSalt = "django.contrib.sessions.backends.signed_cookies"
+
+// DefaultMaxAge is the Django default SESSION_COOKIE_AGE
+const DefaultMaxAge = 14 * 24 * time.Hour
+
+// Cookie decodes Django signed cookie session (django.contrib.sessions.backends.signed_cookies),
+// session must be serialized using JSONSerializer
+type Cookie struct {
+	// SecretKey is the Django SECRET_KEY
+	SecretKey []byte
+
+	// Salt is the signer salt, default is DefaultSalt
+	Salt string
+
+	// Hash is the signer algorithm, default is SHA256 (Django 3.1+)
+	Hash func() hash.Hash
+
+	// MaxAge is the Django SESSION_COOKIE_AGE, default is DefaultMaxAge
+	MaxAge time.Duration
+}
+
+func (c *Cookie) hash() func() hash.Hash {
+	if c.Hash == nil {
+		return sha256.New
+	}
+	return c.Hash
+}
+
+// signature returns signature of value, same as django.core.signing.Signer
+func (c *Cookie) signature(value string) string {
+	salt := c.Salt
+	if salt == "" {
+		salt = DefaultSalt
+	}
+
+	// salted_hmac
+	kh := c.hash()()
+	kh.Write([]byte(salt + "signer"))
+	kh.Write(c.SecretKey)
+	h := hmac.New(c.hash(), kh.Sum(nil))
+	h.Write([]byte(value))
+	return base64.RawURLEncoding.EncodeToString(h.Sum(nil))
+}
+
+// Decode verifies and decodes cookie value into session data,
+// integers decode as int64
+func (c *Cookie) Decode(value string) (session.Data, error) {
+	i := strings.LastIndexByte(value, ':')
+	if i < 0 {
+		return nil, ErrInvalid
+	}
+	value, sig := value[:i], value[i+1:]
+	if !hmac.Equal([]byte(sig), []byte(c.signature(value))) {
+		return nil, ErrInvalid
+	}
+
+	i = strings.LastIndexByte(value, ':')
+	if i < 0 {
+		return nil, ErrInvalid
+	}
+	payload, ts := value[:i], value[i+1:]
+	maxAge := c.MaxAge
+	if maxAge <= 0 {
+		maxAge = DefaultMaxAge
+	}
+	t, ok := base62(ts)
+	if !ok || time.Since(time.Unix(t, 0)) > maxAge {
+		return nil, ErrInvalid
+	}
+
+	compressed := strings.HasPrefix(payload, ".")
+	p, err := base64.RawURLEncoding.DecodeString(strings.TrimPrefix(payload, "."))
+	if err != nil {
+		return nil, ErrInvalid
+	}
+	if compressed {
+		zr, err := zlib.NewReader(bytes.NewReader(p))
+		if err != nil {
+			return nil, ErrInvalid
+		}
+		p, err = ioutil.ReadAll(zr)
+		if err != nil {
+			return nil, ErrInvalid
+		}
+	}
+
+	data, err := interop.DecodeJSON(bytes.NewReader(p))
+	if err != nil {
+		return nil, ErrInvalid
+	}
+	return data, nil
+}
+
+// base62 decodes Django base62 timestamp
+func base62(s string) (int64, bool) {
+	const chars = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+
+	if s == "" || len(s) > 10 {
+		return 0, false
+	}
+	var n int64
+	for i := 0; i < len(s); i++ {
+		x := strings.IndexByte(chars, s[i])
+		if x < 0 {
+			return 0, false
+		}
+		n = n*62 + int64(x)
+	}
+	return n, true
+}
+
+// Import copies values from Django session cookie into session,
+// returns false if cookie not found or can not be decoded
+//
+// Import does not remove Django cookie
+func Import(r *http.Request, s *session.Session, name string, c *Cookie) bool {
+	return interop.Import(r, s, name, c.Decode)
+}
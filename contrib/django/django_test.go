@@ -0,0 +1,67 @@
+package django
+
+import (
+	"crypto/sha1"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/moonrhythm/session"
+)
+
+// generated with python following django.core.signing.dumps(obj, key="secret", compress=True,
+// salt="django.contrib.sessions.backends.signed_cookies")
+const (
+	signedValue     = "eyJfYXV0aF91c2VyX2lkIjoiMSIsImNvdW50IjozfQ:4TdRIW:7v7wGU6ioLHltwgySyiYgRILwfyV92itpAMiU6FXD9Y"
+	compressedValue = ".eJyrVopPLC3JiC8tTi2Kz0xRslIyVNJRysksLlGyilaqIBkANQ8_TbG1ACoigZk:4TdRIW:nV9HqjMn79Jj6hntUhCXI0Gm2dN-2sHjTWh1RtYR_vI"
+	expiredValue    = "eyJhIjoxfQ:124Bxg:MXrowrvZVwkf6dCUSx444jFvR6E4PTjpJ0iTPJPxj_c"
+	sha1Value       = "eyJhIjoxfQ:4TdRIW:0xmDZ8El3dzjrCceUztrwzaLGu4"
+)
+
+func TestCookie(t *testing.T) {
+	t.Parallel()
+
+	c := &Cookie{SecretKey: []byte("secret")}
+
+	data, err := c.Decode(signedValue)
+	assert.NoError(t, err)
+	assert.Equal(t, session.Data{"_auth_user_id": "1", "count": int64(3)}, data)
+
+	data, err = c.Decode(compressedValue)
+	assert.NoError(t, err)
+	assert.Equal(t, "1", data["_auth_user_id"])
+	assert.Len(t, data["list"], 5)
+
+	_, err = c.Decode(expiredValue)
+	assert.Equal(t, ErrInvalid, err, "expected expired cookie rejected")
+
+	_, err = c.Decode(sha1Value)
+	assert.Equal(t, ErrInvalid, err)
+	data, err = (&Cookie{SecretKey: []byte("secret"), Hash: sha1.New}).Decode(sha1Value)
+	assert.NoError(t, err)
+	assert.Equal(t, session.Data{"a": int64(1)}, data)
+
+	_, err = (&Cookie{SecretKey: []byte("other")}).Decode(signedValue)
+	assert.Equal(t, ErrInvalid, err)
+
+	_, err = (&Cookie{SecretKey: []byte("secret"), Salt: "other"}).Decode(signedValue)
+	assert.Equal(t, ErrInvalid, err)
+
+	_, err = c.Decode("invalid")
+	assert.Equal(t, ErrInvalid, err)
+}
+
+func TestImport(t *testing.T) {
+	t.Parallel()
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("Cookie", "sessionid="+signedValue)
+
+	s := &session.Session{}
+	c := &Cookie{SecretKey: []byte("secret")}
+	assert.True(t, Import(r, s, "sessionid", c))
+	assert.Equal(t, "1", s.GetString("_auth_user_id"))
+	assert.False(t, Import(r, s, "missing", c))
+}
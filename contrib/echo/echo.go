@@ -0,0 +1,53 @@
+// Package echo provides session middleware for echo
+package echo
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/moonrhythm/session"
+)
+
+// Middleware returns echo middleware from session config
+func Middleware(config session.Config) echo.MiddlewareFunc {
+	return FromManager(session.New(config))
+}
+
+// FromManager returns echo middleware from session manager
+func FromManager(m *session.Manager) echo.MiddlewareFunc {
+	mw := m.Middleware()
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) (err error) {
+			resp := c.Response()
+			rw := resp.Writer
+			mw(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				c.SetRequest(r)
+				resp.Writer = w
+				err = next(c)
+				if err != nil {
+					// handle error here to write error response through session writer
+					c.Error(err)
+				}
+			})).ServeHTTP(rw, c.Request())
+			resp.Writer = rw
+			return
+		}
+	}
+}
+
+// Get gets session from echo context
+func Get(c echo.Context, name string) (*session.Session, error) {
+	return session.Get(c.Request().Context(), name)
+}
+
+// MustGet gets session from echo context,
+// panics if request not pass middleware or failed to get session
+func MustGet(c echo.Context, name string) *session.Session {
+	return session.MustGet(c.Request().Context(), name)
+}
+
+// Default gets default session from echo context, see session.GetDefault
+func Default(c echo.Context) *session.Session {
+	return session.GetDefault(c.Request())
+}
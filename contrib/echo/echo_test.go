@@ -0,0 +1,58 @@
+package echo_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/moonrhythm/session"
+	echosession "github.com/moonrhythm/session/contrib/echo"
+	"github.com/moonrhythm/session/store"
+)
+
+func TestMiddleware(t *testing.T) {
+	t.Parallel()
+
+	e := echo.New()
+	e.Use(echosession.Middleware(session.Config{
+		Store:           new(store.Memory),
+		RegisterDefault: true,
+	}))
+	e.GET("/set", func(c echo.Context) error {
+		echosession.Default(c).Set("a", c.QueryParam("a"))
+		return c.String(http.StatusOK, "ok")
+	})
+	e.GET("/get", func(c echo.Context) error {
+		s, err := echosession.Get(c, session.DefaultName)
+		if err != nil {
+			return err
+		}
+		return c.String(http.StatusOK, s.GetString("a"))
+	})
+	e.GET("/error", func(c echo.Context) error {
+		echosession.MustGet(c, session.DefaultName).Set("a", "error")
+		return echo.ErrForbidden
+	})
+
+	w := httptest.NewRecorder()
+	e.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/set?a=1", nil))
+	assert.Equal(t, "ok", w.Body.String())
+	cs := w.Result().Cookies()
+	if !assert.Len(t, cs, 1) {
+		return
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/get", nil)
+	req.AddCookie(cs[0])
+	w = httptest.NewRecorder()
+	e.ServeHTTP(w, req)
+	assert.Equal(t, "1", w.Body.String())
+
+	w = httptest.NewRecorder()
+	e.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/error", nil))
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Len(t, w.Result().Cookies(), 1, "expected session saved before error response")
+}
@@ -0,0 +1,75 @@
+// Package gin provides session middleware for gin
+package gin
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/moonrhythm/session"
+)
+
+// Middleware returns gin middleware from session config
+func Middleware(config session.Config) gin.HandlerFunc {
+	return FromManager(session.New(config))
+}
+
+// FromManager returns gin middleware from session manager
+func FromManager(m *session.Manager) gin.HandlerFunc {
+	mw := m.Middleware()
+	return func(c *gin.Context) {
+		rw := c.Writer
+		mw(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			c.Request = r
+			c.Writer = &responseWriter{rw, w}
+			c.Next()
+		})).ServeHTTP(rw, c.Request)
+		c.Writer = rw
+	}
+}
+
+// Get gets session from gin context
+func Get(c *gin.Context, name string) (*session.Session, error) {
+	return session.Get(c.Request.Context(), name)
+}
+
+// MustGet gets session from gin context,
+// panics if request not pass middleware or failed to get session
+func MustGet(c *gin.Context, name string) *session.Session {
+	return session.MustGet(c.Request.Context(), name)
+}
+
+// Default gets default session from gin context, see session.GetDefault
+func Default(c *gin.Context) *session.Session {
+	return session.GetDefault(c.Request)
+}
+
+// responseWriter writes response through session middleware's writer,
+// to save session before write header
+type responseWriter struct {
+	gin.ResponseWriter
+	w http.ResponseWriter
+}
+
+func (w *responseWriter) WriteHeader(code int) {
+	w.w.WriteHeader(code)
+}
+
+func (w *responseWriter) Write(b []byte) (int, error) {
+	return w.w.Write(b)
+}
+
+func (w *responseWriter) WriteString(s string) (int, error) {
+	return w.w.Write([]byte(s))
+}
+
+func (w *responseWriter) WriteHeaderNow() {
+	w.w.WriteHeader(w.Status())
+	w.ResponseWriter.WriteHeaderNow()
+}
+
+func (w *responseWriter) Flush() {
+	if f, ok := w.w.(http.Flusher); ok {
+		f.Flush()
+	}
+}
@@ -0,0 +1,61 @@
+package gin_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/moonrhythm/session"
+	ginsession "github.com/moonrhythm/session/contrib/gin"
+	"github.com/moonrhythm/session/store"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+func TestMiddleware(t *testing.T) {
+	t.Parallel()
+
+	r := gin.New()
+	r.Use(ginsession.Middleware(session.Config{
+		Store:           new(store.Memory),
+		RegisterDefault: true,
+	}))
+	r.GET("/set", func(c *gin.Context) {
+		s := ginsession.Default(c)
+		s.Set("a", c.Query("a"))
+		c.String(http.StatusOK, "ok")
+	})
+	r.GET("/get", func(c *gin.Context) {
+		s, err := ginsession.Get(c, session.DefaultName)
+		assert.NoError(t, err)
+		c.String(http.StatusOK, s.GetString("a"))
+	})
+	r.GET("/status", func(c *gin.Context) {
+		ginsession.MustGet(c, session.DefaultName).Set("a", "status")
+		c.Status(http.StatusNoContent)
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/set?a=1", nil))
+	assert.Equal(t, "ok", w.Body.String())
+	cs := w.Result().Cookies()
+	if !assert.Len(t, cs, 1) {
+		return
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/get", nil)
+	req.AddCookie(cs[0])
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, "1", w.Body.String())
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/status", nil))
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Len(t, w.Result().Cookies(), 1, "expected session saved without body")
+}
@@ -0,0 +1,112 @@
+// Package gorilla provides gorilla/sessions compatibility,
+// to migrate gorilla app to session incrementally
+package gorilla
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gorilla/securecookie"
+	"github.com/gorilla/sessions"
+
+	"github.com/moonrhythm/session"
+	"github.com/moonrhythm/session/internal/interop"
+)
+
+// ErrKeyType is the error when gorilla session value has non-string key
+var ErrKeyType = errors.New("session/gorilla: key must be string")
+
+// Store implements sessions.Store on top of session.Manager,
+// session values are loaded from and saved to manager's store
+type Store struct {
+	Manager *session.Manager
+}
+
+// NewStore creates new gorilla store from manager
+func NewStore(m *session.Manager) *Store {
+	return &Store{Manager: m}
+}
+
+// Get returns session from request registry,
+// loads session from manager if not loaded
+func (s *Store) Get(r *http.Request, name string) (*sessions.Session, error) {
+	return sessions.GetRegistry(r).Get(s, name)
+}
+
+// New loads session from manager without adding to request registry
+func (s *Store) New(r *http.Request, name string) (*sessions.Session, error) {
+	gs := sessions.NewSession(s, name)
+	gs.IsNew = true
+
+	ss, err := s.Manager.Get(r, name)
+	if err != nil {
+		return gs, err
+	}
+	gs.ID = ss.ID()
+	gs.IsNew = ss.IsNew()
+	for _, k := range ss.Keys() {
+		gs.Values[k] = ss.Get(k)
+	}
+	return gs, nil
+}
+
+// Save saves gorilla session values to manager,
+// session with negative Options.MaxAge is destroyed
+func (s *Store) Save(r *http.Request, w http.ResponseWriter, gs *sessions.Session) error {
+	ss, err := s.Manager.Get(r, gs.Name())
+	if err != nil {
+		return err
+	}
+
+	if gs.Options != nil && gs.Options.MaxAge < 0 {
+		err = s.Manager.Destroy(r.Context(), ss)
+		if err != nil {
+			return err
+		}
+		return s.Manager.Save(r.Context(), w, ss)
+	}
+
+	for _, k := range ss.Keys() {
+		if _, ok := gs.Values[k]; !ok {
+			ss.Del(k)
+		}
+	}
+	for k, v := range gs.Values {
+		key, ok := k.(string)
+		if !ok {
+			return ErrKeyType
+		}
+		ss.Set(key, v)
+	}
+
+	err = s.Manager.Save(r.Context(), w, ss)
+	if err != nil {
+		return err
+	}
+	gs.ID = ss.ID()
+	gs.IsNew = false
+	return nil
+}
+
+// Import copies values from gorilla securecookie session cookie into session,
+// codecs are the codecs of gorilla cookie store, e.g. sessions.CookieStore.Codecs,
+// returns false if cookie not found or can not be decoded,
+// values with non-string key are skipped
+//
+// Import does not remove gorilla cookie
+func Import(r *http.Request, s *session.Session, name string, codecs ...securecookie.Codec) bool {
+	return interop.Import(r, s, name, func(value string) (session.Data, error) {
+		values := make(map[interface{}]interface{})
+		err := securecookie.DecodeMulti(name, value, &values, codecs...)
+		if err != nil {
+			return nil, err
+		}
+		data := make(session.Data, len(values))
+		for k, v := range values {
+			if key, ok := k.(string); ok {
+				data[key] = v
+			}
+		}
+		return data, nil
+	})
+}
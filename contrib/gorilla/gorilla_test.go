@@ -0,0 +1,91 @@
+package gorilla_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/sessions"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/moonrhythm/session"
+	"github.com/moonrhythm/session/contrib/gorilla"
+	"github.com/moonrhythm/session/store"
+)
+
+func TestStore(t *testing.T) {
+	t.Parallel()
+
+	m := session.New(session.Config{Store: new(store.Memory)})
+	st := gorilla.NewStore(m)
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	gs, err := st.Get(r, "sess")
+	assert.NoError(t, err)
+	assert.True(t, gs.IsNew)
+	gs.Values["a"] = "1"
+	gs.Values["b"] = 2
+	gs.AddFlash("hello")
+	w := httptest.NewRecorder()
+	assert.NoError(t, gs.Save(r, w))
+	cs := w.Result().Cookies()
+	if !assert.Len(t, cs, 1) {
+		return
+	}
+
+	// read by session
+	r = httptest.NewRequest(http.MethodGet, "/", nil)
+	r.AddCookie(cs[0])
+	s, _ := m.Get(r, "sess")
+	assert.False(t, s.IsNew())
+	assert.Equal(t, "1", s.GetString("a"))
+	assert.Equal(t, 2, s.GetInt("b"))
+
+	// read by gorilla
+	gs, err = st.Get(r, "sess")
+	assert.NoError(t, err)
+	assert.False(t, gs.IsNew)
+	assert.Equal(t, s.ID(), gs.ID)
+	assert.Equal(t, "1", gs.Values["a"])
+	assert.Equal(t, []interface{}{"hello"}, gs.Flashes())
+
+	delete(gs.Values, "b")
+	w = httptest.NewRecorder()
+	assert.NoError(t, gs.Save(r, w))
+	s, _ = m.Get(r, "sess")
+	assert.Nil(t, s.Get("b"), "expected deleted value removed")
+	assert.Nil(t, s.Get("_flash"), "expected consumed flash removed")
+
+	gs.Values[1] = "x"
+	assert.Equal(t, gorilla.ErrKeyType, gs.Save(r, httptest.NewRecorder()))
+	delete(gs.Values, 1)
+
+	gs.Options.MaxAge = -1
+	w = httptest.NewRecorder()
+	assert.NoError(t, gs.Save(r, w))
+	s, _ = m.Get(r, "sess")
+	assert.True(t, s.IsNew(), "expected session destroyed")
+}
+
+func TestImport(t *testing.T) {
+	t.Parallel()
+
+	cs := sessions.NewCookieStore([]byte("secret"))
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	gs, _ := cs.Get(r, "gorilla")
+	gs.Values["user"] = "u1"
+	gs.Values[1] = "skip"
+	w := httptest.NewRecorder()
+	assert.NoError(t, gs.Save(r, w))
+
+	m := session.New(session.Config{Store: new(store.Memory)})
+	r = httptest.NewRequest(http.MethodGet, "/", nil)
+	r.AddCookie(w.Result().Cookies()[0])
+	s, _ := m.Get(r, "sess")
+	assert.True(t, gorilla.Import(r, s, "gorilla", cs.Codecs...))
+	assert.Equal(t, "u1", s.GetString("user"))
+	assert.Equal(t, []string{"user"}, s.Keys())
+
+	assert.False(t, gorilla.Import(r, s, "gorilla", sessions.NewCookieStore([]byte("other")).Codecs...))
+	assert.False(t, gorilla.Import(r, s, "missing", cs.Codecs...))
+}
@@ -0,0 +1,162 @@
+// Package grpc provides session interceptors for grpc server
+//
+// Session id is read from incoming metadata the same way as http request header,
+// (cookie, or authorization when use session.BearerTransport)
+// and session is written back in trailer (set-cookie, or token header)
+// for grpc-web clients.
+//
+// Handlers get session from context using session.Get
+package grpc
+
+import (
+	"context"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	"github.com/moonrhythm/session"
+)
+
+// UnaryServerInterceptor returns unary server interceptor from session config
+func UnaryServerInterceptor(config session.Config) grpc.UnaryServerInterceptor {
+	return UnaryFromManager(session.New(config))
+}
+
+// StreamServerInterceptor returns stream server interceptor from session config
+func StreamServerInterceptor(config session.Config) grpc.StreamServerInterceptor {
+	return StreamFromManager(session.New(config))
+}
+
+// UnaryFromManager returns unary server interceptor from session manager
+func UnaryFromManager(m *session.Manager) grpc.UnaryServerInterceptor {
+	mw := m.Middleware()
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp interface{}, err error) {
+		trailer, err := serve(ctx, mw, info.FullMethod, func(ctx context.Context) error {
+			resp, err = handler(ctx, req)
+			return err
+		})
+		if len(trailer) > 0 {
+			grpc.SetTrailer(ctx, trailer)
+		}
+		return resp, err
+	}
+}
+
+// StreamFromManager returns stream server interceptor from session manager
+func StreamFromManager(m *session.Manager) grpc.StreamServerInterceptor {
+	mw := m.Middleware()
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		trailer, err := serve(ss.Context(), mw, info.FullMethod, func(ctx context.Context) error {
+			return handler(srv, &serverStream{ss, ctx})
+		})
+		if len(trailer) > 0 {
+			ss.SetTrailer(trailer)
+		}
+		return err
+	}
+}
+
+// serve runs fn through session middleware,
+// returns header written by session middleware as trailer
+func serve(ctx context.Context, mw func(http.Handler) http.Handler, method string, fn func(ctx context.Context) error) (trailer metadata.MD, err error) {
+	w := &responseWriter{header: make(http.Header)}
+
+	// session middleware saves sessions after fn returned,
+	// panic after fn returned is save error
+	handled := false
+	defer func() {
+		if !handled {
+			return
+		}
+		if v := recover(); v != nil {
+			trailer, err = nil, status.Errorf(codes.Internal, "%v", v)
+		}
+	}()
+
+	mw(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		err = fn(r.Context())
+		handled = true
+	})).ServeHTTP(w, request(ctx, method))
+
+	if w.code >= 400 {
+		// session error handler was called
+		return nil, status.Error(codes.Internal, "session: "+http.StatusText(w.code))
+	}
+
+	trailer = make(metadata.MD)
+	for k, vs := range w.header {
+		trailer.Append(strings.ToLower(k), vs...)
+	}
+	return trailer, err
+}
+
+// request creates http request from incoming metadata
+func request(ctx context.Context, method string) *http.Request {
+	r := &http.Request{
+		Method:     http.MethodPost,
+		URL:        &url.URL{Path: method},
+		RequestURI: method,
+		Proto:      "HTTP/2.0",
+		ProtoMajor: 2,
+		Header:     make(http.Header),
+	}
+
+	md, _ := metadata.FromIncomingContext(ctx)
+	for k, vs := range md {
+		if strings.HasPrefix(k, ":") {
+			continue
+		}
+		r.Header[textproto.CanonicalMIMEHeaderKey(k)] = vs
+	}
+	if vs := md.Get(":authority"); len(vs) > 0 {
+		r.Host = vs[0]
+	}
+
+	if p, ok := peer.FromContext(ctx); ok {
+		if p.Addr != nil {
+			r.RemoteAddr = p.Addr.String()
+		}
+		if info, ok := p.AuthInfo.(credentials.TLSInfo); ok {
+			r.TLS = &info.State
+		}
+	}
+
+	return r.WithContext(ctx)
+}
+
+type responseWriter struct {
+	header http.Header
+	code   int
+}
+
+func (w *responseWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *responseWriter) WriteHeader(code int) {
+	if w.code == 0 {
+		w.code = code
+	}
+}
+
+func (w *responseWriter) Write(b []byte) (int, error) {
+	w.WriteHeader(http.StatusOK)
+	return len(b), nil
+}
+
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
@@ -0,0 +1,126 @@
+package grpc_test
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+
+	"github.com/moonrhythm/session"
+	grpcsession "github.com/moonrhythm/session/contrib/grpc"
+	"github.com/moonrhythm/session/store"
+)
+
+type transportStream struct {
+	trailer metadata.MD
+}
+
+func (s *transportStream) Method() string               { return "/test/Method" }
+func (s *transportStream) SetHeader(metadata.MD) error  { return nil }
+func (s *transportStream) SendHeader(metadata.MD) error { return nil }
+func (s *transportStream) SetTrailer(md metadata.MD) error {
+	s.trailer = metadata.Join(s.trailer, md)
+	return nil
+}
+
+type serverStream struct {
+	grpc.ServerStream
+	ctx     context.Context
+	trailer metadata.MD
+}
+
+func (s *serverStream) Context() context.Context  { return s.ctx }
+func (s *serverStream) SetTrailer(md metadata.MD) { s.trailer = metadata.Join(s.trailer, md) }
+
+func incomingContext(cookie string) context.Context {
+	ctx := peer.NewContext(context.Background(), &peer.Peer{Addr: &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 1234}})
+	md := metadata.Pairs(":authority", "example.com")
+	if cookie != "" {
+		md.Append("cookie", cookie)
+	}
+	return metadata.NewIncomingContext(ctx, md)
+}
+
+// cookieFromTrailer converts set-cookie trailer to cookie header value
+func cookieFromTrailer(md metadata.MD) string {
+	resp := http.Response{Header: http.Header{"Set-Cookie": md.Get("set-cookie")}}
+	cs := resp.Cookies()
+	if len(cs) == 0 {
+		return ""
+	}
+	return (&http.Cookie{Name: cs[0].Name, Value: cs[0].Value}).String()
+}
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	t.Parallel()
+
+	interceptor := grpcsession.UnaryServerInterceptor(session.Config{Store: new(store.Memory)})
+	info := &grpc.UnaryServerInfo{FullMethod: "/test/Method"}
+
+	call := func(cookie string, handler grpc.UnaryHandler) (interface{}, metadata.MD, error) {
+		ts := new(transportStream)
+		ctx := grpc.NewContextWithServerTransportStream(incomingContext(cookie), ts)
+		resp, err := interceptor(ctx, nil, info, handler)
+		return resp, ts.trailer, err
+	}
+
+	_, trailer, err := call("", func(ctx context.Context, req interface{}) (interface{}, error) {
+		s, err := session.Get(ctx, "sess")
+		if err != nil {
+			return nil, err
+		}
+		s.Set("a", "1")
+		return "ok", nil
+	})
+	assert.NoError(t, err)
+	cookie := cookieFromTrailer(trailer)
+	if !assert.NotEmpty(t, cookie, "expected set-cookie trailer") {
+		return
+	}
+
+	resp, _, err := call(cookie, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return session.MustGet(ctx, "sess").GetString("a"), nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "1", resp)
+
+	_, trailer, err = call("", func(ctx context.Context, req interface{}) (interface{}, error) {
+		return nil, session.ErrNotFound
+	})
+	assert.Equal(t, session.ErrNotFound, err)
+	assert.Empty(t, trailer.Get("set-cookie"), "expected no cookie for unchanged session")
+}
+
+func TestStreamServerInterceptor(t *testing.T) {
+	t.Parallel()
+
+	interceptor := grpcsession.StreamServerInterceptor(session.Config{Store: new(store.Memory)})
+	info := &grpc.StreamServerInfo{FullMethod: "/test/Stream"}
+
+	call := func(cookie string, handler grpc.StreamHandler) (metadata.MD, error) {
+		ss := &serverStream{ctx: incomingContext(cookie)}
+		err := interceptor(nil, ss, info, handler)
+		return ss.trailer, err
+	}
+
+	trailer, err := call("", func(srv interface{}, ss grpc.ServerStream) error {
+		session.MustGet(ss.Context(), "sess").Set("a", "1")
+		return nil
+	})
+	assert.NoError(t, err)
+	cookie := cookieFromTrailer(trailer)
+	if !assert.NotEmpty(t, cookie, "expected set-cookie trailer") {
+		return
+	}
+
+	_, err = call(cookie, func(srv interface{}, ss grpc.ServerStream) error {
+		assert.Equal(t, "1", session.MustGet(ss.Context(), "sess").GetString("a"))
+		return nil
+	})
+	assert.NoError(t, err)
+}
@@ -0,0 +1,214 @@
+// Package rails provides decoder for Rails cookie store session,
+// to share login state with Rails application
+package rails
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"hash"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/pbkdf2"
+
+	"github.com/moonrhythm/session"
+	"github.com/moonrhythm/session/internal/interop"
+)
+
+// ErrInvalid is the error when cookie can not be verified or decrypted
+var ErrInvalid = errors.New("session/rails: invalid cookie")
+
+// Key generator salts, Rails defaults
+const (
+	EncryptedCookieSalt = "authenticated encrypted cookie"
+	SignedCookieSalt    = "signed cookie"
+)
+
+// Cookie decodes Rails cookie store session (ActionDispatch::Session::CookieStore)
+//
+// encrypted cookie uses MessageEncryptor with aes-256-gcm (Rails 5.2+),
+// signed cookie uses MessageVerifier, session must be serialized as JSON
+//
+// Cookie must not be modified after first used, keys are derived once
+type Cookie struct {
+	// SecretKeyBase is the Rails secret_key_base
+	SecretKeyBase []byte
+
+	// Hash is the key generator and signed cookie digest,
+	// default is SHA1 (Rails 5.2 - 6.1), Rails 7.0+ defaults is SHA256
+	Hash func() hash.Hash
+
+	// Signed decodes signed cookie instead of encrypted cookie
+	Signed bool
+
+	once    sync.Once
+	encKey  []byte
+	signKey []byte
+}
+
+func (c *Cookie) hash() func() hash.Hash {
+	if c.Hash == nil {
+		return sha1.New
+	}
+	return c.Hash
+}
+
+// init derives keys from secret key base like ActiveSupport::KeyGenerator
+func (c *Cookie) init() {
+	c.once.Do(func() {
+		if c.Signed {
+			c.signKey = pbkdf2.Key(c.SecretKeyBase, []byte(SignedCookieSalt), 1000, 64, c.hash())
+			return
+		}
+		c.encKey = pbkdf2.Key(c.SecretKeyBase, []byte(EncryptedCookieSalt), 1000, 32, c.hash())
+	})
+}
+
+// Decode verifies and decodes cookie value into session data,
+// name is the Rails session cookie name, e.g. "_app_session",
+// integers decode as int64
+func (c *Cookie) Decode(name, value string) (session.Data, error) {
+	// rack escapes cookie value
+	if v, err := url.QueryUnescape(value); err == nil {
+		value = v
+	}
+
+	c.init()
+	var p []byte
+	var err error
+	if c.Signed {
+		p, err = c.verify(value)
+	} else {
+		p, err = c.decrypt(value)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	p, err = unwrap(p, "cookie."+name)
+	if err != nil {
+		return nil, err
+	}
+	return decodeJSON(p)
+}
+
+func (c *Cookie) decrypt(value string) ([]byte, error) {
+	parts := strings.Split(value, "--")
+	if len(parts) != 3 {
+		return nil, ErrInvalid
+	}
+	var b [3][]byte
+	for i, p := range parts {
+		var err error
+		b[i], err = base64.StdEncoding.DecodeString(p)
+		if err != nil {
+			return nil, ErrInvalid
+		}
+	}
+	ciphertext, iv, tag := b[0], b[1], b[2]
+
+	block, err := aes.NewCipher(c.encKey)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCMWithNonceSize(block, len(iv))
+	if err != nil {
+		return nil, ErrInvalid
+	}
+	p, err := aead.Open(nil, iv, append(ciphertext, tag...), nil)
+	if err != nil {
+		return nil, ErrInvalid
+	}
+	return p, nil
+}
+
+func (c *Cookie) verify(value string) ([]byte, error) {
+	i := strings.LastIndex(value, "--")
+	if i < 0 {
+		return nil, ErrInvalid
+	}
+	data, digest := value[:i], value[i+2:]
+	sig, err := hex.DecodeString(digest)
+	if err != nil {
+		return nil, ErrInvalid
+	}
+
+	h := hmac.New(c.hash(), c.signKey)
+	h.Write([]byte(data))
+	if !hmac.Equal(sig, h.Sum(nil)) {
+		return nil, ErrInvalid
+	}
+
+	p, err := base64.StdEncoding.DecodeString(data)
+	if err != nil {
+		return nil, ErrInvalid
+	}
+	return p, nil
+}
+
+// unwrap returns message from Rails metadata envelope, and checks purpose and expiration,
+// message without envelope is returned as is
+func unwrap(p []byte, purpose string) ([]byte, error) {
+	var envelope struct {
+		Rails *struct {
+			Message string          `json:"message"`
+			Data    json.RawMessage `json:"data"`
+			Exp     *string         `json:"exp"`
+			Pur     string          `json:"pur"`
+		} `json:"_rails"`
+	}
+	if err := json.Unmarshal(p, &envelope); err != nil {
+		return nil, ErrInvalid
+	}
+	m := envelope.Rails
+	if m == nil {
+		return p, nil
+	}
+
+	if m.Pur != "" && m.Pur != purpose {
+		return nil, ErrInvalid
+	}
+	if m.Exp != nil {
+		exp, err := time.Parse(time.RFC3339, *m.Exp)
+		if err != nil || time.Now().After(exp) {
+			return nil, ErrInvalid
+		}
+	}
+	if len(m.Data) > 0 {
+		return m.Data, nil
+	}
+	b, err := base64.StdEncoding.DecodeString(m.Message)
+	if err != nil {
+		return nil, ErrInvalid
+	}
+	return b, nil
+}
+
+// decodeJSON decodes json object into session data, integers decode as int64
+func decodeJSON(p []byte) (session.Data, error) {
+	data, err := interop.DecodeJSON(bytes.NewReader(p))
+	if err != nil {
+		return nil, ErrInvalid
+	}
+	return data, nil
+}
+
+// Import copies values from Rails session cookie into session,
+// returns false if cookie not found or can not be decoded
+//
+// Import does not remove Rails cookie
+func Import(r *http.Request, s *session.Session, name string, c *Cookie) bool {
+	return interop.Import(r, s, name, func(value string) (session.Data, error) {
+		return c.Decode(name, value)
+	})
+}
@@ -0,0 +1,106 @@
+package rails
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/moonrhythm/session"
+)
+
+func TestCookieKey(t *testing.T) {
+	t.Parallel()
+
+	// generated by ActiveSupport::KeyGenerator
+	c := &Cookie{SecretKeyBase: []byte("secret_key_base")}
+	c.init()
+	assert.Equal(t, "f131f32634d09ded0399c579217f0bc2763437e2c69d45c8900bd9d3568af199", hex.EncodeToString(c.encKey))
+
+	key := c.encKey
+	c.SecretKeyBase = []byte("other")
+	c.init()
+	assert.Equal(t, key, c.encKey, "expected key derived once")
+}
+
+func TestSignedCookie(t *testing.T) {
+	t.Parallel()
+
+	value := "eyJfcmFpbHMiOnsibWVzc2FnZSI6ImV5SnpaWE56YVc5dVgybGtJam9pWVdKaklpd2lkWE5sY2w5cFpDSTZORElzSWw5amMzSm1YM1J2YTJWdUlqb2lkQ0o5IiwiZXhwIjpudWxsLCJwdXIiOiJjb29raWUuX2FwcF9zZXNzaW9uIn19--102be5a8cf12f40ecd0ba41281a372570b099ac8"
+	c := &Cookie{SecretKeyBase: []byte("secret_key_base"), Signed: true}
+
+	data, err := c.Decode("_app_session", value)
+	assert.NoError(t, err)
+	assert.Equal(t, session.Data{
+		"session_id":  "abc",
+		"user_id":     int64(42),
+		"_csrf_token": "t",
+	}, data)
+
+	_, err = c.Decode("other", value)
+	assert.Equal(t, ErrInvalid, err, "expected purpose mismatch rejected")
+
+	_, err = (&Cookie{SecretKeyBase: []byte("other"), Signed: true}).Decode("_app_session", value)
+	assert.Equal(t, ErrInvalid, err)
+}
+
+// encrypt encrypts message like ActiveSupport::MessageEncryptor aes-256-gcm
+func encrypt(t *testing.T, c *Cookie, message string) string {
+	c.init()
+	block, _ := aes.NewCipher(c.encKey)
+	aead, _ := cipher.NewGCM(block)
+	iv := []byte("0123456789ab")
+	sealed := aead.Seal(nil, iv, []byte(message), nil)
+	n := len(sealed) - aead.Overhead()
+
+	enc := base64.StdEncoding.EncodeToString
+	return url.QueryEscape(enc(sealed[:n]) + "--" + enc(iv) + "--" + enc(sealed[n:]))
+}
+
+func TestEncryptedCookie(t *testing.T) {
+	t.Parallel()
+
+	c := &Cookie{SecretKeyBase: []byte("secret_key_base"), Hash: sha256.New}
+
+	// Rails 5.2+
+	value := encrypt(t, c, `{"_rails":{"message":"eyJ1c2VyX2lkIjo0Mn0=","exp":null,"pur":"cookie._app_session"}}`)
+	data, err := c.Decode("_app_session", value)
+	assert.NoError(t, err)
+	assert.Equal(t, session.Data{"user_id": int64(42)}, data)
+
+	// Rails 7.1+ metadata
+	value = encrypt(t, c, `{"_rails":{"data":{"user_id":1.5},"pur":"cookie._app_session"}}`)
+	data, err = c.Decode("_app_session", value)
+	assert.NoError(t, err)
+	assert.Equal(t, session.Data{"user_id": 1.5}, data)
+
+	value = encrypt(t, c, `{"_rails":{"data":{},"exp":"2000-01-01T00:00:00.000Z","pur":"cookie._app_session"}}`)
+	_, err = c.Decode("_app_session", value)
+	assert.Equal(t, ErrInvalid, err, "expected expired cookie rejected")
+
+	_, err = (&Cookie{SecretKeyBase: []byte("secret_key_base")}).Decode("_app_session", value)
+	assert.Equal(t, ErrInvalid, err, "expected different digest rejected")
+
+	_, err = c.Decode("_app_session", "invalid")
+	assert.Equal(t, ErrInvalid, err)
+}
+
+func TestImport(t *testing.T) {
+	t.Parallel()
+
+	c := &Cookie{SecretKeyBase: []byte("secret_key_base")}
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("Cookie", "_app_session="+encrypt(t, c, `{"user_id":42}`))
+
+	s := &session.Session{}
+	assert.True(t, Import(r, s, "_app_session", c))
+	assert.Equal(t, int64(42), s.GetInt64("user_id"))
+	assert.False(t, Import(r, s, "missing", c))
+}
@@ -0,0 +1,107 @@
+// Package scs provides adapter to use alexedwards/scs store backends as session.Store
+//
+// Store and CtxStore have the same method sets as scs.Store and scs.CtxStore,
+// so any scs store can be used without importing scs
+package scs
+
+import (
+	"bytes"
+	"context"
+	"time"
+
+	"github.com/moonrhythm/session"
+)
+
+// DefaultTTL is the ttl when session has no ttl, the same as scs default lifetime,
+// scs stores require expiry
+const DefaultTTL = 24 * time.Hour
+
+// Store is the scs.Store interface
+type Store interface {
+	Delete(token string) (err error)
+	Find(token string) (b []byte, found bool, err error)
+	Commit(token string, b []byte, expiry time.Time) (err error)
+}
+
+// CtxStore is the scs.CtxStore interface,
+// adapter uses context methods if store implements CtxStore
+type CtxStore interface {
+	Store
+
+	DeleteCtx(ctx context.Context, token string) (err error)
+	FindCtx(ctx context.Context, token string) (b []byte, found bool, err error)
+	CommitCtx(ctx context.Context, token string, b []byte, expiry time.Time) (err error)
+}
+
+// Adapter wraps scs store as session.Store
+type Adapter struct {
+	Store Store
+	Coder session.StoreCoder
+}
+
+// New creates new adapter
+func New(st Store) *Adapter {
+	return &Adapter{Store: st}
+}
+
+func (s *Adapter) coder() session.StoreCoder {
+	if s.Coder == nil {
+		return session.DefaultStoreCoder
+	}
+	return s.Coder
+}
+
+// Get gets session data from scs store
+func (s *Adapter) Get(ctx context.Context, key string) (session.Data, error) {
+	var (
+		b     []byte
+		found bool
+		err   error
+	)
+	if st, ok := s.Store.(CtxStore); ok {
+		b, found, err = st.FindCtx(ctx, key)
+	} else {
+		b, found, err = s.Store.Find(key)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, session.ErrNotFound
+	}
+
+	var data session.Data
+	err = s.coder().NewDecoder(bytes.NewReader(b)).Decode(&data)
+	if err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// Set sets session data to scs store
+func (s *Adapter) Set(ctx context.Context, key string, value session.Data, opt session.StoreOption) error {
+	var buf bytes.Buffer
+	err := s.coder().NewEncoder(&buf).Encode(value)
+	if err != nil {
+		return err
+	}
+
+	ttl := opt.TTL
+	if ttl <= 0 {
+		ttl = DefaultTTL
+	}
+	expiry := time.Now().Add(ttl)
+
+	if st, ok := s.Store.(CtxStore); ok {
+		return st.CommitCtx(ctx, key, buf.Bytes(), expiry)
+	}
+	return s.Store.Commit(key, buf.Bytes(), expiry)
+}
+
+// Del deletes session data from scs store
+func (s *Adapter) Del(ctx context.Context, key string) error {
+	if st, ok := s.Store.(CtxStore); ok {
+		return st.DeleteCtx(ctx, key)
+	}
+	return s.Store.Delete(key)
+}
@@ -0,0 +1,106 @@
+package scs
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/moonrhythm/session"
+)
+
+// memStore is the in-memory scs store
+type memStore struct {
+	mu    sync.Mutex
+	items map[string]memItem
+}
+
+type memItem struct {
+	b      []byte
+	expiry time.Time
+}
+
+func (s *memStore) Delete(token string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.items, token)
+	return nil
+}
+
+func (s *memStore) Find(token string) ([]byte, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	item, ok := s.items[token]
+	if !ok || time.Now().After(item.expiry) {
+		return nil, false, nil
+	}
+	return item.b, true, nil
+}
+
+func (s *memStore) Commit(token string, b []byte, expiry time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.items == nil {
+		s.items = make(map[string]memItem)
+	}
+	s.items[token] = memItem{b, expiry}
+	return nil
+}
+
+// memCtxStore is the in-memory scs store with context methods
+type memCtxStore struct {
+	memStore
+	calls int
+}
+
+func (s *memCtxStore) DeleteCtx(ctx context.Context, token string) error {
+	s.calls++
+	return s.Delete(token)
+}
+
+func (s *memCtxStore) FindCtx(ctx context.Context, token string) ([]byte, bool, error) {
+	s.calls++
+	return s.Find(token)
+}
+
+func (s *memCtxStore) CommitCtx(ctx context.Context, token string, b []byte, expiry time.Time) error {
+	s.calls++
+	return s.Commit(token, b, expiry)
+}
+
+func testAdapter(t *testing.T, s *Adapter) {
+	ctx := context.Background()
+
+	_, err := s.Get(ctx, "a")
+	assert.Equal(t, session.ErrNotFound, err)
+
+	assert.NoError(t, s.Set(ctx, "a", session.Data{"k": "v"}, session.StoreOption{TTL: time.Minute}))
+	data, err := s.Get(ctx, "a")
+	assert.NoError(t, err)
+	assert.Equal(t, session.Data{"k": "v"}, data)
+
+	assert.NoError(t, s.Set(ctx, "b", session.Data{"k": "v"}, session.StoreOption{}))
+	_, err = s.Get(ctx, "b")
+	assert.NoError(t, err, "expected session without ttl use default ttl")
+
+	assert.NoError(t, s.Set(ctx, "c", session.Data{"k": "v"}, session.StoreOption{TTL: time.Millisecond}))
+	time.Sleep(5 * time.Millisecond)
+	_, err = s.Get(ctx, "c")
+	assert.Equal(t, session.ErrNotFound, err)
+
+	assert.NoError(t, s.Del(ctx, "a"))
+	_, err = s.Get(ctx, "a")
+	assert.Equal(t, session.ErrNotFound, err)
+}
+
+func TestAdapter(t *testing.T) {
+	t.Parallel()
+
+	testAdapter(t, New(new(memStore)))
+
+	st := new(memCtxStore)
+	testAdapter(t, New(st))
+	assert.NotZero(t, st.calls, "expected context methods used")
+}
@@ -0,0 +1,173 @@
+package session
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// cookieMaxSize is the max size of cookie that browsers accept,
+// counts name, value and attributes
+const cookieMaxSize = 4096
+
+// cookieValueSize returns the max value size that cs can hold
+func cookieValueSize(cs http.Cookie, partitioned bool) int {
+	cs.Value = ""
+	n := 0
+	if partitioned {
+		cs.Secure = true
+		n = len("; Partitioned")
+	}
+	n += len(cs.String())
+	return cookieMaxSize - n
+}
+
+// addCookie adds Set-Cookie header,
+// appends Partitioned attribute manually since net/http does not support it yet
+func addCookie(w http.ResponseWriter, cs *http.Cookie, partitioned bool) {
+	if !partitioned {
+		http.SetCookie(w, cs)
+		return
+	}
+
+	// partitioned cookie must be secure
+	cs.Secure = true
+	if v := cs.String(); v != "" {
+		w.Header().Add("Set-Cookie", v+"; Partitioned")
+	}
+}
+
+// loadCookieData loads session data from cookie when store session data in cookie
+func (m *Manager) loadCookieData(r *http.Request, s *Session) error {
+	value, chunks := readCookieValue(r, m.cookieName(s.Name))
+	s.chunks = chunks
+	if len(value) == 0 {
+		return nil
+	}
+
+	// invalid or expired cookie is treated as not found
+	data, err := m.cookieStore.DecodeCookie(s.Name, value)
+	if err != nil {
+		return nil
+	}
+	rawID, _ := data[cookieIDKey].(string)
+	if len(rawID) == 0 {
+		return nil
+	}
+	hashedID := m.hashID(rawID)
+	if m.config.RevocationList != nil {
+		revoked, err := m.config.RevocationList.Revoked(r.Context(), hashedID)
+		if err != nil {
+			return err
+		}
+		if revoked {
+			return nil
+		}
+	}
+	return m.loaded(s, rawID, hashedID, data, true)
+}
+
+// readCookieValue reads cookie value,
+// large value is split into chunks name.0, name.1, ...
+func readCookieValue(r *http.Request, name string) (value string, chunks int) {
+	if cookie, err := r.Cookie(name); err == nil {
+		value = cookie.Value
+	}
+
+	var b strings.Builder
+	for ; ; chunks++ {
+		cookie, err := r.Cookie(chunkName(name, chunks))
+		if err != nil {
+			break
+		}
+		b.WriteString(cookie.Value)
+	}
+	if len(value) == 0 {
+		value = b.String()
+	}
+	return
+}
+
+func chunkName(name string, i int) string {
+	return name + "." + strconv.Itoa(i)
+}
+
+// saveCookie encodes session data into cookie
+func (m *Manager) saveCookie(ctx context.Context, w http.ResponseWriter, s *Session) error {
+	if s.destroyed && !s.Changed() {
+		m.writeCookieValue(w, s, "")
+		return nil
+	}
+
+	ok, err := m.prepareSave(ctx, w, s)
+	if err != nil {
+		return err
+	}
+	// rolling session must rewrite cookie to extend expiration
+	if !ok && !((s.Rolling || s.touched) && !s.isNew) {
+		return nil
+	}
+
+	s.Set(cookieIDKey, s.rawID)
+	var value string
+	err = m.fitDataSize(s, func() (err error) {
+		value, err = m.cookieStore.EncodeCookie(s.Name, s.data, makeStoreOption(m, s))
+		if err == nil && m.config.MaxDataSize > 0 && len(value) > m.config.MaxDataSize {
+			return &dataTooLargeError{size: len(value)}
+		}
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	m.writeCookieValue(w, s, value)
+	m.onCreate(ctx, s)
+	return nil
+}
+
+// writeCookieValue writes value into cookie, or chunks if value too large,
+// and removes stale cookies, empty value removes all cookies
+func (m *Manager) writeCookieValue(w http.ResponseWriter, s *Session, value string) {
+	// remove removes chunk cookie, or main cookie if chunk is negative
+	remove := func(chunk int) {
+		cs := m.cookie(s, "")
+		if chunk >= 0 {
+			cs.Name = chunkName(cs.Name, chunk)
+		}
+		cs.MaxAge = -1
+		cs.Expires = time.Unix(0, 0)
+		addCookie(w, &cs, s.Partitioned)
+	}
+
+	chunks := 0
+	switch cs := m.cookie(s, value); {
+	case len(value) == 0:
+		remove(-1)
+	case len(value) <= cookieValueSize(cs, s.Partitioned):
+		addCookie(w, &cs, s.Partitioned)
+	default:
+		remove(-1)
+		for ; len(value) > 0; chunks++ {
+			cs := m.cookie(s, "")
+			cs.Name = chunkName(cs.Name, chunks)
+			n := cookieValueSize(cs, s.Partitioned)
+			if n > len(value) {
+				n = len(value)
+			}
+			if n < 1 {
+				n = 1
+			}
+			cs.Value = value[:n]
+			addCookie(w, &cs, s.Partitioned)
+			value = value[n:]
+		}
+	}
+
+	// remove stale chunks from previous response
+	for i := chunks; i < s.chunks; i++ {
+		remove(i)
+	}
+	s.chunks = chunks
+}
@@ -0,0 +1,140 @@
+// Package csrf provides csrf token stored in session
+package csrf
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"net/http"
+
+	"github.com/moonrhythm/session"
+)
+
+// Config is the csrf middleware config
+type Config struct {
+	// SessionName is the session name to store token, default is session.DefaultName
+	SessionName string
+
+	// Key is the session key to store token secret, default is "csrf"
+	Key string
+
+	// FieldName is the form field name for token, default is "csrf"
+	FieldName string
+
+	// HeaderName is the request header name for token, default is "X-CSRF-Token"
+	HeaderName string
+
+	// ErrorHandler handles request with invalid token, default responses 403 Forbidden
+	ErrorHandler http.Handler
+}
+
+type ctxKey struct{}
+
+// Middleware verifies csrf token for unsafe methods
+//
+// Middleware must be used after session middleware
+func Middleware(config Config) func(http.Handler) http.Handler {
+	if config.SessionName == "" {
+		config.SessionName = session.DefaultName
+	}
+	if config.Key == "" {
+		config.Key = "csrf"
+	}
+	if config.FieldName == "" {
+		config.FieldName = "csrf"
+	}
+	if config.HeaderName == "" {
+		config.HeaderName = "X-CSRF-Token"
+	}
+	if config.ErrorHandler == nil {
+		config.ErrorHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "Forbidden", http.StatusForbidden)
+		})
+	}
+
+	return func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			s, err := session.Get(r.Context(), config.SessionName)
+			if err != nil {
+				http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+				return
+			}
+
+			if !safeMethod(r.Method) && !verify(s, &config, requestToken(r, &config)) {
+				config.ErrorHandler.ServeHTTP(w, r)
+				return
+			}
+
+			ctx := context.WithValue(r.Context(), ctxKey{}, &config)
+			h.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// Token returns csrf token for the request,
+// returns empty string if request not pass middleware
+//
+// Token is bound to session id, so token changes after session regenerated,
+// Token generates token secret into session on first use,
+// so Token must be called before write response
+func Token(ctx context.Context) string {
+	config, _ := ctx.Value(ctxKey{}).(*Config)
+	if config == nil {
+		return ""
+	}
+	s, err := session.Get(ctx, config.SessionName)
+	if err != nil {
+		return ""
+	}
+	return token(s, config)
+}
+
+func token(s *session.Session, config *Config) string {
+	secret := s.GetString(config.Key)
+	if secret == "" {
+		secret = generateToken()
+		s.Set(config.Key, secret)
+	}
+	return sign(secret, s.ID())
+}
+
+// sign derives token from secret and session id
+func sign(secret, id string) string {
+	h := hmac.New(sha256.New, []byte(secret))
+	h.Write([]byte(id))
+	return base64.RawURLEncoding.EncodeToString(h.Sum(nil))
+}
+
+func generateToken() string {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		panic("csrf: can not generate token; " + err.Error())
+	}
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func safeMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		return true
+	}
+	return false
+}
+
+func requestToken(r *http.Request, config *Config) string {
+	if t := r.Header.Get(config.HeaderName); t != "" {
+		return t
+	}
+	return r.PostFormValue(config.FieldName)
+}
+
+func verify(s *session.Session, config *Config, value string) bool {
+	secret := s.GetString(config.Key)
+	if secret == "" || value == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(sign(secret, s.ID())), []byte(value)) == 1
+}
@@ -0,0 +1,158 @@
+package csrf_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/moonrhythm/session"
+	"github.com/moonrhythm/session/csrf"
+	"github.com/moonrhythm/session/store"
+)
+
+func TestCSRF(t *testing.T) {
+	t.Parallel()
+
+	h := session.Middleware(session.Config{
+		Store: new(store.Memory),
+	})(csrf.Middleware(csrf.Config{})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(csrf.Token(r.Context())))
+	})))
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	token := w.Body.String()
+	assert.NotEmpty(t, token)
+	cs := w.Result().Cookies()
+	if !assert.Len(t, cs, 1) {
+		return
+	}
+
+	t.Run("Same token", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.AddCookie(cs[0])
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		assert.Equal(t, token, w.Body.String())
+	})
+
+	t.Run("Missing token", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodPost, "/", nil)
+		r.AddCookie(cs[0])
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		assert.Equal(t, http.StatusForbidden, w.Code)
+	})
+
+	t.Run("Invalid token", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodPost, "/", nil)
+		r.AddCookie(cs[0])
+		r.Header.Set("X-CSRF-Token", "invalid")
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		assert.Equal(t, http.StatusForbidden, w.Code)
+	})
+
+	t.Run("Header", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodDelete, "/", nil)
+		r.AddCookie(cs[0])
+		r.Header.Set("X-CSRF-Token", token)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		assert.Equal(t, http.StatusOK, w.Code)
+	})
+
+	t.Run("Form", func(t *testing.T) {
+		form := url.Values{"csrf": {token}}
+		r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(form.Encode()))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		r.AddCookie(cs[0])
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		assert.Equal(t, http.StatusOK, w.Code)
+	})
+
+	t.Run("Without session", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodPost, "/", nil)
+		r.Header.Set("X-CSRF-Token", token)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		assert.Equal(t, http.StatusForbidden, w.Code)
+	})
+}
+
+func TestCSRFLazy(t *testing.T) {
+	t.Parallel()
+
+	h := session.Middleware(session.Config{
+		Store: new(store.Memory),
+	})(csrf.Middleware(csrf.Config{})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	})))
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, w.Result().Cookies(), "expected token not generated when not used")
+}
+
+func TestCSRFRegenerate(t *testing.T) {
+	t.Parallel()
+
+	h := session.Middleware(session.Config{
+		Store: new(store.Memory),
+	})(csrf.Middleware(csrf.Config{})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/login" {
+			s, _ := session.Get(r.Context(), session.DefaultName)
+			s.Regenerate()
+		}
+		w.Write([]byte(csrf.Token(r.Context())))
+	})))
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	token := w.Body.String()
+	cs := w.Result().Cookies()
+	if !assert.Len(t, cs, 1) {
+		return
+	}
+
+	r := httptest.NewRequest(http.MethodPost, "/login", nil)
+	r.AddCookie(cs[0])
+	r.Header.Set("X-CSRF-Token", token)
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusOK, w.Code)
+	newToken := w.Body.String()
+	assert.NotEqual(t, token, newToken, "expected token rotated after regenerate")
+	cs = w.Result().Cookies()
+	if !assert.Len(t, cs, 1) {
+		return
+	}
+
+	r = httptest.NewRequest(http.MethodPost, "/", nil)
+	r.AddCookie(cs[0])
+	r.Header.Set("X-CSRF-Token", token)
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusForbidden, w.Code, "expected token before regenerate rejected")
+
+	r = httptest.NewRequest(http.MethodPost, "/", nil)
+	r.AddCookie(cs[0])
+	r.Header.Set("X-CSRF-Token", newToken)
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestTokenWithoutMiddleware(t *testing.T) {
+	t.Parallel()
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	assert.Empty(t, csrf.Token(r.Context()))
+}
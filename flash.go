@@ -3,6 +3,16 @@ package session
 import (
 	"bytes"
 	"encoding/gob"
+	"sort"
+)
+
+// Flash categories for list-style messages,
+// f.Add(FlashError, msg) then f.Values(FlashError)
+const (
+	FlashInfo    = "info"
+	FlashSuccess = "success"
+	FlashWarning = "warning"
+	FlashError   = "error"
 )
 
 type flashData map[string][]interface{}
@@ -11,6 +21,13 @@ type flashData map[string][]interface{}
 type Flash struct {
 	v       flashData
 	changed bool
+	onWrite func(key string) // reports late write to session
+}
+
+func (f *Flash) written(key string) {
+	if f.onWrite != nil {
+		f.onWrite(key)
+	}
 }
 
 func (f *Flash) decode(b []byte) error {
@@ -57,6 +74,7 @@ func (f *Flash) Set(key string, value interface{}) {
 		f.v = make(flashData)
 	}
 	f.v[key] = []interface{}{value}
+	f.written(key)
 }
 
 // Add adds value to flash
@@ -68,6 +86,7 @@ func (f *Flash) Add(key string, value interface{}) {
 		f.v = make(flashData)
 	}
 	f.v[key] = append(f.v[key], value)
+	f.written(key)
 }
 
 // Get gets value from flash
@@ -126,6 +145,22 @@ func (f *Flash) Del(key string) {
 	delete(f.v, key)
 }
 
+// Peek gets value from flash without delete the key
+func (f *Flash) Peek(key string) interface{} {
+	if !f.Has(key) {
+		return nil
+	}
+	return f.v[key][0]
+}
+
+// PeekValues gets values of given key without delete the key
+func (f *Flash) PeekValues(key string) []interface{} {
+	if !f.Has(key) {
+		return []interface{}{}
+	}
+	return append([]interface{}{}, f.v[key]...)
+}
+
 // Has checks is flash has a given key
 func (f *Flash) Has(key string) bool {
 	if f.v == nil {
@@ -134,6 +169,18 @@ func (f *Flash) Has(key string) bool {
 	return len(f.v[key]) > 0
 }
 
+// Keys returns sorted keys (categories) of flash
+func (f *Flash) Keys() []string {
+	r := make([]string, 0, len(f.v))
+	for k, vv := range f.v {
+		if len(vv) > 0 {
+			r = append(r, k)
+		}
+	}
+	sort.Strings(r)
+	return r
+}
+
 // Clear deletes all data
 func (f *Flash) Clear() {
 	if f.Count() > 0 {
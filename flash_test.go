@@ -238,3 +238,39 @@ func TestFlashGet(t *testing.T) {
 		assert.Zero(t, f.GetBool("a"))
 	})
 }
+
+func TestFlashCategories(t *testing.T) {
+	t.Parallel()
+
+	f := new(Flash)
+	assert.Empty(t, f.Keys())
+
+	f.Add(FlashError, "invalid email")
+	f.Add(FlashError, "invalid password")
+	f.Add(FlashInfo, "try again")
+	assert.Equal(t, []string{FlashError, FlashInfo}, f.Keys())
+
+	assert.Equal(t, []interface{}{"invalid email", "invalid password"}, f.Values(FlashError))
+	assert.Empty(t, f.Values(FlashError), "expected values consumed")
+	assert.Equal(t, []string{FlashInfo}, f.Keys())
+}
+
+func TestFlashPeek(t *testing.T) {
+	t.Parallel()
+
+	f := new(Flash)
+	assert.Nil(t, f.Peek("a"))
+	assert.Empty(t, f.PeekValues("a"))
+
+	f.Add("a", 1)
+	f.Add("a", 2)
+	f.changed = false
+
+	assert.Equal(t, 1, f.Peek("a"))
+	assert.Equal(t, []interface{}{1, 2}, f.PeekValues("a"))
+	assert.True(t, f.Has("a"), "expected value not consumed")
+	assert.False(t, f.Changed())
+
+	assert.Equal(t, 1, f.Get("a"))
+	assert.False(t, f.Has("a"))
+}
@@ -0,0 +1,84 @@
+package session
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"time"
+)
+
+// IDFormat is the format of generated session id
+type IDFormat int
+
+// IDFormat values
+const (
+	RandomBase64 IDFormat = iota // 256-bit random, base64 url encoded
+	ULID                         // 48-bit timestamp and 80-bit random, sortable by creation time
+	UUIDv4                       // random UUID version 4
+)
+
+func randomBytes(n int) []byte {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		// this should never happened
+		// or something wrong with OS's crypto pseudorandom generator
+		panic(err)
+	}
+	return b
+}
+
+// generator returns session id generator for the format
+func (f IDFormat) generator() func() string {
+	switch f {
+	case ULID:
+		return generateULID
+	case UUIDv4:
+		return generateUUIDv4
+	}
+	return generateRandomBase64
+}
+
+func generateRandomBase64() string {
+	return base64.RawURLEncoding.EncodeToString(randomBytes(32))
+}
+
+// crockfordBase32 is the ULID alphabet
+const crockfordBase32 = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+func generateULID() string {
+	var b [16]byte
+	ms := uint64(time.Now().UnixNano() / int64(time.Millisecond))
+	binary.BigEndian.PutUint16(b[:2], uint16(ms>>32))
+	binary.BigEndian.PutUint32(b[2:6], uint32(ms))
+	copy(b[6:], randomBytes(10))
+
+	// encode 128 bits into 26 characters, 5 bits each from the most significant bit
+	hi := binary.BigEndian.Uint64(b[:8])
+	lo := binary.BigEndian.Uint64(b[8:])
+	var r [26]byte
+	for i := 25; i >= 0; i-- {
+		r[i] = crockfordBase32[lo&0x1f]
+		lo = lo>>5 | hi<<59
+		hi >>= 5
+	}
+	return string(r[:])
+}
+
+func generateUUIDv4() string {
+	b := randomBytes(16)
+	b[6] = b[6]&0x0f | 0x40 // version 4
+	b[8] = b[8]&0x3f | 0x80 // variant 10
+
+	var r [36]byte
+	hex.Encode(r[0:8], b[0:4])
+	r[8] = '-'
+	hex.Encode(r[9:13], b[4:6])
+	r[13] = '-'
+	hex.Encode(r[14:18], b[6:8])
+	r[18] = '-'
+	hex.Encode(r[19:23], b[8:10])
+	r[23] = '-'
+	hex.Encode(r[24:], b[10:])
+	return string(r[:])
+}
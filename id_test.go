@@ -0,0 +1,39 @@
+package session
+
+import (
+	"regexp"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIDFormat(t *testing.T) {
+	t.Parallel()
+
+	t.Run("RandomBase64", func(t *testing.T) {
+		id := RandomBase64.generator()()
+		assert.Len(t, id, 43)
+		assert.NotEqual(t, id, RandomBase64.generator()())
+	})
+
+	t.Run("ULID", func(t *testing.T) {
+		gen := ULID.generator()
+		re := regexp.MustCompile(`^[0-9A-HJKMNP-TV-Z]{26}$`)
+
+		var ids []string
+		for i := 0; i < 3; i++ {
+			id := gen()
+			assert.Regexp(t, re, id)
+			ids = append(ids, id)
+			time.Sleep(2 * time.Millisecond)
+		}
+		assert.True(t, sort.StringsAreSorted(ids), "expected ulid sorted by creation time")
+	})
+
+	t.Run("UUIDv4", func(t *testing.T) {
+		id := UUIDv4.generator()()
+		assert.Regexp(t, `^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`, id)
+	})
+}
@@ -0,0 +1,54 @@
+// Package aesgcm encrypts data using AES-GCM with key rotation
+package aesgcm
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+)
+
+// Cipher encrypts data using the first key, and decrypts data using all keys
+type Cipher []cipher.AEAD
+
+// New creates new cipher from AES keys (16, 24 or 32 bytes)
+func New(keys [][]byte) (Cipher, error) {
+	c := make(Cipher, 0, len(keys))
+	for _, k := range keys {
+		block, err := aes.NewCipher(k)
+		if err != nil {
+			return nil, err
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, err
+		}
+		c = append(c, aead)
+	}
+	return c, nil
+}
+
+// Encrypt encrypts plaintext into [nonce][sealed],
+// additionalData binds ciphertext to its context, e.g. store key
+func (c Cipher) Encrypt(plaintext, additionalData []byte) ([]byte, error) {
+	aead := c[0]
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(plaintext)+aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return aead.Seal(nonce, nonce, plaintext, additionalData), nil
+}
+
+// Decrypt decrypts ciphertext using any key,
+// returns false if no key can decrypt
+func (c Cipher) Decrypt(ciphertext, additionalData []byte) ([]byte, bool) {
+	for _, aead := range c {
+		if len(ciphertext) < aead.NonceSize() {
+			continue
+		}
+		nonce, sealed := ciphertext[:aead.NonceSize()], ciphertext[aead.NonceSize():]
+		if b, err := aead.Open(nil, nonce, sealed, additionalData); err == nil {
+			return b, true
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,41 @@
+package aesgcm
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCipher(t *testing.T) {
+	t.Parallel()
+
+	oldKey := []byte("0123456789abcdef")
+	newKey := []byte("fedcba9876543210")
+
+	c, err := New([][]byte{oldKey})
+	assert.NoError(t, err)
+
+	b, err := c.Encrypt([]byte("data"), []byte("a"))
+	assert.NoError(t, err)
+
+	p, ok := c.Decrypt(b, []byte("a"))
+	assert.True(t, ok)
+	assert.Equal(t, []byte("data"), p)
+
+	_, ok = c.Decrypt(b, []byte("b"))
+	assert.False(t, ok, "expected additional data mismatch")
+
+	_, ok = c.Decrypt(b[:4], []byte("a"))
+	assert.False(t, ok)
+
+	// rotate key
+	c, err = New([][]byte{newKey, oldKey})
+	assert.NoError(t, err)
+
+	p, ok = c.Decrypt(b, []byte("a"))
+	assert.True(t, ok, "expected decrypt by old key")
+	assert.Equal(t, []byte("data"), p)
+
+	_, err = New([][]byte{[]byte("short")})
+	assert.Error(t, err)
+}
@@ -0,0 +1,180 @@
+// Package example is an example web application using session,
+// used by integration tests to validate features against realistic flows
+package example
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/moonrhythm/session"
+	"github.com/moonrhythm/session/csrf"
+	"github.com/moonrhythm/session/rememberme"
+	"github.com/moonrhythm/session/store"
+)
+
+const (
+	sessName = "sess"
+
+	userKey = "user"
+)
+
+// Users is the user database, username => password
+type Users map[string]string
+
+// App is the example application
+type App struct {
+	users Users
+	sm    *session.Manager
+	h     http.Handler
+
+	// active sessions for admin page, session id => username
+	mu     sync.Mutex
+	active map[string]string
+}
+
+// New creates new example app
+func New(users Users) *App {
+	app := &App{
+		users:  users,
+		active: make(map[string]string),
+	}
+	app.sm = session.New(session.Config{
+		Store:    new(store.Memory),
+		HTTPOnly: true,
+		Path:     "/",
+		MaxAge:   time.Hour,
+		Secure:   session.PreferSecure,
+		SameSite: http.SameSiteLaxMode,
+		Secret:   []byte("example secret"),
+	})
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", app.index)
+	mux.HandleFunc("/login", app.login)
+	mux.HandleFunc("/logout", app.logout)
+	mux.HandleFunc("/admin/sessions", app.adminSessions)
+
+	remember := rememberme.Middleware(rememberme.Config{
+		Store:       new(store.Memory),
+		SessionName: sessName,
+		Key:         userKey,
+		Path:        "/",
+		SameSite:    http.SameSiteLaxMode,
+	})
+
+	app.h = app.sm.Middleware()(remember(csrf.Middleware(csrf.Config{SessionName: sessName})(mux)))
+	return app
+}
+
+// ServeHTTP implements http.Handler
+func (app *App) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	app.h.ServeHTTP(w, r)
+}
+
+func (app *App) index(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		http.NotFound(w, r)
+		return
+	}
+
+	s := session.MustGet(r.Context(), sessName)
+	// session must be modified before write response
+	token := csrf.Token(r.Context())
+	flashes := s.Flash().Values("message")
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	for _, m := range flashes {
+		fmt.Fprintf(w, "flash: %v\n", m)
+	}
+	if user := s.GetString(userKey); user != "" {
+		fmt.Fprintf(w, "user: %s\n", user)
+	} else {
+		fmt.Fprintln(w, "user: anonymous")
+	}
+	fmt.Fprintf(w, "csrf: %s\n", token)
+}
+
+func (app *App) login(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	s := session.MustGet(r.Context(), sessName)
+
+	username := r.FormValue("username")
+	password, ok := app.users[username]
+	if !ok || subtle.ConstantTimeCompare([]byte(password), []byte(r.FormValue("password"))) != 1 {
+		s.Flash().Add("message", "invalid credentials")
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+		return
+	}
+
+	// prevent session fixation
+	if err := s.Renew(); err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	s.Set(userKey, username)
+	s.Flash().Add("message", "welcome "+username)
+
+	if r.FormValue("remember") == "on" {
+		if err := rememberme.Remember(w, r, username); err != nil {
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	app.mu.Lock()
+	app.active[s.ID()] = username
+	app.mu.Unlock()
+
+	http.Redirect(w, r, "/", http.StatusSeeOther)
+}
+
+func (app *App) logout(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	s := session.MustGet(r.Context(), sessName)
+
+	app.mu.Lock()
+	delete(app.active, s.ID())
+	app.mu.Unlock()
+
+	if err := rememberme.Forget(w, r); err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	if err := s.Destroy(); err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	http.Redirect(w, r, "/", http.StatusSeeOther)
+}
+
+func (app *App) adminSessions(w http.ResponseWriter, r *http.Request) {
+	s := session.MustGet(r.Context(), sessName)
+	if s.GetString(userKey) != "admin" {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+
+	app.mu.Lock()
+	list := make([]string, 0, len(app.active))
+	for _, user := range app.active {
+		list = append(list, user)
+	}
+	app.mu.Unlock()
+	sort.Strings(list)
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	fmt.Fprintln(w, strings.Join(list, "\n"))
+}
@@ -0,0 +1,170 @@
+package example
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type client struct {
+	t      *testing.T
+	server *httptest.Server
+	http   *http.Client
+}
+
+func newClient(t *testing.T, server *httptest.Server) *client {
+	jar, _ := cookiejar.New(nil)
+	return &client{t: t, server: server, http: &http.Client{Jar: jar}}
+}
+
+func (c *client) get(path string) (int, string) {
+	resp, err := c.http.Get(c.server.URL + path)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	b, _ := ioutil.ReadAll(resp.Body)
+	return resp.StatusCode, string(b)
+}
+
+func (c *client) post(path string, form url.Values) (int, string) {
+	resp, err := c.http.PostForm(c.server.URL+path, form)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	b, _ := ioutil.ReadAll(resp.Body)
+	return resp.StatusCode, string(b)
+}
+
+var reCSRF = regexp.MustCompile(`csrf: (\S+)`)
+
+func (c *client) csrf() string {
+	_, body := c.get("/")
+	m := reCSRF.FindStringSubmatch(body)
+	if m == nil {
+		c.t.Fatal("csrf token not found")
+	}
+	return m[1]
+}
+
+func (c *client) login(username, password string) (int, string) {
+	return c.post("/login", url.Values{
+		"csrf":     {c.csrf()},
+		"username": {username},
+		"password": {password},
+	})
+}
+
+// rememberCookie returns new client which has only remember-me cookie,
+// as session cookie expired
+func (c *client) rememberCookie() *client {
+	u, _ := url.Parse(c.server.URL)
+	nc := newClient(c.t, c.server)
+	for _, cookie := range c.http.Jar.Cookies(u) {
+		if cookie.Name == "remember" {
+			nc.http.Jar.SetCookies(u, []*http.Cookie{cookie})
+		}
+	}
+	return nc
+}
+
+func TestApp(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(New(Users{
+		"admin": "admin1234",
+		"user":  "user1234",
+	}))
+	defer server.Close()
+
+	t.Run("Anonymous", func(t *testing.T) {
+		c := newClient(t, server)
+		code, body := c.get("/")
+		assert.Equal(t, http.StatusOK, code)
+		assert.Contains(t, body, "user: anonymous")
+	})
+
+	t.Run("Login without CSRF", func(t *testing.T) {
+		c := newClient(t, server)
+		c.get("/")
+		code, _ := c.post("/login", url.Values{"username": {"user"}, "password": {"user1234"}})
+		assert.Equal(t, http.StatusForbidden, code)
+	})
+
+	t.Run("Login invalid credentials", func(t *testing.T) {
+		c := newClient(t, server)
+		code, body := c.login("user", "wrong")
+		assert.Equal(t, http.StatusOK, code)
+		assert.Contains(t, body, "flash: invalid credentials")
+		assert.Contains(t, body, "user: anonymous")
+
+		_, body = c.get("/")
+		assert.NotContains(t, body, "flash:", "expected flash consumed")
+	})
+
+	t.Run("Login and Logout", func(t *testing.T) {
+		c := newClient(t, server)
+		code, body := c.login("user", "user1234")
+		assert.Equal(t, http.StatusOK, code)
+		assert.Contains(t, body, "flash: welcome user")
+		assert.Contains(t, body, "user: user")
+
+		code, _ = c.get("/admin/sessions")
+		assert.Equal(t, http.StatusForbidden, code)
+
+		code, body = c.post("/logout", url.Values{"csrf": {c.csrf()}})
+		assert.Equal(t, http.StatusOK, code)
+		assert.Contains(t, body, "user: anonymous")
+	})
+
+	t.Run("Remember Me", func(t *testing.T) {
+		c := newClient(t, server)
+		code, body := c.post("/login", url.Values{
+			"csrf":     {c.csrf()},
+			"username": {"user"},
+			"password": {"user1234"},
+			"remember": {"on"},
+		})
+		assert.Equal(t, http.StatusOK, code)
+		assert.Contains(t, body, "user: user")
+
+		c = c.rememberCookie()
+		_, body = c.get("/")
+		assert.Contains(t, body, "user: user", "expected user restored from remember-me token")
+
+		replay := c.rememberCookie()
+		code, body = c.post("/logout", url.Values{"csrf": {c.csrf()}})
+		assert.Equal(t, http.StatusOK, code)
+		assert.Contains(t, body, "user: anonymous")
+
+		_, body = replay.get("/")
+		assert.Contains(t, body, "user: anonymous", "expected remember-me token forgotten after logout")
+	})
+
+	t.Run("Login without Remember Me", func(t *testing.T) {
+		c := newClient(t, server)
+		c.login("user", "user1234")
+
+		_, body := c.rememberCookie().get("/")
+		assert.Contains(t, body, "user: anonymous")
+	})
+
+	t.Run("Admin", func(t *testing.T) {
+		u := newClient(t, server)
+		u.login("user", "user1234")
+
+		c := newClient(t, server)
+		c.login("admin", "admin1234")
+		code, body := c.get("/admin/sessions")
+		assert.Equal(t, http.StatusOK, code)
+		assert.Contains(t, body, "admin")
+		assert.Contains(t, body, "user")
+	})
+}
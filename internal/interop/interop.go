@@ -0,0 +1,62 @@
+// Package interop reads session data created by other session libraries
+package interop
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+
+	"github.com/moonrhythm/session"
+)
+
+// DecodeJSON decodes json object into session data, integers decode as int64
+func DecodeJSON(r io.Reader) (session.Data, error) {
+	var m map[string]interface{}
+	dec := json.NewDecoder(r)
+	dec.UseNumber()
+	if err := dec.Decode(&m); err != nil {
+		return nil, err
+	}
+	for k, v := range m {
+		m[k] = Number(v)
+	}
+	return m, nil
+}
+
+// Number converts json.Number in v to int64 or float64
+func Number(v interface{}) interface{} {
+	switch v := v.(type) {
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i
+		}
+		f, _ := v.Float64()
+		return f
+	case map[string]interface{}:
+		for k, x := range v {
+			v[k] = Number(x)
+		}
+	case []interface{}:
+		for i, x := range v {
+			v[i] = Number(x)
+		}
+	}
+	return v
+}
+
+// Import copies values decoded from cookie into session,
+// returns false if cookie not found or can not be decoded
+func Import(r *http.Request, s *session.Session, name string, decode func(value string) (session.Data, error)) bool {
+	c, err := r.Cookie(name)
+	if err != nil {
+		return false
+	}
+	data, err := decode(c.Value)
+	if err != nil {
+		return false
+	}
+	for k, v := range data {
+		s.Set(k, v)
+	}
+	return true
+}
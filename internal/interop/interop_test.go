@@ -0,0 +1,52 @@
+package interop
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/moonrhythm/session"
+)
+
+func TestDecodeJSON(t *testing.T) {
+	t.Parallel()
+
+	data, err := DecodeJSON(strings.NewReader(`{"a":1,"b":1.5,"c":[2,{"d":3}]}`))
+	assert.NoError(t, err)
+	assert.Equal(t, session.Data{
+		"a": int64(1),
+		"b": 1.5,
+		"c": []interface{}{int64(2), map[string]interface{}{"d": int64(3)}},
+	}, data)
+
+	_, err = DecodeJSON(strings.NewReader(`[]`))
+	assert.Error(t, err)
+}
+
+func TestImport(t *testing.T) {
+	t.Parallel()
+
+	decode := func(value string) (session.Data, error) {
+		if value != "valid" {
+			return nil, errors.New("invalid")
+		}
+		return session.Data{"user": "u1"}, nil
+	}
+
+	var s session.Session
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	assert.False(t, Import(r, &s, "sess", decode), "expected cookie not found")
+
+	r.AddCookie(&http.Cookie{Name: "sess", Value: "invalid"})
+	assert.False(t, Import(r, &s, "sess", decode))
+	assert.Nil(t, s.Get("user"))
+
+	r = httptest.NewRequest(http.MethodGet, "/", nil)
+	r.AddCookie(&http.Cookie{Name: "sess", Value: "valid"})
+	assert.True(t, Import(r, &s, "sess", decode))
+	assert.Equal(t, "u1", s.Get("user"))
+}
@@ -1,10 +1,30 @@
 package session
 
 const (
+	// reservedKeyPrefix is the prefix for session internal keys
+	reservedKeyPrefix = "_session/"
+
 	// manager internal data
 	timestampKey = "_session/timestamp"
+	createdKey   = "_session/created"   // for absolute lifetime
 	destroyedKey = "_session/destroyed" // for detect session hijack
+	payloadKey   = "_session/payload"   // encoded session data when manager encodes payload
+	cookieIDKey  = "_session/id"        // session id when store session data in cookie
+	versionKey   = "_session/version"   // data version, for CASStore
+	ipKey        = "_session/ip"        // client ip address of the last save
+	userAgentKey = "_session/ua"        // client user agent of the last save
+	boundIPKey   = "_session/bindip"    // client ip address when session created, for BindIP
+	boundUAKey   = "_session/bindua"    // user agent fingerprint when session created, for BindUserAgent
+	boundFPKey   = "_session/bindfp"    // device fingerprint when session created, for Fingerprint
+	aliasKey     = "_session/alias"     // store keys of the same session hashed with other secrets
+
+	// user's session index
+	userIndexPrefix      = "_session/user/" // store key prefix
+	userIndexSessionsKey = "sessions"
 
 	// session internal data
-	flashKey = "_session/flash"
+	elevatedKey = "_session/elevated" // elevated state expiration
+	flashKey    = "_session/flash"
+	keyOrderKey = "_session/keys" // for evict oldest keys when exceeded quota
+	ttlPrefix   = "_session/ttl/" // prefix for expiration of data set by SetTTL
 )
@@ -2,9 +2,9 @@ package session
 
 import (
 	"context"
-	"crypto/rand"
 	"crypto/sha256"
 	"encoding/base64"
+	"net"
 	"net/http"
 	"strings"
 	"time"
@@ -12,8 +12,12 @@ import (
 
 // Manager is the session manager
 type Manager struct {
-	config Config
-	hashID func(id string) string
+	config      Config
+	hashID      func(id string) string
+	oldHashIDs  []func(id string) string // hash with old secrets for lookup
+	cookieStore CookieStore
+	store       Store         // store without payload encoding, for user index
+	idleTimeout time.Duration // enforced idle timeout, zero if not set
 }
 
 // New creates new session manager
@@ -24,99 +28,524 @@ func New(config Config) *Manager {
 
 	m := Manager{}
 	m.config = config
+	m.store = config.Store
+	m.config.Store = newPayloadStore(&config)
+	m.cookieStore, _ = config.Store.(CookieStore)
+	if m.config.DetectConflict && !supportCAS(m.config.Store) {
+		panic("session: DetectConflict requires store that implements CASStore")
+	}
 
 	if m.config.GenerateID == nil {
-		m.config.GenerateID = func() string {
-			b := make([]byte, 32)
-			if _, err := rand.Read(b); err != nil {
-				// this should never happened
-				// or something wrong with OS's crypto pseudorandom generator
-				panic(err)
-			}
-			return base64.RawURLEncoding.EncodeToString(b)
-		}
+		m.config.GenerateID = m.config.IDFormat.generator()
 	}
 
 	if m.config.DisableHashID {
 		m.hashID = func(id string) string {
 			return id
 		}
+	} else if m.config.HashFunc != nil {
+		m.hashID = m.config.HashFunc
 	} else {
-		m.hashID = func(id string) string {
-			h := sha256.New()
-			h.Write([]byte(id))
-			h.Write(config.Secret)
-			return base64.RawURLEncoding.EncodeToString(h.Sum(nil))
+		secrets := config.Secrets
+		if len(secrets) == 0 {
+			secrets = [][]byte{config.Secret}
+		}
+		m.hashID = hashWithSecret(secrets[0])
+		for _, secret := range secrets[1:] {
+			m.oldHashIDs = append(m.oldHashIDs, hashWithSecret(secret))
 		}
 	}
 
+	m.idleTimeout = m.config.IdleTimeout
 	if m.config.IdleTimeout <= 0 {
 		m.config.IdleTimeout = m.config.MaxAge
 	}
 
+	m.config.CookiePrefix.validate(&m.config)
+
 	return &m
 }
 
+func hashWithSecret(secret []byte) func(id string) string {
+	return func(id string) string {
+		h := sha256.New()
+		h.Write([]byte(id))
+		h.Write(secret)
+		return base64.RawURLEncoding.EncodeToString(h.Sum(nil))
+	}
+}
+
 // Get retrieves session from request
 func (m *Manager) Get(r *http.Request, name string) (*Session, error) {
+	s := m.newSession(r, name)
+
+	if m.cookieStore != nil {
+		err := m.loadCookieData(r, s)
+		if err != nil {
+			return nil, err
+		}
+	} else if rawID, hashedID, ok := m.readID(r, name); ok {
+		// get session data from store
+		data, err := m.config.Store.Get(r.Context(), hashedID)
+		if err == ErrNotFound {
+			data, err = m.getOld(r.Context(), s, rawID)
+		}
+		if err != nil && err != ErrNotFound {
+			if m.config.FailureMode != ServeWithEmptySession {
+				return nil, err
+			}
+			s.loadFailed = true
+		}
+		err = m.loaded(s, rawID, hashedID, data, err == nil)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	m.initID(s)
+	return s, nil
+}
+
+// Load retrieves session named Config.Name, or DefaultName, from request,
+// use Load and Save when can not use middleware
+func (m *Manager) Load(r *http.Request) (*Session, error) {
+	return m.Get(r, m.name())
+}
+
+func (m *Manager) name() string {
+	if m.config.Name == "" {
+		return DefaultName
+	}
+	return m.config.Name
+}
+
+// GetMulti retrieves sessions from request,
+// uses single store round-trip if store implements BatchStore
+func (m *Manager) GetMulti(r *http.Request, names ...string) ([]*Session, error) {
+	st, ok := m.config.Store.(BatchStore)
+	if !ok || len(names) < 2 {
+		return m.getEach(r, names)
+	}
+
+	type pending struct {
+		s      *Session
+		rawID  string
+		hashID string
+	}
+
+	sessions := make([]*Session, len(names))
+	var (
+		ps   []pending
+		keys []string
+	)
+	for i, name := range names {
+		sessions[i] = m.newSession(r, name)
+		if rawID, hashedID, ok := m.readID(r, name); ok {
+			ps = append(ps, pending{sessions[i], rawID, hashedID})
+			keys = append(keys, hashedID)
+		}
+	}
+
+	if len(keys) > 0 {
+		values, err := st.GetMulti(r.Context(), keys)
+		if err == ErrNotSupported {
+			return m.getEach(r, names)
+		}
+		if err != nil && m.config.FailureMode != ServeWithEmptySession {
+			return nil, err
+		}
+		failed := err != nil
+		for _, p := range ps {
+			if failed {
+				p.s.loadFailed = true
+				continue
+			}
+			data, found := values[p.hashID]
+			if !found && len(m.oldHashIDs) > 0 {
+				data, err = m.getOld(r.Context(), p.s, p.rawID)
+				if err != nil && err != ErrNotFound {
+					if m.config.FailureMode != ServeWithEmptySession {
+						return nil, err
+					}
+					p.s.loadFailed = true
+				}
+				found = err == nil
+			}
+			err = m.loaded(p.s, p.rawID, p.hashID, data, found)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	for _, s := range sessions {
+		m.initID(s)
+	}
+	return sessions, nil
+}
+
+func (m *Manager) getEach(r *http.Request, names []string) ([]*Session, error) {
+	sessions := make([]*Session, len(names))
+	for i, name := range names {
+		s, err := m.Get(r, name)
+		if err != nil {
+			return nil, err
+		}
+		sessions[i] = s
+	}
+	return sessions, nil
+}
+
+func (m *Manager) newSession(r *http.Request, name string) *Session {
 	s := Session{
 		Name:     name,
-		Domain:   m.config.Domain,
+		Domain:   m.domain(r),
 		Path:     m.config.Path,
 		HTTPOnly: m.config.HTTPOnly,
 		MaxAge:   m.config.MaxAge,
 		Secure:   m.isSecure(r),
 		SameSite: m.config.SameSite,
 		Rolling:  m.config.Rolling,
+
+		Partitioned: m.config.Partitioned,
+
+		ip:        m.clientIP(r),
+		userAgent: r.UserAgent(),
 	}
+	if m.config.Fingerprint != nil {
+		s.fingerprint = m.config.Fingerprint(r)
+	}
+	if m.config.Quota.enabled() {
+		s.quota = &m.config.Quota
+	}
+	m.loadAnalyticsID(r, &s)
+	return &s
+}
 
-	// get session id from cookie
-	cookie, err := r.Cookie(name)
-	if err == nil && len(cookie.Value) > 0 {
-		var rawID string
+// readID reads session id from client
+func (m *Manager) readID(r *http.Request, name string) (rawID string, hashedID string, ok bool) {
+	value := m.readIDValue(r, name)
+	if len(value) == 0 {
+		return
+	}
 
-		// verify signature
-		if len(m.config.Keys) > 0 {
-			parts := strings.Split(cookie.Value, ".")
-			rawID = parts[0]
+	// verify signature
+	if len(m.config.Keys) > 0 {
+		parts := strings.Split(value, ".")
+		rawID = parts[0]
 
-			if len(parts) != 2 || !verify(rawID, parts[1], m.config.Keys) {
-				goto invalidSignature
-			}
-		} else {
-			rawID = cookie.Value
+		if len(parts) != 2 || !verify(rawID, parts[1], m.config.Keys) {
+			return "", "", false
 		}
+	} else {
+		rawID = value
+	}
 
-		hashedID := m.hashID(rawID)
+	hashedID = m.hashID(rawID)
+	if strings.HasPrefix(hashedID, reservedKeyPrefix) {
+		// store key reserved for internal data, e.g. user's session index
+		return "", "", false
+	}
+	return rawID, hashedID, true
+}
 
-		// get session data from store
-		s.data, err = m.config.Store.Get(r.Context(), hashedID)
+// getOld gets session data stored with old secrets,
+// session found with old secret will be saved with the current secret
+func (m *Manager) getOld(ctx context.Context, s *Session, rawID string) (Data, error) {
+	for _, hashID := range m.oldHashIDs {
+		data, err := m.config.Store.Get(ctx, hashID(rawID))
+		if err == ErrNotFound {
+			continue
+		}
 		if err == nil {
-			s.rawID = rawID
-			s.id = hashedID
-		} else if err != ErrNotFound {
-			return nil, err
+			s.changed = true
+			s.oldKey = hashID(rawID)
+			// data will be saved to new store key as new version
+			delete(data, versionKey)
+			addAlias(data, s.oldKey)
+		}
+		return data, err
+	}
+	return nil, ErrNotFound
+}
+
+// addAlias adds store key of the same session to data
+func addAlias(data Data, key string) {
+	aliases := toStrings(data[aliasKey])
+	for _, k := range aliases {
+		if k == key {
+			return
+		}
+	}
+	data[aliasKey] = append(aliases, key)
+}
+
+// oldCopy returns the copy of session data to save to the store key loaded with old secret,
+// the copy links to the new store key, so DestroyAllForUser can delete both copies
+// from the old key in user's session index
+func (m *Manager) oldCopy(s *Session) Data {
+	data := s.data.Clone()
+	delete(data, versionKey)
+	aliases := toStrings(data[aliasKey])
+	r := make([]string, 0, len(aliases))
+	for _, k := range aliases {
+		if k != s.oldKey {
+			r = append(r, k)
 		}
+	}
+	data[aliasKey] = r
+	addAlias(data, s.id)
+	return data
+}
 
-		// DO NOT set session id to cookie value if not found in store
-		// to prevent session fixation attack
+// linkOld saves the copy of session data to the store key loaded with old secret
+func (m *Manager) linkOld(ctx context.Context, s *Session) error {
+	if s.oldKey == "" {
+		return nil
+	}
+	err := m.config.Store.Set(ctx, s.oldKey, m.oldCopy(s), makeStoreOption(m, s))
+	if err != nil {
+		return err
 	}
-invalidSignature:
+	s.oldKey = ""
+	return nil
+}
 
+// delOld deletes session data stored with old secrets,
+// so the old copy can not be loaded after session destroyed or regenerated
+func (m *Manager) delOld(ctx context.Context, rawID string) error {
+	if rawID == "" {
+		return nil
+	}
+	for _, hashID := range m.oldHashIDs {
+		err := m.config.Store.Del(ctx, hashID(rawID))
+		if err != nil && err != ErrNotFound {
+			return err
+		}
+	}
+	return nil
+}
+
+// loaded sets session data loaded from store
+func (m *Manager) loaded(s *Session, rawID, hashedID string, data Data, found bool) error {
+	// DO NOT set session id to cookie value if not found in store
+	// to prevent session fixation attack
+	if !found || m.expired(data) || !m.bound(s, data) {
+		s.oldKey = ""
+		return nil
+	}
+	s.data = data
+	s.sizes = nil
+	s.rawID = rawID
+	s.token = m.token(rawID)
+	s.id = hashedID
+	s.version = data.Version()
+	s.removeExpired()
+
+	if m.config.AfterLoad != nil {
+		err := m.config.AfterLoad(s)
+		if err == ErrNotFound {
+			// discard loaded session, new session will be generated
+			s.data = nil
+			s.rawID = ""
+			s.token = ""
+			s.id = ""
+			s.oldKey = ""
+			s.changed = false
+			return nil
+		}
+		return err
+	}
+	return nil
+}
+
+// expired checks is session data not saved longer than idle timeout,
+// or created longer than absolute lifetime
+func (m *Manager) expired(data Data) bool {
+	if m.config.AbsoluteLifetime > 0 {
+		t, ok := timestamp(data, createdKey)
+		if !ok || t > 0 && time.Since(time.Unix(t, 0)) > m.config.AbsoluteLifetime {
+			return true
+		}
+	}
+	if m.idleTimeout > 0 {
+		// zero timestamp is the old session after regenerate
+		t, ok := timestamp(data, timestampKey)
+		if !ok || t > 0 && time.Since(time.Unix(t, 0)) > m.idleTimeout {
+			return true
+		}
+	}
+	return false
+}
+
+// timestamp gets unix timestamp from session data,
+// returns false if the value exists but is not a number, e.g. tampered data
+func timestamp(data Data, key string) (int64, bool) {
+	v, ok := data[key]
+	if !ok {
+		return 0, true
+	}
+	return parseInt64(v)
+}
+
+// initID generates new session id if session not loaded from store
+func (m *Manager) initID(s *Session) {
 	if len(s.id) == 0 {
 		s.rawID = m.config.GenerateID()
+		s.token = m.token(s.rawID)
 		s.id = m.hashID(s.rawID)
 		s.isNew = true
+		s.version = 0
 	}
-
-	return &s, nil
 }
 
 // Save saves session to store and set cookie to response
 //
-// Save must be called before response header was written
+// Save must be called before response header was written,
+// nil w saves session to store without set cookie, e.g. in background job
 func (m *Manager) Save(ctx context.Context, w http.ResponseWriter, s *Session) error {
+	if w == nil {
+		w = nopResponseWriter{}
+	}
+	if m.cookieStore != nil {
+		return m.saveCookie(ctx, w, s)
+	}
+
+	ok, err := m.prepareSave(ctx, w, s)
+	if !ok || err != nil {
+		return err
+	}
+	err = m.linkOld(ctx, s)
+	if err != nil {
+		return err
+	}
+	err = m.fitDataSize(s, func() error {
+		return m.set(ctx, s)
+	})
+	if err != nil {
+		return err
+	}
 	m.setCookie(w, s)
+	m.onCreate(ctx, s)
+	return nil
+}
+
+// onCreate calls OnCreate after new session was written to store
+func (m *Manager) onCreate(ctx context.Context, s *Session) {
+	if !s.created {
+		return
+	}
+	s.created = false
+	if m.config.OnCreate != nil {
+		m.config.OnCreate(ctx, s)
+	}
+}
+
+// set sets session data to store,
+// compares version of stored data if DetectConflict enabled
+func (m *Manager) set(ctx context.Context, s *Session) error {
+	opt := makeStoreOption(m, s)
+	if !m.config.DetectConflict {
+		return m.config.Store.Set(ctx, s.id, s.data, opt)
+	}
+
+	version := s.data.Version() + 1
+	s.data[versionKey] = version
+	err := m.config.Store.(CASStore).CompareAndSet(ctx, s.id, s.version, s.data, opt)
+	if err != nil {
+		// restore version to retry
+		if version > 1 {
+			s.data[versionKey] = version - 1
+		} else {
+			delete(s.data, versionKey)
+		}
+		return err
+	}
+	s.version = version
+	return nil
+}
+
+// SaveMulti saves sessions to store and set cookies to response,
+// uses single store round-trip if store implements BatchStore and DetectConflict disabled
+//
+// SaveMulti must be called before response header was written
+func (m *Manager) SaveMulti(ctx context.Context, w http.ResponseWriter, sessions ...*Session) error {
+	if w == nil {
+		w = nopResponseWriter{}
+	}
+	st, ok := m.config.Store.(BatchStore)
+	if !ok || len(sessions) < 2 || m.config.DetectConflict {
+		for _, s := range sessions {
+			err := m.Save(ctx, w, s)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	var pending []*Session
+	values := make(map[string]Data)
+	for _, s := range sessions {
+		ok, err := m.prepareSave(ctx, w, s)
+		if err != nil {
+			return err
+		}
+		if ok {
+			pending = append(pending, s)
+			values[s.id] = s.data
+			if s.oldKey != "" {
+				values[s.oldKey] = m.oldCopy(s)
+			}
+		}
+	}
+	if len(values) == 0 {
+		return nil
+	}
+
+	// store option is the same for all sessions from the same manager
+	err := st.SetMulti(ctx, values, makeStoreOption(m, pending[0]))
+	if _, ok := err.(*dataTooLargeError); ok || err == ErrNotSupported {
+		for _, s := range pending {
+			err = m.linkOld(ctx, s)
+			if err != nil {
+				return err
+			}
+			err = m.fitDataSize(s, func() error {
+				return m.set(ctx, s)
+			})
+			if err != nil {
+				return err
+			}
+			m.setCookie(w, s)
+			m.onCreate(ctx, s)
+		}
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	for _, s := range pending {
+		s.oldKey = ""
+		m.setCookie(w, s)
+		m.onCreate(ctx, s)
+	}
+	return nil
+}
+
+// prepareSave prepares session data,
+// returns true if session data must be saved to store then set cookie
+func (m *Manager) prepareSave(ctx context.Context, w http.ResponseWriter, s *Session) (bool, error) {
+	m.setAnalyticsCookie(w, s)
+
+	// do not replace client's session which could not be loaded
+	if s.loadFailed {
+		return false, nil
+	}
+
+	if !s.isNew && m.config.RenewPolicy != nil && m.config.RenewPolicy.ShouldRenew(s) {
+		s.Touch()
+	}
 
 	// detect is flash changed and encode new flash data
 	if s.flash != nil && s.flash.Changed() {
@@ -124,41 +553,151 @@ func (m *Manager) Save(ctx context.Context, w http.ResponseWriter, s *Session) e
 		s.Set(flashKey, b)
 	}
 
+	if !s.Changed() && !s.isNew && (s.Rolling || s.touched || m.shouldResave(s)) && m.idleTimeout <= 0 {
+		// renew ttl without rewrite data if store supports,
+		// enforced idle timeout must rewrite data to refresh timestamp
+		if st, ok := m.config.Store.(Toucher); ok {
+			err := st.Touch(ctx, s.id, m.config.IdleTimeout)
+			if err != ErrNotSupported {
+				m.setCookie(w, s)
+				return false, err
+			}
+		}
+	}
+
+	if !m.shouldSave(s) {
+		m.setCookie(w, s)
+		return false, nil
+	}
+
+	if m.config.BeforeSave != nil {
+		err := m.config.BeforeSave(s)
+		if err == ErrSkipSave {
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+	}
+
+	m.setMeta(s)
+	m.bind(s)
+
+	now := time.Now().Unix()
+	if _, ok := s.data[createdKey]; !ok {
+		s.Set(createdKey, now)
+		s.created = s.isNew
+	}
+	s.Set(timestampKey, now)
+	return true, nil
+}
+
+func (m *Manager) shouldSave(s *Session) bool {
 	// if session modified, then save
-	if s.Changed() {
-		goto save
+	if s.Changed() || s.touched {
+		return true
+	}
+	if s.isNew {
+		return m.config.SaveUninitialized
+	}
+	return m.shouldResave(s)
+}
+
+func (m *Manager) shouldResave(s *Session) bool {
+	lastSave := time.Unix(toInt64(s.Get(timestampKey)), 0)
+
+	// refresh timestamp before session reach idle timeout
+	if m.idleTimeout > 0 && !s.isNew && time.Since(lastSave) > m.idleTimeout/2 {
+		return true
 	}
 
 	// session not modified, and not resave, then do nothing
 	if !m.config.Resave {
-		return nil
+		return false
 	}
 
 	// session not modified, configured to resave but not pass ResaveAfter
-	if lastSave := time.Unix(s.GetInt64(timestampKey), 0); time.Now().Before(lastSave.Add(m.config.ResaveAfter)) {
-		return nil
+	return !time.Now().Before(lastSave.Add(m.config.ResaveAfter))
+}
+
+// Destroy deletes session from store,
+// then starts new session, data set after destroy will be saved to the new session
+//
+// when store session data in cookie, session must be saved to remove the cookie
+func (m *Manager) Destroy(ctx context.Context, s *Session) error {
+	if m.cookieStore == nil {
+		err := m.config.Store.Del(ctx, s.id)
+		if err != nil {
+			return err
+		}
+		err = m.delOld(ctx, s.rawID)
+		if err != nil {
+			return err
+		}
+	} else if m.config.RevocationList != nil && !s.isNew {
+		err := m.config.RevocationList.Revoke(ctx, s.id, m.config.IdleTimeout)
+		if err != nil {
+			return err
+		}
+	}
+
+	if m.config.OnDestroy != nil {
+		m.config.OnDestroy(ctx, s)
 	}
 
-save:
-	// save session data to store
-	s.Set(timestampKey, time.Now().Unix())
-	return m.config.Store.Set(ctx, s.id, s.data, makeStoreOption(m, s))
+	// remove cookie when save if nothing set after destroy
+	s.destroyed = s.destroyed || !s.isNew || s.chunks > 0
+
+	s.data = nil
+	s.flash = nil
+	s.changed = false
+	s.id = ""
+	s.oldKey = ""
+	m.initID(s)
+	return nil
 }
 
-// Destroy deletes session from store
-func (m *Manager) Destroy(ctx context.Context, s *Session) error {
-	return m.config.Store.Del(ctx, s.id)
+// Revoke revokes session by id when store keeps session data in cookie,
+// returns ErrNotSupported if RevocationList is not set
+func (m *Manager) Revoke(ctx context.Context, id string) error {
+	if m.config.RevocationList == nil {
+		return ErrNotSupported
+	}
+	return m.config.RevocationList.Revoke(ctx, id, m.config.IdleTimeout)
 }
 
-// Regenerate regenerates session id
+// DestroyByPrefix deletes all sessions which store key has the given prefix,
+// store key is the hashed id if hash enabled,
+// so prefix is useful only when DisableHashID or GenerateID generates prefixed id
+func (m *Manager) DestroyByPrefix(ctx context.Context, prefix string) error {
+	st, ok := m.config.Store.(PrefixDeleter)
+	if !ok {
+		return ErrNotSupported
+	}
+	return st.DelPrefix(ctx, prefix)
+}
+
+// Regenerate regenerates session id and keeps session data
 // use when change user access level to prevent session fixation
 func (m *Manager) Regenerate(ctx context.Context, s *Session) error {
 	id := s.id
+	if err := m.delOld(ctx, s.rawID); err != nil {
+		return err
+	}
+
+	delete(s.data, aliasKey)
+	s.oldKey = ""
 
 	s.rawID = m.config.GenerateID()
+	s.token = m.token(s.rawID)
 	s.isNew = true
 	s.id = m.hashID(s.rawID)
 	s.changed = true
+	s.version = 0
+
+	if m.config.OnRegenerate != nil {
+		m.config.OnRegenerate(ctx, id, s)
+	}
 
 	if m.config.DeleteOldSession {
 		return m.config.Store.Del(ctx, id)
@@ -167,7 +706,17 @@ func (m *Manager) Regenerate(ctx context.Context, s *Session) error {
 	data := s.data.Clone()
 	data[timestampKey] = int64(0)
 	data[destroyedKey] = time.Now().UnixNano()
-	return m.config.Store.Set(ctx, id, data, makeStoreOption(m, s))
+	opt := makeStoreOption(m, s)
+	if m.config.RegenerateOldTTL > 0 {
+		opt.TTL = m.config.RegenerateOldTTL
+	}
+	return m.config.Store.Set(ctx, id, data, opt)
+}
+
+// Rotate is the alias of Regenerate,
+// rotates session id and keeps session data
+func (m *Manager) Rotate(ctx context.Context, s *Session) error {
+	return m.Regenerate(ctx, s)
 }
 
 // Renew clears session data and regenerate new session id
@@ -177,26 +726,37 @@ func (m *Manager) Renew(ctx context.Context, s *Session) error {
 }
 
 func (m *Manager) setCookie(w http.ResponseWriter, s *Session) {
+	// cookie store sets cookie when save session data
+	if m.cookieStore != nil {
+		return
+	}
+
 	// if session don't have raw id, don't set cookie
 	if len(s.rawID) == 0 {
 		return
 	}
 
-	if s.isNew && !s.Changed() {
+	if s.isNew && !s.Changed() && !m.config.SaveUninitialized {
 		return
 	}
-	if !s.Rolling && (!s.isNew || !s.Changed()) {
+	if !s.Rolling && !s.touched && !s.isNew {
 		return
 	}
 
-	value := s.rawID
+	m.writeIDValue(w, s, s.token)
+}
+
+// token returns session id value for client
+func (m *Manager) token(rawID string) string {
 	if len(m.config.Keys) > 0 {
-		digest := sign(value, m.config.Keys[0])
-		value += "." + digest
+		return rawID + "." + sign(rawID, m.config.Keys[0])
 	}
+	return rawID
+}
 
+func (m *Manager) cookie(s *Session, value string) http.Cookie {
 	cs := http.Cookie{
-		Name:     s.Name,
+		Name:     m.cookieName(s.Name),
 		Domain:   s.Domain,
 		Path:     s.Path,
 		HttpOnly: s.HTTPOnly,
@@ -208,22 +768,100 @@ func (m *Manager) setCookie(w http.ResponseWriter, s *Session) {
 		cs.MaxAge = int(s.MaxAge / time.Second)
 		cs.Expires = time.Now().Add(s.MaxAge)
 	}
+	m.config.CookiePrefix.apply(&cs)
+	return cs
+}
 
-	http.SetCookie(w, &cs)
+func (m *Manager) cookieName(name string) string {
+	return m.config.CookiePrefix.String() + name
+}
+
+func (m *Manager) domain(r *http.Request) string {
+	if m.config.DomainFunc != nil {
+		return m.config.DomainFunc(r)
+	}
+	return m.config.Domain
 }
 
 func (m *Manager) isSecure(r *http.Request) bool {
+	if m.config.SecureFunc != nil {
+		return m.config.SecureFunc(r)
+	}
 	if m.config.Secure == ForceSecure {
 		return true
 	}
 	if m.config.Secure == PreferSecure {
-		if r.TLS != nil {
-			return true
-		}
-		if m.config.Proxy && r.Header.Get("X-Forwarded-Proto") == "https" {
-			return true
+		if m.config.IsTLS != nil {
+			return m.config.IsTLS(r)
 		}
+		return m.isTLS(r)
 	}
 
 	return false
 }
+
+func (m *Manager) isTLS(r *http.Request) bool {
+	if r.TLS != nil {
+		return true
+	}
+	if m.config.Proxy {
+		return strings.EqualFold(forwardedProto(r, m.config.ProxyHeader), "https")
+	}
+	return false
+}
+
+// clientIP returns client ip address,
+// uses X-Forwarded-For address added by the farthest trusted proxy when Proxy enabled
+func (m *Manager) clientIP(r *http.Request) string {
+	if m.config.Proxy {
+		if v := r.Header.Values("X-Forwarded-For"); len(v) > 0 {
+			ips := strings.Split(strings.Join(v, ","), ",")
+			n := m.config.TrustedProxies
+			if n <= 0 {
+				n = 1
+			}
+			i := len(ips) - n
+			if i < 0 {
+				i = 0
+			}
+			if ip := strings.TrimSpace(ips[i]); ip != "" {
+				return ip
+			}
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// forwardedProto returns request protocol from proxy header
+func forwardedProto(r *http.Request, header string) string {
+	if header == "" {
+		header = "X-Forwarded-Proto"
+	}
+	v := r.Header.Get(header)
+	if !strings.EqualFold(header, "Forwarded") {
+		return strings.TrimSpace(v)
+	}
+
+	// RFC 7239, uses the first proxy's element, e.g. for=1.2.3.4;proto=https, for=...
+	if i := strings.IndexByte(v, ','); i >= 0 {
+		v = v[:i]
+	}
+	for _, p := range strings.Split(v, ";") {
+		k, val, ok := strings.Cut(strings.TrimSpace(p), "=")
+		if ok && strings.EqualFold(k, "proto") {
+			return strings.Trim(val, `"`)
+		}
+	}
+	return ""
+}
+
+// nopResponseWriter discards response
+type nopResponseWriter struct{}
+
+func (nopResponseWriter) Header() http.Header         { return make(http.Header) }
+func (nopResponseWriter) Write(b []byte) (int, error) { return len(b), nil }
+func (nopResponseWriter) WriteHeader(int)             {}
@@ -1,16 +1,22 @@
 package session_test
 
 import (
+	"bytes"
+	"context"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
 
 	"github.com/moonrhythm/session"
+	"github.com/moonrhythm/session/coder"
 	"github.com/moonrhythm/session/store"
+	"github.com/moonrhythm/session/store/cookie"
+	"github.com/moonrhythm/session/store/mock"
 )
 
 func TestManagerGetSave(t *testing.T) {
@@ -23,13 +29,13 @@ func TestManagerGetSave(t *testing.T) {
 
 	m := session.New(session.Config{
 		MaxAge: time.Second,
-		Store: &mockStore{
-			SetFunc: func(key string, value session.Data, opt session.StoreOption) error {
+		Store: &mock.Store{
+			SetFunc: func(ctx context.Context, key string, value session.Data, opt session.StoreOption) error {
 				setKey = key
 				setValue = value
 				return nil
 			},
-			GetFunc: func(key string) (session.Data, error) {
+			GetFunc: func(ctx context.Context, key string) (session.Data, error) {
 				assert.Equal(t, setKey, key)
 				return setValue, nil
 			},
@@ -64,8 +70,8 @@ func TestManagerGetError(t *testing.T) {
 
 	m := session.New(session.Config{
 		MaxAge: time.Second,
-		Store: &mockStore{
-			GetFunc: func(key string) (session.Data, error) {
+		Store: &mock.Store{
+			GetFunc: func(ctx context.Context, key string) (session.Data, error) {
 				return nil, fmt.Errorf("store error")
 			},
 		},
@@ -114,3 +120,1042 @@ func TestManagerNotPassMiddleware(t *testing.T) {
 		}
 	})
 }
+
+func TestManagerDestroyByPrefix(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	t.Run("Not Supported", func(t *testing.T) {
+		m := session.New(session.Config{Store: &mock.Store{}})
+		assert.Equal(t, session.ErrNotSupported, m.DestroyByPrefix(ctx, "a"))
+	})
+
+	t.Run("Supported", func(t *testing.T) {
+		st := new(store.Memory)
+		st.Set(ctx, "a:1", session.Data{}, session.StoreOption{})
+		st.Set(ctx, "b:1", session.Data{}, session.StoreOption{})
+
+		m := session.New(session.Config{Store: st, DisableHashID: true})
+		assert.NoError(t, m.DestroyByPrefix(ctx, "a:"))
+
+		_, err := st.Get(ctx, "a:1")
+		assert.Equal(t, session.ErrNotFound, err)
+		_, err = st.Get(ctx, "b:1")
+		assert.NoError(t, err)
+	})
+}
+
+func TestManagerBeforeSave(t *testing.T) {
+	t.Parallel()
+
+	var setValue session.Data
+	st := &mock.Store{
+		SetFunc: func(ctx context.Context, key string, value session.Data, opt session.StoreOption) error {
+			setValue = value
+			return nil
+		},
+	}
+
+	t.Run("Modify", func(t *testing.T) {
+		m := session.New(session.Config{
+			Store: st,
+			BeforeSave: func(s *session.Session) error {
+				s.Del("debug")
+				return nil
+			},
+		})
+
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		w := httptest.NewRecorder()
+		s, _ := m.Get(r, sessName)
+		s.Set("a", 1)
+		s.Set("debug", true)
+		assert.NoError(t, m.Save(r.Context(), w, s))
+		assert.Equal(t, 1, setValue["a"])
+		assert.NotContains(t, setValue, "debug")
+	})
+
+	t.Run("Skip", func(t *testing.T) {
+		setValue = nil
+		m := session.New(session.Config{
+			Store: st,
+			BeforeSave: func(s *session.Session) error {
+				return session.ErrSkipSave
+			},
+		})
+
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		w := httptest.NewRecorder()
+		s, _ := m.Get(r, sessName)
+		s.Set("a", 1)
+		assert.NoError(t, m.Save(r.Context(), w, s))
+		assert.Nil(t, setValue)
+		assert.Empty(t, w.Header().Get("Set-Cookie"))
+	})
+
+	t.Run("Error", func(t *testing.T) {
+		m := session.New(session.Config{
+			Store: st,
+			BeforeSave: func(s *session.Session) error {
+				return fmt.Errorf("invalid session")
+			},
+		})
+
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		w := httptest.NewRecorder()
+		s, _ := m.Get(r, sessName)
+		s.Set("a", 1)
+		assert.Error(t, m.Save(r.Context(), w, s))
+	})
+}
+
+func TestManagerAnalyticsID(t *testing.T) {
+	t.Parallel()
+
+	m := session.New(session.Config{
+		Store:           new(store.Memory),
+		AnalyticsCookie: "aid",
+		AnalyticsMaxAge: time.Hour,
+	})
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	w := httptest.NewRecorder()
+	s, _ := m.Get(r, sessName)
+	aid := s.AnalyticsID()
+	assert.NotEmpty(t, aid)
+	assert.NotEqual(t, s.ID(), aid)
+	m.Save(r.Context(), w, s)
+
+	cs := w.Result().Cookies()
+	if assert.Len(t, cs, 1, "expected analytics cookie set even session not changed") {
+		assert.Equal(t, "aid_"+sessName, cs[0].Name)
+		assert.Equal(t, aid, cs[0].Value)
+		assert.Equal(t, 3600, cs[0].MaxAge)
+	}
+
+	r = httptest.NewRequest(http.MethodGet, "/", nil)
+	r.AddCookie(cs[0])
+	w = httptest.NewRecorder()
+	s, _ = m.Get(r, sessName)
+	assert.Equal(t, aid, s.AnalyticsID())
+	m.Save(r.Context(), w, s)
+	assert.Empty(t, w.Result().Cookies())
+
+	s.RotateAnalyticsID()
+	assert.NotEqual(t, aid, s.AnalyticsID())
+	w = httptest.NewRecorder()
+	m.Save(r.Context(), w, s)
+	assert.Len(t, w.Result().Cookies(), 1)
+
+	// other session has its own analytics cookie
+	r = httptest.NewRequest(http.MethodGet, "/", nil)
+	r.AddCookie(cs[0])
+	s, _ = m.Get(r, "other")
+	assert.NotEqual(t, aid, s.AnalyticsID())
+	w = httptest.NewRecorder()
+	m.Save(r.Context(), w, s)
+	if assert.Len(t, w.Result().Cookies(), 1) {
+		assert.Equal(t, "aid_other", w.Result().Cookies()[0].Name)
+	}
+}
+
+type touchStore struct {
+	mock.Store
+	TouchFunc func(string, time.Duration) error
+}
+
+func (m *touchStore) Touch(ctx context.Context, key string, ttl time.Duration) error {
+	return m.TouchFunc(key, ttl)
+}
+
+func TestManagerTouch(t *testing.T) {
+	t.Parallel()
+
+	var (
+		setCalled int
+		touched   string
+	)
+	st := &touchStore{
+		Store: mock.Store{
+			GetFunc: func(ctx context.Context, key string) (session.Data, error) {
+				return session.Data{"a": 1}, nil
+			},
+			SetFunc: func(ctx context.Context, key string, value session.Data, opt session.StoreOption) error {
+				setCalled++
+				return nil
+			},
+		},
+		TouchFunc: func(key string, ttl time.Duration) error {
+			touched = key
+			return nil
+		},
+	}
+
+	m := session.New(session.Config{
+		Store:   st,
+		MaxAge:  time.Minute,
+		Rolling: true,
+	})
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("Cookie", sessName+"=test")
+	w := httptest.NewRecorder()
+	s, _ := m.Get(r, sessName)
+	assert.NoError(t, m.Save(r.Context(), w, s))
+	assert.Equal(t, s.ID(), touched, "expected rolling session touched")
+	assert.Equal(t, 0, setCalled, "expected session data not rewritten")
+	assert.Len(t, w.Result().Cookies(), 1)
+
+	st.TouchFunc = func(key string, ttl time.Duration) error {
+		return session.ErrNotSupported
+	}
+	w = httptest.NewRecorder()
+	s, _ = m.Get(r, sessName)
+	assert.NoError(t, m.Save(r.Context(), w, s))
+	assert.Equal(t, 0, setCalled, "expected fallback to not save unchanged session")
+}
+
+func TestSessionTouch(t *testing.T) {
+	t.Parallel()
+
+	var (
+		setCalled int
+		touched   string
+	)
+	st := &touchStore{
+		Store: mock.Store{
+			GetFunc: func(ctx context.Context, key string) (session.Data, error) {
+				return session.Data{"a": 1}, nil
+			},
+			SetFunc: func(ctx context.Context, key string, value session.Data, opt session.StoreOption) error {
+				setCalled++
+				return nil
+			},
+		},
+		TouchFunc: func(key string, ttl time.Duration) error {
+			touched = key
+			return nil
+		},
+	}
+
+	m := session.New(session.Config{
+		Store:  st,
+		MaxAge: time.Minute,
+	})
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("Cookie", sessName+"=test")
+	w := httptest.NewRecorder()
+	s, _ := m.Get(r, sessName)
+	assert.NoError(t, m.Save(r.Context(), w, s))
+	assert.Empty(t, touched, "expected unchanged session not touched")
+	assert.Empty(t, w.Result().Cookies())
+
+	w = httptest.NewRecorder()
+	s, _ = m.Get(r, sessName)
+	s.Touch()
+	assert.NoError(t, m.Save(r.Context(), w, s))
+	assert.Equal(t, s.ID(), touched)
+	assert.Equal(t, 0, setCalled, "expected session data not rewritten")
+	assert.Len(t, w.Result().Cookies(), 1)
+
+	st.TouchFunc = func(key string, ttl time.Duration) error {
+		return session.ErrNotSupported
+	}
+	w = httptest.NewRecorder()
+	s, _ = m.Get(r, sessName)
+	s.Touch()
+	assert.NoError(t, m.Save(r.Context(), w, s))
+	assert.Equal(t, 1, setCalled, "expected fallback to resave session")
+	assert.Len(t, w.Result().Cookies(), 1)
+}
+
+func TestManagerIdleTimeout(t *testing.T) {
+	t.Parallel()
+
+	var (
+		lastSave  time.Time
+		setCalled int
+	)
+	m := session.New(session.Config{
+		Store: &mock.Store{
+			GetFunc: func(ctx context.Context, key string) (session.Data, error) {
+				return session.Data{
+					"_session/timestamp": lastSave.Unix(),
+					"a":                  1,
+				}, nil
+			},
+			SetFunc: func(ctx context.Context, key string, value session.Data, opt session.StoreOption) error {
+				setCalled++
+				return nil
+			},
+		},
+		MaxAge:      time.Hour,
+		IdleTimeout: 10 * time.Minute,
+	})
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("Cookie", sessName+"=test")
+
+	lastSave = time.Now().Add(-time.Minute)
+	s, _ := m.Get(r, sessName)
+	assert.False(t, s.IsNew())
+	assert.NoError(t, m.Save(r.Context(), httptest.NewRecorder(), s))
+	assert.Equal(t, 0, setCalled, "expected recently saved session not resaved")
+
+	lastSave = time.Now().Add(-6 * time.Minute)
+	s, _ = m.Get(r, sessName)
+	assert.False(t, s.IsNew())
+	assert.NoError(t, m.Save(r.Context(), httptest.NewRecorder(), s))
+	assert.Equal(t, 1, setCalled, "expected session resaved after half of idle timeout")
+
+	lastSave = time.Now().Add(-11 * time.Minute)
+	s, _ = m.Get(r, sessName)
+	assert.True(t, s.IsNew(), "expected idle session rejected")
+	assert.Nil(t, s.Get("a"))
+}
+
+func TestManagerAbsoluteLifetime(t *testing.T) {
+	t.Parallel()
+
+	var created time.Time
+	m := session.New(session.Config{
+		Store: &mock.Store{
+			GetFunc: func(ctx context.Context, key string) (session.Data, error) {
+				return session.Data{
+					"_session/created":   created.Unix(),
+					"_session/timestamp": time.Now().Unix(),
+				}, nil
+			},
+		},
+		Rolling:          true,
+		AbsoluteLifetime: 12 * time.Hour,
+	})
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("Cookie", sessName+"=test")
+
+	created = time.Now().Add(-time.Hour)
+	s, _ := m.Get(r, sessName)
+	assert.False(t, s.IsNew())
+
+	created = time.Now().Add(-13 * time.Hour)
+	s, _ = m.Get(r, sessName)
+	assert.True(t, s.IsNew(), "expected session older than absolute lifetime rejected")
+
+	var saved session.Data
+	m = session.New(session.Config{
+		Store: &mock.Store{
+			SetFunc: func(ctx context.Context, key string, value session.Data, opt session.StoreOption) error {
+				saved = value
+				return nil
+			},
+		},
+	})
+	s, _ = m.Get(httptest.NewRequest(http.MethodGet, "/", nil), sessName)
+	s.Set("a", 1)
+	assert.NoError(t, m.Save(r.Context(), httptest.NewRecorder(), s))
+	assert.NotZero(t, saved["_session/created"], "expected created time recorded")
+}
+
+func TestManagerExpiredCoder(t *testing.T) {
+	t.Parallel()
+
+	// cbor decodes positive integer as uint64
+	var (
+		stored    session.Data
+		setCalled int
+	)
+	m := session.New(session.Config{
+		Store: &mock.Store{
+			SetFunc: func(ctx context.Context, key string, value session.Data, opt session.StoreOption) error {
+				setCalled++
+				return nil
+			},
+			GetFunc: func(ctx context.Context, key string) (session.Data, error) {
+				var buf bytes.Buffer
+				err := coder.CBOR{}.NewEncoder(&buf).Encode(stored)
+				if err != nil {
+					return nil, err
+				}
+				var data session.Data
+				err = coder.CBOR{}.NewDecoder(&buf).Decode(&data)
+				return data, err
+			},
+		},
+		IdleTimeout:      10 * time.Minute,
+		AbsoluteLifetime: 12 * time.Hour,
+	})
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("Cookie", sessName+"=test")
+
+	stored = session.Data{
+		"_session/created":   time.Now().Add(-time.Hour).Unix(),
+		"_session/timestamp": time.Now().Add(-time.Minute).Unix(),
+	}
+	s, _ := m.Get(r, sessName)
+	assert.False(t, s.IsNew())
+	assert.NoError(t, m.Save(r.Context(), httptest.NewRecorder(), s))
+	assert.Equal(t, 0, setCalled, "expected recently saved session not resaved")
+	assert.False(t, s.Meta().LastSeenAt.IsZero())
+
+	stored = session.Data{
+		"_session/created":   time.Now().Add(-time.Hour).Unix(),
+		"_session/timestamp": time.Now().Add(-11 * time.Minute).Unix(),
+	}
+	s, _ = m.Get(r, sessName)
+	assert.True(t, s.IsNew(), "expected idle session rejected")
+
+	stored = session.Data{
+		"_session/created":   time.Now().Add(-13 * time.Hour).Unix(),
+		"_session/timestamp": time.Now().Unix(),
+	}
+	s, _ = m.Get(r, sessName)
+	assert.True(t, s.IsNew(), "expected session older than absolute lifetime rejected")
+
+	stored = session.Data{
+		"_session/created":   "invalid",
+		"_session/timestamp": time.Now().Unix(),
+	}
+	s, _ = m.Get(r, sessName)
+	assert.True(t, s.IsNew(), "expected invalid timestamp treated as expired")
+}
+
+func TestManagerRenewPolicy(t *testing.T) {
+	t.Parallel()
+
+	var touched []string
+	m := session.New(session.Config{
+		Store: &touchStore{
+			Store: mock.Store{
+				GetFunc: func(ctx context.Context, key string) (session.Data, error) {
+					return session.Data{"admin": true}, nil
+				},
+			},
+			TouchFunc: func(key string, ttl time.Duration) error {
+				touched = append(touched, key)
+				return nil
+			},
+		},
+		MaxAge: time.Minute,
+		RenewPolicy: session.RenewPolicyFunc(func(s *session.Session) bool {
+			return s.GetBool("admin")
+		}),
+	})
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("Cookie", sessName+"=test")
+	w := httptest.NewRecorder()
+	s, _ := m.Get(r, sessName)
+	assert.NoError(t, m.Save(r.Context(), w, s))
+	assert.Equal(t, []string{s.ID()}, touched)
+	assert.Len(t, w.Result().Cookies(), 1)
+
+	w = httptest.NewRecorder()
+	s, _ = m.Get(r, sessName)
+	s.Del("admin")
+	assert.NoError(t, m.Save(r.Context(), w, s))
+	assert.Len(t, touched, 1, "expected policy not renew")
+}
+
+func TestManagerAfterLoad(t *testing.T) {
+	t.Parallel()
+
+	m := session.New(session.Config{
+		Store: &mock.Store{
+			GetFunc: func(ctx context.Context, key string) (session.Data, error) {
+				return session.Data{"deprecated": 1, "version": 1}, nil
+			},
+		},
+		AfterLoad: func(s *session.Session) error {
+			if s.GetInt("version") < 1 {
+				return session.ErrNotFound
+			}
+			s.Del("deprecated")
+			return nil
+		},
+	})
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("Cookie", sessName+"=test")
+	s, err := m.Get(r, sessName)
+	assert.NoError(t, err)
+	assert.False(t, s.IsNew())
+	assert.Nil(t, s.Get("deprecated"))
+	assert.True(t, s.Changed())
+
+	m = session.New(session.Config{
+		Store: &mock.Store{
+			GetFunc: func(ctx context.Context, key string) (session.Data, error) {
+				return session.Data{"a": 1}, nil
+			},
+		},
+		AfterLoad: func(s *session.Session) error {
+			return session.ErrNotFound
+		},
+	})
+	s, err = m.Get(r, sessName)
+	assert.NoError(t, err)
+	assert.True(t, s.IsNew(), "expected discarded session")
+	assert.Nil(t, s.Get("a"))
+	assert.NotEmpty(t, s.ID())
+}
+
+func TestManagerCookieStore(t *testing.T) {
+	t.Parallel()
+
+	m := session.New(session.Config{
+		Store:  cookie.New([]byte("0123456789abcdef")),
+		MaxAge: time.Minute,
+	})
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	w := httptest.NewRecorder()
+	s, _ := m.Get(r, sessName)
+	s.Set("a", 1)
+	assert.NoError(t, m.Save(r.Context(), w, s))
+	id := s.ID()
+
+	cs := w.Result().Cookies()
+	if !assert.Len(t, cs, 1) {
+		return
+	}
+
+	r = httptest.NewRequest(http.MethodGet, "/", nil)
+	r.AddCookie(cs[0])
+	w = httptest.NewRecorder()
+	s, _ = m.Get(r, sessName)
+	assert.False(t, s.IsNew())
+	assert.Equal(t, id, s.ID())
+	assert.Equal(t, 1, s.GetInt("a"))
+
+	assert.NoError(t, m.Save(r.Context(), w, s))
+	assert.Empty(t, w.Result().Cookies(), "expected unchanged session not rewrite cookie")
+
+	assert.NoError(t, m.Destroy(r.Context(), s))
+	w = httptest.NewRecorder()
+	assert.NoError(t, m.Save(r.Context(), w, s))
+	cs = w.Result().Cookies()
+	if assert.Len(t, cs, 1) {
+		assert.True(t, cs[0].MaxAge < 0, "expected cookie removed")
+	}
+
+	assert.NoError(t, m.Destroy(r.Context(), s))
+	s.Set("b", 2)
+	w = httptest.NewRecorder()
+	assert.NoError(t, m.Save(r.Context(), w, s))
+	cs = w.Result().Cookies()
+	if assert.Len(t, cs, 1) {
+		assert.True(t, cs[0].MaxAge > 0, "expected new session cookie")
+	}
+	r = httptest.NewRequest(http.MethodGet, "/", nil)
+	r.AddCookie(cs[0])
+	s, _ = m.Get(r, sessName)
+	assert.Nil(t, s.Get("a"))
+	assert.Equal(t, 2, s.GetInt("b"))
+}
+
+func TestManagerCookieStorePayloadOptions(t *testing.T) {
+	t.Parallel()
+
+	for _, config := range []session.Config{
+		{EncryptionKeys: [][]byte{[]byte("0123456789abcdef")}},
+		{SignPayload: true, Secret: []byte("secret")},
+		{Coder: session.DefaultStoreCoder},
+		{PayloadVersion: 1},
+		{Migrate: func(version uint8, data session.Data) (session.Data, error) { return data, nil }},
+	} {
+		config.Store = cookie.New([]byte("0123456789abcdef"))
+		assert.Panics(t, func() { session.New(config) }, "expected payload options not silently ignored")
+	}
+}
+
+func TestManagerCookieStoreChunk(t *testing.T) {
+	t.Parallel()
+
+	m := session.New(session.Config{
+		Store: cookie.New([]byte("0123456789abcdef")),
+	})
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	w := httptest.NewRecorder()
+	s, _ := m.Get(r, sessName)
+	s.Set("a", strings.Repeat("x", 6000))
+	assert.NoError(t, m.Save(r.Context(), w, s))
+
+	cs := w.Result().Cookies()
+	if !assert.Len(t, cs, 4) {
+		return
+	}
+	assert.Equal(t, sessName, cs[0].Name)
+	assert.True(t, cs[0].MaxAge < 0, "expected unchunked cookie removed")
+	r = httptest.NewRequest(http.MethodGet, "/", nil)
+	for i, c := range cs[1:] {
+		assert.Equal(t, fmt.Sprintf("%s.%d", sessName, i), c.Name)
+		assert.True(t, len(c.Value) <= 4096)
+		r.AddCookie(c)
+	}
+	w = httptest.NewRecorder()
+	s, _ = m.Get(r, sessName)
+	assert.False(t, s.IsNew())
+	assert.Len(t, s.GetString("a"), 6000)
+
+	// shrink into single cookie
+	s.Set("a", "x")
+	w = httptest.NewRecorder()
+	assert.NoError(t, m.Save(r.Context(), w, s))
+	cs = w.Result().Cookies()
+	if !assert.Len(t, cs, 4) {
+		return
+	}
+	assert.Equal(t, sessName, cs[0].Name)
+	assert.True(t, cs[0].MaxAge >= 0)
+	for i, c := range cs[1:] {
+		assert.Equal(t, fmt.Sprintf("%s.%d", sessName, i), c.Name)
+		assert.True(t, c.MaxAge < 0, "expected stale chunk removed")
+	}
+}
+
+func TestManagerCookieStoreChunkSize(t *testing.T) {
+	t.Parallel()
+
+	m := session.New(session.Config{
+		Store:       cookie.New([]byte("0123456789abcdef")),
+		Domain:      strings.Repeat("sub.", 50) + "example.com",
+		Path:        "/" + strings.Repeat("path/", 50),
+		MaxAge:      time.Hour,
+		SameSite:    http.SameSiteStrictMode,
+		Partitioned: true,
+	})
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	w := httptest.NewRecorder()
+	s, _ := m.Get(r, sessName)
+	s.Set("a", strings.Repeat("x", 6000))
+	assert.NoError(t, m.Save(r.Context(), w, s))
+
+	headers := w.Header().Values("Set-Cookie")
+	assert.NotEmpty(t, headers)
+	for _, h := range headers {
+		assert.LessOrEqual(t, len(h), 4096, "expected cookie fits browser limit")
+	}
+
+	r = httptest.NewRequest(http.MethodGet, "/", nil)
+	for _, c := range w.Result().Cookies() {
+		if c.MaxAge >= 0 {
+			r.AddCookie(c)
+		}
+	}
+	s, _ = m.Get(r, sessName)
+	assert.Len(t, s.GetString("a"), 6000)
+}
+
+func TestManagerCookiePrefix(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Host", func(t *testing.T) {
+		m := session.New(session.Config{
+			Store:        new(store.Memory),
+			CookiePrefix: session.HostCookiePrefix,
+		})
+
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		w := httptest.NewRecorder()
+		s, _ := m.Get(r, sessName)
+		s.Path = "/path"
+		s.Set("a", 1)
+		assert.NoError(t, m.Save(r.Context(), w, s))
+
+		cs := w.Result().Cookies()
+		if !assert.Len(t, cs, 1) {
+			return
+		}
+		assert.Equal(t, "__Host-"+sessName, cs[0].Name)
+		assert.True(t, cs[0].Secure)
+		assert.Equal(t, "/", cs[0].Path, "expected path adjusted")
+		assert.Empty(t, cs[0].Domain)
+
+		r = httptest.NewRequest(http.MethodGet, "/", nil)
+		r.AddCookie(cs[0])
+		s, _ = m.Get(r, sessName)
+		assert.False(t, s.IsNew())
+		assert.Equal(t, 1, s.GetInt("a"))
+	})
+
+	t.Run("Secure", func(t *testing.T) {
+		m := session.New(session.Config{
+			Store:        new(store.Memory),
+			Domain:       "example.com",
+			CookiePrefix: session.SecureCookiePrefix,
+		})
+
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		w := httptest.NewRecorder()
+		s, _ := m.Get(r, sessName)
+		s.Set("a", 1)
+		assert.NoError(t, m.Save(r.Context(), w, s))
+
+		cs := w.Result().Cookies()
+		if assert.Len(t, cs, 1) {
+			assert.Equal(t, "__Secure-"+sessName, cs[0].Name)
+			assert.True(t, cs[0].Secure)
+			assert.Equal(t, "example.com", cs[0].Domain)
+		}
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		assert.Panics(t, func() {
+			session.New(session.Config{
+				Store:        new(store.Memory),
+				Domain:       "example.com",
+				CookiePrefix: session.HostCookiePrefix,
+			})
+		})
+		assert.Panics(t, func() {
+			session.New(session.Config{
+				Store:        new(store.Memory),
+				Path:         "/path",
+				CookiePrefix: session.HostCookiePrefix,
+			})
+		})
+	})
+}
+
+func TestManagerPartitioned(t *testing.T) {
+	t.Parallel()
+
+	m := session.New(session.Config{
+		Store:       new(store.Memory),
+		Partitioned: true,
+		SameSite:    http.SameSiteNoneMode,
+	})
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	w := httptest.NewRecorder()
+	s, _ := m.Get(r, sessName)
+	s.Set("a", 1)
+	assert.NoError(t, m.Save(r.Context(), w, s))
+
+	h := w.Header().Values("Set-Cookie")
+	if assert.Len(t, h, 1) {
+		assert.True(t, strings.HasSuffix(h[0], "; Partitioned"))
+		assert.Contains(t, h[0], "; Secure")
+	}
+}
+
+func TestManagerDomainFunc(t *testing.T) {
+	t.Parallel()
+
+	m := session.New(session.Config{
+		Store:  new(store.Memory),
+		Domain: "default.com",
+		DomainFunc: func(r *http.Request) string {
+			return r.Host
+		},
+	})
+
+	for _, host := range []string{"tenant1.com", "tenant2.com"} {
+		r := httptest.NewRequest(http.MethodGet, "http://"+host+"/", nil)
+		w := httptest.NewRecorder()
+		s, _ := m.Get(r, sessName)
+		s.Set("a", 1)
+		assert.NoError(t, m.Save(r.Context(), w, s))
+
+		cs := w.Result().Cookies()
+		if assert.Len(t, cs, 1) {
+			assert.Equal(t, host, cs[0].Domain)
+		}
+	}
+}
+
+func TestManagerSignedIDRejectBeforeStore(t *testing.T) {
+	t.Parallel()
+
+	st := &mock.Store{}
+	m := session.New(session.Config{
+		Store: st,
+		Keys:  [][]byte{[]byte("key1")},
+	})
+
+	for _, v := range []string{"unsigned", "forged.signature", "a.b.c"} {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Set("Cookie", sessName+"="+v)
+		s, err := m.Get(r, sessName)
+		assert.NoError(t, err)
+		assert.True(t, s.IsNew())
+	}
+	assert.Equal(t, 0, st.CallCount("Get"), "expected invalid id not touch store")
+}
+
+func TestManagerSecretsRotation(t *testing.T) {
+	t.Parallel()
+
+	st := new(store.Memory)
+	m := session.New(session.Config{
+		Store:  st,
+		Secret: []byte("old"),
+	})
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	w := httptest.NewRecorder()
+	s, _ := m.Get(r, sessName)
+	s.Set("a", 1)
+	assert.NoError(t, m.Save(r.Context(), w, s))
+	oldID := s.ID()
+
+	cs := w.Result().Cookies()
+	if !assert.Len(t, cs, 1) {
+		return
+	}
+	r = httptest.NewRequest(http.MethodGet, "/", nil)
+	r.AddCookie(cs[0])
+
+	m = session.New(session.Config{
+		Store:   st,
+		Secrets: [][]byte{[]byte("new"), []byte("old")},
+	})
+	s, err := m.Get(r, sessName)
+	assert.NoError(t, err)
+	assert.False(t, s.IsNew(), "expected session found with old secret")
+	assert.Equal(t, 1, s.GetInt("a"))
+	assert.NotEqual(t, oldID, s.ID(), "expected session id hashed with new secret")
+	assert.NoError(t, m.Save(r.Context(), httptest.NewRecorder(), s))
+
+	m = session.New(session.Config{
+		Store:   st,
+		Secrets: [][]byte{[]byte("new")},
+	})
+	s, err = m.Get(r, sessName)
+	assert.NoError(t, err)
+	assert.False(t, s.IsNew(), "expected session saved with new secret")
+	assert.Equal(t, 1, s.GetInt("a"))
+}
+
+func TestManagerSecretsRotationLogout(t *testing.T) {
+	t.Parallel()
+
+	for _, logout := range []func(m *session.Manager, s *session.Session) error{
+		func(m *session.Manager, s *session.Session) error {
+			return m.Destroy(context.Background(), s)
+		},
+		func(m *session.Manager, s *session.Session) error {
+			return m.Regenerate(context.Background(), s)
+		},
+	} {
+		st := new(store.Memory)
+		m := session.New(session.Config{
+			Store:  st,
+			Secret: []byte("old"),
+		})
+
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		w := httptest.NewRecorder()
+		s, _ := m.Get(r, sessName)
+		s.Set("a", 1)
+		assert.NoError(t, m.Save(r.Context(), w, s))
+
+		cs := w.Result().Cookies()
+		if !assert.Len(t, cs, 1) {
+			return
+		}
+		r = httptest.NewRequest(http.MethodGet, "/", nil)
+		r.AddCookie(cs[0])
+
+		m = session.New(session.Config{
+			Store:            st,
+			Secrets:          [][]byte{[]byte("new"), []byte("old")},
+			DeleteOldSession: true,
+		})
+		s, _ = m.Get(r, sessName)
+		assert.False(t, s.IsNew())
+		assert.NoError(t, m.Save(r.Context(), httptest.NewRecorder(), s))
+
+		assert.NoError(t, logout(m, s))
+		assert.NoError(t, m.Save(r.Context(), httptest.NewRecorder(), s))
+
+		// replay cookie after logout
+		s, _ = m.Get(r, sessName)
+		assert.True(t, s.IsNew(), "expected old secret copy deleted")
+		assert.Nil(t, s.Get("a"))
+	}
+}
+
+func TestManagerHashFunc(t *testing.T) {
+	t.Parallel()
+
+	var keys []string
+	st := &mock.Store{
+		SetFunc: func(ctx context.Context, key string, value session.Data, opt session.StoreOption) error {
+			keys = append(keys, key)
+			return nil
+		},
+	}
+
+	m := session.New(session.Config{
+		Store: st,
+		GenerateID: func() string {
+			return "id"
+		},
+		HashFunc: func(id string) string {
+			return "hashed-" + id
+		},
+	})
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	s, _ := m.Get(r, sessName)
+	s.Set("a", 1)
+	assert.NoError(t, m.Save(r.Context(), httptest.NewRecorder(), s))
+	assert.Equal(t, "hashed-id", s.ID())
+	assert.Equal(t, []string{"hashed-id"}, keys)
+
+	h := session.HMACHashFunc([]byte("key"))
+	assert.Equal(t, h("id"), h("id"))
+	assert.NotEqual(t, h("id"), session.HMACHashFunc([]byte("other"))("id"))
+}
+
+func TestManagerLoad(t *testing.T) {
+	t.Parallel()
+
+	st := new(store.Memory)
+	m := session.New(session.Config{
+		Store: st,
+	})
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	w := httptest.NewRecorder()
+	s, err := m.Load(r)
+	assert.NoError(t, err)
+	assert.Equal(t, session.DefaultName, s.Name)
+	s.Set("a", 1)
+	assert.NoError(t, m.Save(r.Context(), w, s))
+
+	cs := w.Result().Cookies()
+	if !assert.Len(t, cs, 1) {
+		return
+	}
+	assert.Equal(t, session.DefaultName, cs[0].Name)
+
+	r = httptest.NewRequest(http.MethodGet, "/", nil)
+	r.AddCookie(cs[0])
+	s, err = m.Load(r)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, s.GetInt("a"))
+
+	// save without response, e.g. background job
+	s.Set("a", 2)
+	assert.NoError(t, m.Save(context.Background(), nil, s))
+	s, _ = m.Load(r)
+	assert.Equal(t, 2, s.GetInt("a"))
+}
+
+func TestManagerRevocationList(t *testing.T) {
+	t.Parallel()
+
+	m := session.New(session.Config{
+		Store:          cookie.New([]byte("0123456789abcdef")),
+		MaxAge:         time.Minute,
+		RevocationList: &store.RevocationList{Store: new(store.Memory)},
+	})
+
+	login := func() *http.Cookie {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		w := httptest.NewRecorder()
+		s, _ := m.Get(r, sessName)
+		s.Set("a", 1)
+		assert.NoError(t, m.Save(r.Context(), w, s))
+		return w.Result().Cookies()[0]
+	}
+	get := func(c *http.Cookie) *session.Session {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.AddCookie(c)
+		s, err := m.Get(r, sessName)
+		assert.NoError(t, err)
+		return s
+	}
+
+	// force logout
+	c := login()
+	s := get(c)
+	assert.False(t, s.IsNew())
+	assert.NoError(t, m.Revoke(context.Background(), s.ID()))
+	assert.True(t, get(c).IsNew(), "expected revoked session rejected")
+
+	// replay cookie after destroy
+	c = login()
+	s = get(c)
+	assert.NoError(t, m.Destroy(context.Background(), s))
+	assert.True(t, get(c).IsNew(), "expected destroyed session rejected")
+
+	m = session.New(session.Config{Store: new(store.Memory)})
+	assert.Equal(t, session.ErrNotSupported, m.Revoke(context.Background(), "id"))
+}
+
+func TestManagerCASNotSupported(t *testing.T) {
+	t.Parallel()
+
+	assert.Panics(t, func() {
+		session.New(session.Config{Store: &mock.Store{}, DetectConflict: true})
+	})
+	assert.Panics(t, func() {
+		session.New(session.Config{Store: store.WithCompression(&mock.Store{}, nil), DetectConflict: true})
+	}, "expected wrapped store checked")
+	assert.NotPanics(t, func() {
+		session.New(session.Config{Store: store.WithRetry(new(store.Memory), store.RetryPolicy{}), DetectConflict: true})
+	})
+}
+
+func TestManagerCAS(t *testing.T) {
+	t.Parallel()
+
+	for name, config := range map[string]session.Config{
+		"Store":   {Store: new(store.Memory), DetectConflict: true},
+		"Payload": {Store: new(store.Memory), DetectConflict: true, Coder: session.DefaultStoreCoder, PayloadVersion: 1},
+		"Wrapped": {
+			Store:          store.WithCompression(store.WithEncryption(new(store.Memory), []byte("0123456789abcdef")), nil),
+			DetectConflict: true,
+		},
+	} {
+		config := config
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			m := session.New(config)
+
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			s, _ := m.Get(r, sessName)
+			s.Set("a", 1)
+			w := httptest.NewRecorder()
+			if !assert.NoError(t, m.Save(ctx, w, s)) {
+				return
+			}
+			s.Set("a", 2)
+			assert.NoError(t, m.Save(ctx, w, s), "expected save again in the same request")
+
+			// concurrent requests load the same version
+			r = httptest.NewRequest(http.MethodGet, "/", nil)
+			for _, c := range w.Result().Cookies() {
+				r.AddCookie(c)
+			}
+			s1, _ := m.Get(r, sessName)
+			s2, _ := m.Get(r, sessName)
+			assert.Equal(t, 2, s1.Get("a"))
+
+			s1.Set("a", 3)
+			assert.NoError(t, m.Save(ctx, nil, s1))
+			s2.Set("a", 4)
+			assert.Equal(t, session.ErrConflict, m.Save(ctx, nil, s2), "expected conflicting write detected")
+
+			s3, _ := m.Get(r, sessName)
+			assert.Equal(t, 3, s3.Get("a"))
+
+			// retry after conflict must reload
+			s2.Set("a", 4)
+			assert.Equal(t, session.ErrConflict, m.Save(ctx, nil, s2))
+
+			assert.NoError(t, m.Regenerate(ctx, s3))
+			s3.Set("a", 5)
+			assert.NoError(t, m.Save(ctx, nil, s3), "expected regenerated session saved as new")
+		})
+	}
+}
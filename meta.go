@@ -0,0 +1,48 @@
+package session
+
+import (
+	"time"
+)
+
+// Meta is the session metadata recorded by manager when session is saved to store,
+// use to render user's active devices
+type Meta struct {
+	CreatedAt  time.Time // time when session first saved
+	LastSeenAt time.Time // time when session last saved
+	IP         string    // client ip address of the last save
+	UserAgent  string    // client user agent of the last save
+}
+
+// Meta returns session metadata
+func (s *Session) Meta() Meta {
+	return dataMeta(s.data)
+}
+
+func dataMeta(data Data) Meta {
+	ip, _ := data[ipKey].(string)
+	ua, _ := data[userAgentKey].(string)
+	return Meta{
+		CreatedAt:  unixTime(data[createdKey]),
+		LastSeenAt: unixTime(data[timestampKey]),
+		IP:         ip,
+		UserAgent:  ua,
+	}
+}
+
+func unixTime(v interface{}) time.Time {
+	t := toInt64(v)
+	if t <= 0 {
+		return time.Time{}
+	}
+	return time.Unix(t, 0)
+}
+
+// setMeta records client info to session data before save
+func (m *Manager) setMeta(s *Session) {
+	if s.ip != "" && s.GetString(ipKey) != s.ip {
+		s.Set(ipKey, s.ip)
+	}
+	if s.userAgent != "" && s.GetString(userAgentKey) != s.userAgent {
+		s.Set(userAgentKey, s.userAgent)
+	}
+}
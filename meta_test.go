@@ -0,0 +1,90 @@
+package session_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/moonrhythm/session"
+	"github.com/moonrhythm/session/store"
+)
+
+func TestSessionMeta(t *testing.T) {
+	t.Parallel()
+
+	m := session.New(session.Config{
+		Store: new(store.Memory),
+		Proxy: true,
+	})
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.RemoteAddr = "10.0.0.1:1234"
+	r.Header.Set("User-Agent", "test-agent")
+	s, _ := m.Get(r, sessName)
+	assert.Zero(t, s.Meta(), "expected new session has no metadata")
+
+	s.Set("a", 1)
+	w := httptest.NewRecorder()
+	assert.NoError(t, m.Save(r.Context(), w, s))
+
+	meta := s.Meta()
+	assert.Equal(t, "10.0.0.1", meta.IP)
+	assert.Equal(t, "test-agent", meta.UserAgent)
+	assert.WithinDuration(t, time.Now(), meta.CreatedAt, 2*time.Second)
+	assert.WithinDuration(t, time.Now(), meta.LastSeenAt, 2*time.Second)
+
+	r = httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("X-Forwarded-For", "203.0.113.1, 192.168.1.1")
+	r.Header.Set("User-Agent", "other-agent")
+	r.Header.Set("Cookie", w.Result().Cookies()[0].String())
+	s, _ = m.Get(r, sessName)
+	assert.Equal(t, "10.0.0.1", s.Meta().IP)
+
+	s.Set("a", 2)
+	assert.NoError(t, m.Save(r.Context(), httptest.NewRecorder(), s))
+	assert.Equal(t, "192.168.1.1", s.Meta().IP)
+	assert.Equal(t, "other-agent", s.Meta().UserAgent)
+	assert.Equal(t, meta.CreatedAt, s.Meta().CreatedAt)
+}
+
+func TestSessionMetaTrustedProxies(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name    string
+		proxies int
+		xff     []string
+		ip      string
+	}{
+		{"Default", 0, []string{"1.1.1.1, 192.168.1.1"}, "192.168.1.1"},
+		{"Spoofed", 0, []string{"1.1.1.1, 2.2.2.2, 192.168.1.1"}, "192.168.1.1"},
+		{"Two Proxies", 2, []string{"1.1.1.1, 192.168.1.1, 10.0.0.2"}, "192.168.1.1"},
+		{"Multiple Headers", 2, []string{"1.1.1.1, 192.168.1.1", "10.0.0.2"}, "192.168.1.1"},
+		{"Fewer Addresses", 3, []string{"192.168.1.1, 10.0.0.2"}, "192.168.1.1"},
+		{"Empty", 0, []string{""}, "10.0.0.1"},
+	}
+
+	for _, c := range cases {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			m := session.New(session.Config{
+				Store:          new(store.Memory),
+				Proxy:          true,
+				TrustedProxies: c.proxies,
+			})
+
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.RemoteAddr = "10.0.0.1:1234"
+			for _, v := range c.xff {
+				r.Header.Add("X-Forwarded-For", v)
+			}
+			s, _ := m.Get(r, sessName)
+			s.Set("a", 1)
+			assert.NoError(t, m.Save(r.Context(), httptest.NewRecorder(), s))
+			assert.Equal(t, c.ip, s.Meta().IP)
+		})
+	}
+}
@@ -11,6 +11,13 @@ import (
 // Errors
 var (
 	ErrNotPassMiddleware = errors.New("session: request not pass middleware")
+
+	// ErrSkipSave is the error for Config.BeforeSave to skip the save
+	ErrSkipSave = errors.New("session: skip save")
+
+	// ErrLoadFailed is the error from Exists when store failed to load the session
+	// with ServeWithEmptySession failure mode
+	ErrLoadFailed = errors.New("session: load failed")
 )
 
 // Middleware is the Manager middleware wrapper
@@ -21,17 +28,24 @@ func Middleware(config Config) func(http.Handler) http.Handler {
 }
 
 // Middleware injects session manager into request's context.
+// Session is loaded from store when first get from context.
 //
 // All data changed before write response writer's header will be save.
 func (m *Manager) Middleware() func(http.Handler) http.Handler {
 	return func(h http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if m.config.Skipper != nil && m.config.Skipper(r) {
+				h.ServeHTTP(w, r)
+				return
+			}
+
 			rm := &scopedManager{
 				Manager:        m,
 				ResponseWriter: w,
 				r:              r,
 				storage:        make(map[string]*Session),
 			}
+			rm.parent, _ = r.Context().Value(scopedManagerKey{}).(*scopedManager)
 
 			ctx := context.WithValue(r.Context(), scopedManagerKey{}, rm)
 			h.ServeHTTP(rm, r.WithContext(ctx))
@@ -41,29 +55,172 @@ func (m *Manager) Middleware() func(http.Handler) http.Handler {
 	}
 }
 
+// Commit saves all sessions from context and sends response header to client,
+// use before stream response (e.g. server-sent events) to save sessions and send cookie
+// before the first streamed byte instead of when the stream ends.
+//
+// Commit always sends status 200, to send other status code
+// call w.WriteHeader then flush w instead.
+//
+// w must be the response writer passed to handler,
+// sessions changed after commit will not be saved
+func Commit(ctx context.Context, w http.ResponseWriter) error {
+	m, _ := ctx.Value(scopedManagerKey{}).(*scopedManager)
+	if m == nil {
+		return ErrNotPassMiddleware
+	}
+	for ; m != nil; m = m.parent {
+		if m.wroteHeader || m.committed {
+			continue
+		}
+		sessions := m.sessions()
+		err := m.Manager.SaveMulti(ctx, m.ResponseWriter, sessions...)
+		if err != nil {
+			return err
+		}
+		for _, s := range sessions {
+			s.saved()
+		}
+		m.committed = true
+	}
+
+	w.WriteHeader(http.StatusOK)
+	if f, ok := w.(http.Flusher); ok {
+		f.Flush()
+	}
+	return nil
+}
+
+// scopedManagerFor returns the nearest middleware's scoped manager configured for the name,
+// or the nearest middleware without name
+func scopedManagerFor(ctx context.Context, name string) *scopedManager {
+	var fallback *scopedManager
+	for m, _ := ctx.Value(scopedManagerKey{}).(*scopedManager); m != nil; m = m.parent {
+		if m.config.Name == name {
+			return m
+		}
+		if m.config.Name == "" && fallback == nil {
+			fallback = m
+		}
+	}
+	return fallback
+}
+
 // Get gets session from context
 func Get(ctx context.Context, name string) (*Session, error) {
-	m, _ := ctx.Value(scopedManagerKey{}).(*scopedManager)
+	m := scopedManagerFor(ctx, name)
 	if m == nil {
 		return nil, ErrNotPassMiddleware
 	}
 
-	// try get session from storage first
-	// to preserve session data from difference handler
-	if s, ok := m.storage[name]; ok {
-		return s, nil
+	return m.get(name)
+}
+
+// MustGet gets session from context,
+// panics if request not pass middleware or failed to get session
+func MustGet(ctx context.Context, name string) *Session {
+	s, err := Get(ctx, name)
+	if err == ErrNotPassMiddleware {
+		panic("session: MustGet(" + name + ") called on request that not pass session middleware")
 	}
+	if err != nil {
+		panic("session: MustGet(" + name + "): " + err.Error())
+	}
+	return s
+}
 
-	// get session from manager
-	s, err := m.Get(name)
+// GetDefault gets default session from request,
+// panics if request not pass middleware with Config.RegisterDefault
+// or failed to get session
+func GetDefault(r *http.Request) *Session {
+	var m *scopedManager
+	for m, _ = r.Context().Value(scopedManagerKey{}).(*scopedManager); m != nil; m = m.parent {
+		if m.config.RegisterDefault {
+			break
+		}
+	}
+	if m == nil {
+		panic("session: GetDefault called on request that not pass session middleware with RegisterDefault")
+	}
+	s, err := m.get(m.name())
 	if err != nil {
-		return nil, err
+		panic("session: GetDefault: " + err.Error())
 	}
-	s.m = m
+	return s
+}
 
-	// save session to storage for later get
-	m.storage[name] = s
-	return s, nil
+// Exists checks is request has an existing session with given name,
+// Exists does not create new session when not exists,
+// and returns error when session can not be loaded from store
+func Exists(ctx context.Context, name string) (bool, error) {
+	m := scopedManagerFor(ctx, name)
+	if m == nil {
+		return false, ErrNotPassMiddleware
+	}
+
+	s, ok := m.storage[name]
+	if !ok {
+		var err error
+		s, err = m.Get(name)
+		if err != nil {
+			return false, err
+		}
+		if s.IsNew() {
+			// do not keep new session in storage,
+			// so it will not be saved by SaveUninitialized
+			if s.loadFailed {
+				return false, ErrLoadFailed
+			}
+			return false, nil
+		}
+		s.m = m
+		m.storage[name] = s
+	}
+	if s.loadFailed {
+		return false, ErrLoadFailed
+	}
+	return !s.IsNew(), nil
+}
+
+// GetMulti gets sessions from context,
+// sessions that not loaded yet will be loaded in single store round-trip
+// if store implements BatchStore
+func GetMulti(ctx context.Context, names ...string) ([]*Session, error) {
+	// group names by middleware
+	var (
+		managers []*scopedManager
+		load     = make(map[*scopedManager][]string)
+	)
+	for _, name := range names {
+		m := scopedManagerFor(ctx, name)
+		if m == nil {
+			return nil, ErrNotPassMiddleware
+		}
+		if _, ok := m.storage[name]; ok {
+			continue
+		}
+		if _, ok := load[m]; !ok {
+			managers = append(managers, m)
+		}
+		load[m] = append(load[m], name)
+	}
+
+	for _, m := range managers {
+		loaded, err := m.Manager.GetMulti(m.r, load[m]...)
+		if err != nil {
+			return nil, err
+		}
+		for _, s := range loaded {
+			s.m = m
+			m.storage[s.Name] = s
+		}
+	}
+
+	r := make([]*Session, len(names))
+	for i, name := range names {
+		r[i] = scopedManagerFor(ctx, name).storage[name]
+	}
+	return r, nil
 }
 
 type scopedManagerKey struct{}
@@ -75,6 +232,29 @@ type scopedManager struct {
 	r           *http.Request
 	storage     map[string]*Session
 	wroteHeader bool
+	committed   bool           // sessions saved by Commit
+	failed      bool           // save failed, discard response from handler
+	parent      *scopedManager // outer middleware
+}
+
+// get gets session from storage, or load from manager
+func (m *scopedManager) get(name string) (*Session, error) {
+	// try get session from storage first
+	// to preserve session data from difference handler
+	if s, ok := m.storage[name]; ok {
+		return s, nil
+	}
+
+	// get session from manager
+	s, err := m.Get(name)
+	if err != nil {
+		return nil, err
+	}
+	s.m = m
+
+	// save session to storage for later get
+	m.storage[name] = s
+	return s, nil
 }
 
 func (m *scopedManager) Get(name string) (*Session, error) {
@@ -85,23 +265,67 @@ func (m *scopedManager) Save(s *Session) error {
 	return m.Manager.Save(m.r.Context(), m.ResponseWriter, s)
 }
 
+// SaveNow saves session before middleware saves all sessions
+func (m *scopedManager) SaveNow(ctx context.Context, s *Session) error {
+	err := m.Manager.Save(ctx, m.ResponseWriter, s)
+	if err != nil {
+		return err
+	}
+	s.saved()
+	return nil
+}
+
 func (m *scopedManager) MustSaveAll() {
-	if m.wroteHeader {
+	if m.wroteHeader || m.committed {
 		return
 	}
 
+	if m.config.PanicHandler != nil {
+		defer func() {
+			if v := recover(); v != nil {
+				m.config.PanicHandler(m.ResponseWriter, m.r, v)
+				m.failed = true
+				m.wroteHeader = true
+			}
+		}()
+	}
+
+	err := m.Manager.SaveMulti(m.r.Context(), m.ResponseWriter, m.sessions()...)
+	if err != nil {
+		m.handleError(err)
+	}
+}
+
+// sessions returns all loaded sessions
+func (m *scopedManager) sessions() []*Session {
+	sessions := make([]*Session, 0, len(m.storage))
 	for _, s := range m.storage {
-		err := m.Save(s)
-		if err != nil {
-			panic("session: " + err.Error())
-		}
+		sessions = append(sessions, s)
 	}
+	return sessions
+}
+
+// handleError handles save error by Config.FailureMode
+func (m *scopedManager) handleError(err error) {
+	if m.config.FailureMode == ServeWithEmptySession {
+		return
+	}
+	if m.config.ErrorHandler == nil {
+		panic("session: " + err.Error())
+	}
+	m.config.ErrorHandler(m.ResponseWriter, m.r, err)
+	m.failed = true
+	m.wroteHeader = true
 }
 
 func (m *scopedManager) Regenerate(s *Session) error {
 	return m.Manager.Regenerate(m.r.Context(), s)
 }
 
+func (m *scopedManager) Rotate(s *Session) error {
+	return m.Regenerate(s)
+}
+
 func (m *scopedManager) Renew(s *Session) error {
 	return m.Manager.Renew(m.r.Context(), s)
 }
@@ -111,6 +335,9 @@ func (m *scopedManager) Write(b []byte) (int, error) {
 	if !m.wroteHeader {
 		m.WriteHeader(http.StatusOK)
 	}
+	if m.failed {
+		return len(b), nil
+	}
 	return m.ResponseWriter.Write(b)
 }
 
@@ -120,6 +347,9 @@ func (m *scopedManager) WriteHeader(code int) {
 		return
 	}
 	m.MustSaveAll()
+	if m.failed {
+		return
+	}
 	m.wroteHeader = true
 	m.ResponseWriter.WriteHeader(code)
 }
@@ -132,13 +362,26 @@ func (m *scopedManager) Push(target string, opts *http.PushOptions) error {
 	return http.ErrNotSupported
 }
 
-// Flush implements Flusher interface
+// Flush implements Flusher interface,
+// saves sessions before flush header
 func (m *scopedManager) Flush() {
-	if w, ok := m.ResponseWriter.(http.Flusher); ok {
+	w, ok := m.ResponseWriter.(http.Flusher)
+	if !ok {
+		return
+	}
+	if !m.wroteHeader {
+		m.WriteHeader(http.StatusOK)
+	}
+	if !m.failed {
 		w.Flush()
 	}
 }
 
+// Unwrap returns the underlying response writer for http.ResponseController
+func (m *scopedManager) Unwrap() http.ResponseWriter {
+	return m.ResponseWriter
+}
+
 // Hijack implements Hijacker interface
 func (m *scopedManager) Hijack() (net.Conn, *bufio.ReadWriter, error) {
 	if w, ok := m.ResponseWriter.(http.Hijacker); ok {
@@ -2,9 +2,12 @@ package session_test
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
@@ -12,6 +15,7 @@ import (
 
 	"github.com/moonrhythm/session"
 	"github.com/moonrhythm/session/store"
+	"github.com/moonrhythm/session/store/mock"
 )
 
 const sessName = "sess"
@@ -38,7 +42,7 @@ func TestDefaultConfig(t *testing.T) {
 	t.Parallel()
 
 	h := session.Middleware(session.Config{
-		Store: &mockStore{},
+		Store: &mock.Store{},
 	})(mockHandler)
 
 	w := httptest.NewRecorder()
@@ -60,8 +64,8 @@ func TestSessionCookie(t *testing.T) {
 
 	h := session.Middleware(session.Config{
 		IdleTimeout: time.Second,
-		Store: &mockStore{
-			SetFunc: func(key string, value session.Data, opt session.StoreOption) error {
+		Store: &mock.Store{
+			SetFunc: func(ctx context.Context, key string, value session.Data, opt session.StoreOption) error {
 				setCalled = true
 				setKey = key
 				setValue = value
@@ -90,16 +94,16 @@ func TestEmptySession(t *testing.T) {
 	t.Parallel()
 
 	h := session.Middleware(session.Config{
-		Store: &mockStore{
-			GetFunc: func(key string) (session.Data, error) {
+		Store: &mock.Store{
+			GetFunc: func(ctx context.Context, key string) (session.Data, error) {
 				assert.Fail(t, "expected get was not called")
 				return nil, nil
 			},
-			SetFunc: func(key string, value session.Data, opt session.StoreOption) error {
+			SetFunc: func(ctx context.Context, key string, value session.Data, opt session.StoreOption) error {
 				assert.Fail(t, "expected set was not called")
 				return nil
 			},
-			DelFunc: func(key string) error {
+			DelFunc: func(ctx context.Context, key string) error {
 				assert.Fail(t, "expected del was not called")
 				return nil
 			},
@@ -119,16 +123,16 @@ func TestEmptySessionFlash(t *testing.T) {
 	t.Parallel()
 
 	h := session.Middleware(session.Config{
-		Store: &mockStore{
-			GetFunc: func(key string) (session.Data, error) {
+		Store: &mock.Store{
+			GetFunc: func(ctx context.Context, key string) (session.Data, error) {
 				assert.Fail(t, "expected get was not called")
 				return nil, nil
 			},
-			SetFunc: func(key string, value session.Data, opt session.StoreOption) error {
+			SetFunc: func(ctx context.Context, key string, value session.Data, opt session.StoreOption) error {
 				assert.Fail(t, "expected set was not called")
 				return nil
 			},
-			DelFunc: func(key string) error {
+			DelFunc: func(ctx context.Context, key string) error {
 				assert.Fail(t, "expected del was not called")
 				return nil
 			},
@@ -159,8 +163,8 @@ func TestSessionSetInStore(t *testing.T) {
 
 	h := session.Middleware(session.Config{
 		MaxAge: time.Second,
-		Store: &mockStore{
-			SetFunc: func(key string, value session.Data, opt session.StoreOption) error {
+		Store: &mock.Store{
+			SetFunc: func(ctx context.Context, key string, value session.Data, opt session.StoreOption) error {
 				setCalled = true
 				setKey = key
 				setValue = value
@@ -195,14 +199,14 @@ func TestSessionGetSet(t *testing.T) {
 
 	h := session.Middleware(session.Config{
 		MaxAge: time.Second,
-		Store: &mockStore{
-			SetFunc: func(key string, value session.Data, opt session.StoreOption) error {
+		Store: &mock.Store{
+			SetFunc: func(ctx context.Context, key string, value session.Data, opt session.StoreOption) error {
 				setCalled++
 				setKey = key
 				setValue = value
 				return nil
 			},
-			GetFunc: func(key string) (session.Data, error) {
+			GetFunc: func(ctx context.Context, key string) (session.Data, error) {
 				assert.Equal(t, setKey, key)
 				return setValue, nil
 			},
@@ -247,7 +251,7 @@ func TestSecureFlag(t *testing.T) {
 
 	for _, c := range cases {
 		h := session.Middleware(session.Config{
-			Store:  &mockStore{},
+			Store:  &mock.Store{},
 			Secure: c.flag,
 			Proxy:  true,
 		})(mockHandler)
@@ -280,7 +284,7 @@ func TestSecureFlagWithoutProxy(t *testing.T) {
 
 	for _, c := range cases {
 		h := session.Middleware(session.Config{
-			Store:  &mockStore{},
+			Store:  &mock.Store{},
 			Secure: c.flag,
 			Proxy:  false,
 		})(mockHandler)
@@ -297,6 +301,107 @@ func TestSecureFlagWithoutProxy(t *testing.T) {
 	}
 }
 
+func TestSecureFlagProxyHeader(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		header   string
+		name     string
+		value    string
+		expected bool
+	}{
+		{"X-Forwarded-Scheme", "X-Forwarded-Scheme", "https", true},
+		{"X-Forwarded-Scheme", "X-Forwarded-Proto", "https", false},
+		{"Forwarded", "Forwarded", "for=192.0.2.60;proto=https;by=203.0.113.43", true},
+		{"Forwarded", "Forwarded", `for="[2001:db8::1]";Proto="HTTPS", for=10.0.0.1;proto=http`, true},
+		{"Forwarded", "Forwarded", "for=192.0.2.60;proto=http, for=10.0.0.1;proto=https", false},
+		{"Forwarded", "Forwarded", "for=192.0.2.60", false},
+	}
+
+	for _, c := range cases {
+		h := session.Middleware(session.Config{
+			Store:       &mock.Store{},
+			Secure:      session.PreferSecure,
+			Proxy:       true,
+			ProxyHeader: c.header,
+		})(mockHandler)
+
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Set(c.name, c.value)
+		h.ServeHTTP(w, r)
+
+		cs := w.Result().Cookies()
+		if assert.Len(t, cs, 1) {
+			assert.Equal(t, c.expected, cs[0].Secure, c.value)
+		}
+	}
+}
+
+func TestSecureFunc(t *testing.T) {
+	t.Parallel()
+
+	h := session.Middleware(session.Config{
+		Store:  &mock.Store{},
+		Secure: session.ForceSecure,
+		SecureFunc: func(r *http.Request) bool {
+			return r.Host != "internal"
+		},
+	})(mockHandler)
+
+	for host, expected := range map[string]bool{"example.com": true, "internal": false} {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Host = host
+		h.ServeHTTP(w, r)
+
+		cs := w.Result().Cookies()
+		if assert.Len(t, cs, 1) {
+			assert.Equal(t, expected, cs[0].Secure, host)
+		}
+	}
+}
+
+func TestSecureFlagRequestTLS(t *testing.T) {
+	t.Parallel()
+
+	h := session.Middleware(session.Config{
+		Store:  &mock.Store{},
+		Secure: session.PreferSecure,
+	})(mockHandler)
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "https://example.com/", nil)
+	h.ServeHTTP(w, r)
+
+	cs := w.Result().Cookies()
+	if assert.Len(t, cs, 1) {
+		assert.True(t, cs[0].Secure)
+	}
+}
+
+func TestSecureFlagIsTLS(t *testing.T) {
+	t.Parallel()
+
+	h := session.Middleware(session.Config{
+		Store:  &mock.Store{},
+		Secure: session.PreferSecure,
+		IsTLS: func(r *http.Request) bool {
+			return r.Header.Get("X-Scheme") == "https"
+		},
+	})(mockHandler)
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("X-Scheme", "https")
+	h.ServeHTTP(w, r)
+
+	cs := w.Result().Cookies()
+	if assert.Len(t, cs, 1) {
+		assert.True(t, cs[0].Secure)
+	}
+}
+
 func TestHttpOnlyFlag(t *testing.T) {
 	t.Parallel()
 
@@ -309,7 +414,7 @@ func TestHttpOnlyFlag(t *testing.T) {
 
 	for _, c := range cases {
 		h := session.Middleware(session.Config{
-			Store:    &mockStore{},
+			Store:    &mock.Store{},
 			HTTPOnly: c.flag,
 		})(mockHandler)
 
@@ -338,7 +443,7 @@ func TestSameSiteFlag(t *testing.T) {
 
 	for _, c := range cases {
 		h := session.Middleware(session.Config{
-			Store:    &mockStore{},
+			Store:    &mock.Store{},
 			SameSite: c.flag,
 		})(mockHandler)
 
@@ -368,8 +473,8 @@ func TestRegenerate(t *testing.T) {
 	)
 
 	h := session.Middleware(session.Config{
-		Store: &mockStore{
-			SetFunc: func(key string, value session.Data, opt session.StoreOption) error {
+		Store: &mock.Store{
+			SetFunc: func(ctx context.Context, key string, value session.Data, opt session.StoreOption) error {
 				setValue[key] = value
 				if c == 0 {
 					setKey = key
@@ -378,10 +483,10 @@ func TestRegenerate(t *testing.T) {
 				assert.NotEqual(t, setKey, key, "expected key after regenerate to renew")
 				return nil
 			},
-			GetFunc: func(key string) (session.Data, error) {
+			GetFunc: func(ctx context.Context, key string) (session.Data, error) {
 				return setValue[key], nil
 			},
-			DelFunc: func(key string) error {
+			DelFunc: func(ctx context.Context, key string) error {
 				setValue[key] = nil
 				return nil
 			},
@@ -441,15 +546,15 @@ func TestRegenerateDeleteOldSession(t *testing.T) {
 
 	h := session.Middleware(session.Config{
 		DeleteOldSession: true,
-		Store: &mockStore{
-			SetFunc: func(key string, value session.Data, opt session.StoreOption) error {
+		Store: &mock.Store{
+			SetFunc: func(ctx context.Context, key string, value session.Data, opt session.StoreOption) error {
 				setValue[key] = value
 				return nil
 			},
-			GetFunc: func(key string) (session.Data, error) {
+			GetFunc: func(ctx context.Context, key string) (session.Data, error) {
 				return setValue[key], nil
 			},
-			DelFunc: func(key string) error {
+			DelFunc: func(ctx context.Context, key string) error {
 				setValue[key] = nil
 				return nil
 			},
@@ -496,6 +601,60 @@ func TestRegenerateDeleteOldSession(t *testing.T) {
 	assert.Equal(t, "3", w.Body.String())
 }
 
+func TestRegenerateOldTTL(t *testing.T) {
+	t.Parallel()
+
+	ttl := make(map[string]time.Duration)
+
+	h := session.Middleware(session.Config{
+		IdleTimeout:      time.Hour,
+		RegenerateOldTTL: 30 * time.Second,
+		Store: &mock.Store{
+			SetFunc: func(ctx context.Context, key string, value session.Data, opt session.StoreOption) error {
+				ttl[key] = opt.TTL
+				return nil
+			},
+		},
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s, _ := session.Get(r.Context(), sessName)
+		s.Set("test", 1)
+		id := s.ID()
+		s.Regenerate()
+		assert.Equal(t, 30*time.Second, ttl[id], "expected old session use RegenerateOldTTL")
+		w.Write([]byte("ok"))
+		assert.Equal(t, time.Hour, ttl[s.ID()], "expected new session use IdleTimeout")
+	}))
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	h.ServeHTTP(w, r)
+}
+
+func TestSaveUninitialized(t *testing.T) {
+	t.Parallel()
+
+	for _, saveUninitialized := range []bool{false, true} {
+		setCalled := false
+		h := session.Middleware(session.Config{
+			Store: &mock.Store{
+				SetFunc: func(ctx context.Context, key string, value session.Data, opt session.StoreOption) error {
+					setCalled = true
+					return nil
+				},
+			},
+			SaveUninitialized: saveUninitialized,
+		})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			session.Get(r.Context(), sessName)
+			w.Write([]byte("ok"))
+		}))
+
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+		assert.Equal(t, saveUninitialized, setCalled)
+		assert.Equal(t, saveUninitialized, len(w.Result().Cookies()) == 1)
+	}
+}
+
 func TestResave(t *testing.T) {
 	t.Parallel()
 
@@ -504,13 +663,13 @@ func TestResave(t *testing.T) {
 
 	h := session.Middleware(session.Config{
 		Resave: true,
-		Store: &mockStore{
-			SetFunc: func(key string, value session.Data, opt session.StoreOption) error {
+		Store: &mock.Store{
+			SetFunc: func(ctx context.Context, key string, value session.Data, opt session.StoreOption) error {
 				setCalled++
 				setValue[key] = value
 				return nil
 			},
-			GetFunc: func(key string) (session.Data, error) {
+			GetFunc: func(ctx context.Context, key string) (session.Data, error) {
 				return setValue[key], nil
 			},
 		},
@@ -620,16 +779,16 @@ func TestDestroy(t *testing.T) {
 	)
 
 	h := session.Middleware(session.Config{
-		Store: &mockStore{
-			SetFunc: func(key string, value session.Data, opt session.StoreOption) error {
+		Store: &mock.Store{
+			SetFunc: func(ctx context.Context, key string, value session.Data, opt session.StoreOption) error {
 				setKey = key
 				setValue = value
 				return nil
 			},
-			GetFunc: func(key string) (session.Data, error) {
+			GetFunc: func(ctx context.Context, key string) (session.Data, error) {
 				return setValue, nil
 			},
-			DelFunc: func(key string) error {
+			DelFunc: func(ctx context.Context, key string) error {
 				delCalled = true
 				assert.Equal(t, setKey, key, "expected destroy old key")
 				return nil
@@ -657,6 +816,327 @@ func TestDestroy(t *testing.T) {
 	assert.True(t, delCalled)
 }
 
+func TestDestroyThenSet(t *testing.T) {
+	t.Parallel()
+
+	st := new(store.Memory)
+	h := session.Middleware(session.Config{
+		Store: st,
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s, _ := session.Get(r.Context(), sessName)
+		if r.URL.Path == "/login" {
+			s.Set("user", "u1")
+		} else {
+			s.Destroy()
+			s.Flash().Set("msg", "logged out")
+		}
+		w.Write([]byte("ok"))
+	}))
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/login", nil))
+	cs := w.Result().Cookies()
+	if !assert.Len(t, cs, 1) {
+		return
+	}
+	oldCookie := cs[0]
+
+	r := httptest.NewRequest(http.MethodGet, "/logout", nil)
+	r.AddCookie(oldCookie)
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	cs = w.Result().Cookies()
+	if !assert.Len(t, cs, 1, "expected new session cookie") {
+		return
+	}
+	assert.NotEqual(t, oldCookie.Value, cs[0].Value)
+
+	r = httptest.NewRequest(http.MethodGet, "/", nil)
+	r.AddCookie(cs[0])
+	s, err := session.New(session.Config{Store: st}).Get(r, sessName)
+	assert.NoError(t, err)
+	assert.False(t, s.IsNew())
+	assert.Nil(t, s.Get("user"), "expected old data discarded")
+	assert.Equal(t, "logged out", s.Flash().Get("msg"))
+
+	r = httptest.NewRequest(http.MethodGet, "/", nil)
+	r.AddCookie(oldCookie)
+	s, _ = session.New(session.Config{Store: st}).Get(r, sessName)
+	assert.True(t, s.IsNew(), "expected old session destroyed")
+}
+
+func TestFailureMode(t *testing.T) {
+	t.Parallel()
+
+	storeErr := errors.New("store down")
+	st := &mock.Store{
+		GetFunc: func(ctx context.Context, key string) (session.Data, error) {
+			return nil, storeErr
+		},
+		SetFunc: func(ctx context.Context, key string, value session.Data, opt session.StoreOption) error {
+			return storeErr
+		},
+	}
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s, err := session.Get(r.Context(), sessName)
+		if err != nil {
+			http.Error(w, "load failed", http.StatusServiceUnavailable)
+			return
+		}
+		s.Set("a", 1)
+		w.Write([]byte("ok"))
+	})
+
+	t.Run("Return500", func(t *testing.T) {
+		var handled error
+		h := session.Middleware(session.Config{
+			Store: st,
+			ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
+				handled = err
+				http.Error(w, "save failed", http.StatusInternalServerError)
+			},
+		})(handler)
+
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+		assert.Equal(t, storeErr, handled)
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+		assert.Equal(t, "save failed\n", w.Body.String(), "expected handler response discarded")
+
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Set("Cookie", sessName+"=test")
+		w = httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		assert.Equal(t, http.StatusServiceUnavailable, w.Code, "expected load error returned")
+	})
+
+	t.Run("ServeWithEmptySession", func(t *testing.T) {
+		var sets int
+		h := session.Middleware(session.Config{
+			Store: &mock.Store{
+				GetFunc: st.GetFunc,
+				SetFunc: func(ctx context.Context, key string, value session.Data, opt session.StoreOption) error {
+					sets++
+					return nil
+				},
+			},
+			FailureMode: session.ServeWithEmptySession,
+		})(handler)
+
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Set("Cookie", sessName+"=test")
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "ok", w.Body.String())
+		assert.Empty(t, w.Result().Cookies(), "expected client's session cookie kept after load failed")
+		assert.Equal(t, 0, sets, "expected empty session not saved after load failed")
+
+		// session without cookie is not affected by store error
+		w = httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+		assert.Len(t, w.Result().Cookies(), 1)
+		assert.Equal(t, 1, sets)
+	})
+}
+
+func TestLifecycleHooks(t *testing.T) {
+	t.Parallel()
+
+	var events []string
+	h := session.Middleware(session.Config{
+		Store: new(store.Memory),
+		OnCreate: func(ctx context.Context, s *session.Session) {
+			events = append(events, "create")
+		},
+		OnRegenerate: func(ctx context.Context, oldID string, s *session.Session) {
+			assert.NotEqual(t, oldID, s.ID())
+			events = append(events, "regenerate")
+		},
+		OnDestroy: func(ctx context.Context, s *session.Session) {
+			events = append(events, "destroy")
+		},
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s, _ := session.Get(r.Context(), sessName)
+		switch r.URL.Path {
+		case "/create":
+			s.Set("a", 1)
+		case "/regenerate":
+			s.Regenerate()
+		case "/destroy":
+			s.Destroy()
+		}
+		w.Write([]byte("ok"))
+	}))
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/create", nil))
+	c := w.Result().Cookies()[0]
+	assert.Equal(t, []string{"create"}, events)
+
+	r := httptest.NewRequest(http.MethodGet, "/regenerate", nil)
+	r.AddCookie(c)
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	c = w.Result().Cookies()[0]
+	assert.Equal(t, []string{"create", "regenerate"}, events, "expected regenerated session not created")
+
+	r = httptest.NewRequest(http.MethodGet, "/destroy", nil)
+	r.AddCookie(c)
+	h.ServeHTTP(httptest.NewRecorder(), r)
+	assert.Equal(t, []string{"create", "regenerate", "destroy"}, events)
+}
+
+func TestOnCreateAfterSave(t *testing.T) {
+	t.Parallel()
+
+	var fail bool
+	var created int
+	m := session.New(session.Config{
+		Store: &mock.Store{
+			SetFunc: func(ctx context.Context, key string, value session.Data, opt session.StoreOption) error {
+				if fail {
+					return errors.New("store error")
+				}
+				return nil
+			},
+		},
+		OnCreate: func(ctx context.Context, s *session.Session) {
+			created++
+		},
+	})
+
+	ctx := context.Background()
+	s, _ := m.Get(httptest.NewRequest(http.MethodGet, "/", nil), sessName)
+	s.Set("a", 1)
+
+	fail = true
+	assert.Error(t, m.Save(ctx, httptest.NewRecorder(), s))
+	assert.Equal(t, 0, created, "expected OnCreate not called when store failed")
+
+	fail = false
+	assert.NoError(t, m.Save(ctx, httptest.NewRecorder(), s))
+	assert.Equal(t, 1, created)
+
+	s.Set("b", 2)
+	assert.NoError(t, m.Save(ctx, httptest.NewRecorder(), s))
+	assert.Equal(t, 1, created, "expected OnCreate called once")
+}
+
+func TestSkipper(t *testing.T) {
+	t.Parallel()
+
+	getCalled := false
+	h := session.Middleware(session.Config{
+		Store: &mock.Store{
+			GetFunc: func(ctx context.Context, key string) (session.Data, error) {
+				getCalled = true
+				return nil, session.ErrNotFound
+			},
+		},
+		Skipper: func(r *http.Request) bool {
+			return strings.HasPrefix(r.URL.Path, "/static/")
+		},
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, err := session.Get(r.Context(), sessName)
+		if err == session.ErrNotPassMiddleware {
+			w.Write([]byte("skipped"))
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+
+	r := httptest.NewRequest(http.MethodGet, "/static/app.js", nil)
+	r.Header.Set("Cookie", sessName+"=test")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	assert.Equal(t, "skipped", w.Body.String())
+	assert.False(t, getCalled)
+
+	r = httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("Cookie", sessName+"=test")
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	assert.Equal(t, "ok", w.Body.String())
+	assert.True(t, getCalled)
+}
+
+func TestLazyLoad(t *testing.T) {
+	t.Parallel()
+
+	h := session.Middleware(session.Config{
+		Store: &mock.Store{
+			GetFunc: func(ctx context.Context, key string) (session.Data, error) {
+				t.Error("expected store not called when handler does not use session")
+				return nil, session.ErrNotFound
+			},
+		},
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("Cookie", sessName+"=test")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	assert.Equal(t, "ok", w.Body.String())
+}
+
+func TestLateWrite(t *testing.T) {
+	t.Parallel()
+
+	var lateKeys []string
+	h := session.Middleware(session.Config{
+		Store: new(store.Memory),
+		OnLateWrite: func(s *session.Session, key string) {
+			lateKeys = append(lateKeys, key)
+		},
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s, _ := session.Get(r.Context(), sessName)
+		s.Set("a", 1)
+		w.Write([]byte("ok"))
+		s.Set("b", 2)
+		s.Del("a")
+		s.SetTTL("c", 3, time.Minute)
+		s.Inc("d", 1)
+		s.Flash().Add("e", "msg")
+		s.Clear()
+	}))
+
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	if assert.Len(t, lateKeys, 8) {
+		assert.Equal(t, []string{"b", "a", "c", "d", "e"}, lateKeys[:5])
+		assert.ElementsMatch(t, []string{"b", "c", "d"}, lateKeys[5:], "expected keys deleted by clear reported")
+	}
+}
+
+func TestPanicHandler(t *testing.T) {
+	t.Parallel()
+
+	var recovered interface{}
+	h := session.Middleware(session.Config{
+		Store: &mock.Store{
+			SetFunc: func(ctx context.Context, key string, value session.Data, opt session.StoreOption) error {
+				panic("store panic")
+			},
+		},
+		PanicHandler: func(w http.ResponseWriter, r *http.Request, v interface{}) {
+			recovered = v
+			http.Error(w, "internal error", http.StatusInternalServerError)
+		},
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s, _ := session.Get(r.Context(), sessName)
+		s.Set("a", 1)
+		w.Write([]byte("ok"))
+	}))
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, "store panic", recovered)
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Equal(t, "internal error\n", w.Body.String())
+}
+
 func TestDisableHashID(t *testing.T) {
 	t.Parallel()
 
@@ -664,8 +1144,8 @@ func TestDisableHashID(t *testing.T) {
 
 	h := session.Middleware(session.Config{
 		DisableHashID: true,
-		Store: &mockStore{
-			SetFunc: func(key string, value session.Data, opt session.StoreOption) error {
+		Store: &mock.Store{
+			SetFunc: func(ctx context.Context, key string, value session.Data, opt session.StoreOption) error {
 				setKey = key
 				return nil
 			},
@@ -686,7 +1166,7 @@ func TestSessionMultipleGet(t *testing.T) {
 	t.Parallel()
 
 	h := session.Middleware(session.Config{
-		Store: &mockStore{},
+		Store: &mock.Store{},
 	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		s, _ := session.Get(r.Context(), "sess")
 		s.Set("test", 1)
@@ -712,6 +1192,80 @@ func TestEmptyContext(t *testing.T) {
 	assert.Equal(t, session.ErrNotPassMiddleware, err)
 }
 
+func TestMustGet(t *testing.T) {
+	t.Parallel()
+
+	assert.Panics(t, func() {
+		session.MustGet(context.Background(), "sess")
+	}, "expected panic when not pass middleware")
+
+	h := session.Middleware(session.Config{
+		Store: &mock.Store{},
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.NotNil(t, session.MustGet(r.Context(), sessName))
+	}))
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	h.ServeHTTP(w, r)
+}
+
+func TestExists(t *testing.T) {
+	t.Parallel()
+
+	exists, err := session.Exists(context.Background(), "sess")
+	assert.False(t, exists)
+	assert.Equal(t, session.ErrNotPassMiddleware, err)
+
+	mem := new(store.Memory)
+	st := &mock.Store{GetFunc: mem.Get, SetFunc: mem.Set}
+	h := session.Middleware(session.Config{
+		Store:             st,
+		SaveUninitialized: true,
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		exists, err = session.Exists(r.Context(), sessName)
+		if r.URL.Path == "/set" {
+			session.MustGet(r.Context(), sessName).Set("a", 1)
+		}
+	}))
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.False(t, exists)
+	assert.NoError(t, err)
+	assert.Empty(t, w.Result().Cookies(), "expected exists not create session")
+	assert.Equal(t, 0, st.CallCount("Set"))
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/set", nil))
+	assert.False(t, exists)
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("Cookie", w.Header().Get("Set-Cookie"))
+	h.ServeHTTP(httptest.NewRecorder(), r)
+	assert.True(t, exists)
+	assert.NoError(t, err)
+
+	// store error
+	for _, mode := range []session.FailureMode{session.Return500, session.ServeWithEmptySession} {
+		h = session.Middleware(session.Config{
+			Store: &mock.Store{
+				GetFunc: func(ctx context.Context, key string) (session.Data, error) {
+					return nil, errors.New("store error")
+				},
+			},
+			FailureMode: mode,
+		})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			exists, err = session.Exists(r.Context(), sessName)
+		}))
+		r = httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Set("Cookie", w.Header().Get("Set-Cookie"))
+		h.ServeHTTP(httptest.NewRecorder(), r)
+		assert.False(t, exists)
+		assert.Error(t, err, "expected load error reported")
+	}
+}
+
 func TestFlash(t *testing.T) {
 	t.Parallel()
 
@@ -754,15 +1308,15 @@ func TestHijack(t *testing.T) {
 	setValue := make(map[string]session.Data)
 
 	h := session.Middleware(session.Config{
-		Store: &mockStore{
-			SetFunc: func(key string, value session.Data, opt session.StoreOption) error {
+		Store: &mock.Store{
+			SetFunc: func(ctx context.Context, key string, value session.Data, opt session.StoreOption) error {
 				setValue[key] = value
 				return nil
 			},
-			GetFunc: func(key string) (session.Data, error) {
+			GetFunc: func(ctx context.Context, key string) (session.Data, error) {
 				return setValue[key], nil
 			},
-			DelFunc: func(key string) error {
+			DelFunc: func(ctx context.Context, key string) error {
 				setValue[key] = nil
 				return nil
 			},
@@ -907,7 +1461,7 @@ func TestEmptyBody(t *testing.T) {
 
 func BenchmarkDefaultConfig(b *testing.B) {
 	h := session.Middleware(session.Config{
-		Store: &mockStore{},
+		Store: &mock.Store{},
 	})(mockHandler)
 	r := httptest.NewRequest(http.MethodGet, "/", nil)
 	for i := 0; i < b.N; i++ {
@@ -915,3 +1469,278 @@ func BenchmarkDefaultConfig(b *testing.B) {
 		h.ServeHTTP(w, r)
 	}
 }
+
+type batchStore struct {
+	store.Memory
+	getMulti int
+	setMulti int
+}
+
+func (s *batchStore) GetMulti(ctx context.Context, keys []string) (map[string]session.Data, error) {
+	s.getMulti++
+	return s.Memory.GetMulti(ctx, keys)
+}
+
+func (s *batchStore) SetMulti(ctx context.Context, values map[string]session.Data, opt session.StoreOption) error {
+	s.setMulti++
+	return s.Memory.SetMulti(ctx, values, opt)
+}
+
+func TestGetMulti(t *testing.T) {
+	t.Parallel()
+
+	st := new(batchStore)
+	h := session.Middleware(session.Config{
+		Store: st,
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ss, err := session.GetMulti(r.Context(), "sess1", "sess2")
+		if !assert.NoError(t, err) || !assert.Len(t, ss, 2) {
+			return
+		}
+		assert.Equal(t, "sess1", ss[0].Name)
+		assert.Equal(t, "sess2", ss[1].Name)
+
+		s, _ := session.Get(r.Context(), "sess1")
+		assert.Equal(t, ss[0], s, "expected get the same session")
+
+		var body string
+		for _, s := range ss {
+			body += fmt.Sprintf("%d", s.GetInt("cnt"))
+			s.Set("cnt", s.GetInt("cnt")+1)
+		}
+		fmt.Fprint(w, body)
+	}))
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	h.ServeHTTP(w, r)
+	assert.Equal(t, "00", w.Body.String())
+	assert.Equal(t, 1, st.setMulti)
+
+	r = httptest.NewRequest(http.MethodGet, "/", nil)
+	for _, c := range w.Result().Cookies() {
+		r.AddCookie(c)
+	}
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	assert.Equal(t, "11", w.Body.String())
+	assert.Equal(t, 1, st.getMulti)
+	assert.Equal(t, 2, st.setMulti)
+}
+
+func TestMultipleMiddlewares(t *testing.T) {
+	t.Parallel()
+
+	userStore := new(store.Memory)
+	adminStore := new(store.Memory)
+
+	var userID, adminID string
+	h := session.Middleware(session.Config{
+		Name:  "sess",
+		Store: userStore,
+	})(session.Middleware(session.Config{
+		Name:   "admin_sess",
+		Store:  adminStore,
+		MaxAge: time.Minute,
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s, err := session.Get(r.Context(), "sess")
+		assert.NoError(t, err)
+		s.Set("user", "u1")
+		userID = s.ID()
+
+		ss, err := session.GetMulti(r.Context(), "admin_sess", "sess")
+		assert.NoError(t, err)
+		assert.Equal(t, s, ss[1])
+		ss[0].Set("admin", true)
+		adminID = ss[0].ID()
+
+		w.Write([]byte("ok"))
+	})))
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	h.ServeHTTP(w, r)
+
+	cs := w.Result().Cookies()
+	if assert.Len(t, cs, 2) {
+		for _, c := range cs {
+			if c.Name == "admin_sess" {
+				assert.Equal(t, 60, c.MaxAge, "expected admin session use admin config")
+			}
+		}
+	}
+
+	ctx := context.Background()
+	_, err := userStore.Get(ctx, userID)
+	assert.NoError(t, err)
+	_, err = adminStore.Get(ctx, userID)
+	assert.Equal(t, session.ErrNotFound, err)
+	_, err = adminStore.Get(ctx, adminID)
+	assert.NoError(t, err)
+}
+
+func TestGetDefault(t *testing.T) {
+	t.Parallel()
+
+	h := session.Middleware(session.Config{
+		Store:           new(store.Memory),
+		RegisterDefault: true,
+	})(session.Middleware(session.Config{
+		Name:  "admin_sess",
+		Store: new(store.Memory),
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s := session.GetDefault(r)
+		assert.Equal(t, session.DefaultName, s.Name)
+		assert.Equal(t, s, session.MustGet(r.Context(), session.DefaultName))
+		s.Set("a", 1)
+		w.Write([]byte("ok"))
+	})))
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	cs := w.Result().Cookies()
+	if assert.Len(t, cs, 1) {
+		assert.Equal(t, session.DefaultName, cs[0].Name)
+	}
+
+	assert.Panics(t, func() {
+		session.GetDefault(httptest.NewRequest(http.MethodGet, "/", nil))
+	})
+}
+
+func TestSaveNow(t *testing.T) {
+	t.Parallel()
+
+	st := new(store.Memory)
+	sets := 0
+	h := session.Middleware(session.Config{
+		Store: &mock.Store{
+			SetFunc: func(ctx context.Context, key string, value session.Data, opt session.StoreOption) error {
+				sets++
+				return st.Set(ctx, key, value, opt)
+			},
+			GetFunc: st.Get,
+		},
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s, _ := session.Get(r.Context(), sessName)
+		s.Set("user", "u1")
+		if !assert.NoError(t, s.SaveNow(r.Context())) {
+			return
+		}
+
+		conn, bw, err := w.(http.Hijacker).Hijack()
+		if !assert.NoError(t, err) {
+			return
+		}
+		defer conn.Close()
+
+		body := s.GetString("user")
+		bw.WriteString("HTTP/1.1 200 OK\r\n")
+		w.Header().Write(bw)
+		fmt.Fprintf(bw, "Content-Length: %d\r\nConnection: close\r\n\r\n%s", len(body), body)
+		bw.Flush()
+	}))
+
+	done := make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer close(done)
+		h.ServeHTTP(w, r)
+	}))
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	assert.Equal(t, "u1", string(body))
+	assert.Len(t, resp.Cookies(), 1, "expected session cookie sent in hijacked response")
+
+	<-done
+	assert.Equal(t, 1, sets, "expected session not saved again after hijacked")
+}
+
+func TestCommit(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Stream", func(t *testing.T) {
+		sets := 0
+		st := new(store.Memory)
+		h := session.Middleware(session.Config{
+			Store: &mock.Store{
+				SetFunc: func(ctx context.Context, key string, value session.Data, opt session.StoreOption) error {
+					sets++
+					return st.Set(ctx, key, value, opt)
+				},
+				GetFunc: st.Get,
+			},
+		})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			s, _ := session.Get(r.Context(), sessName)
+			s.Set("user", "u1")
+			w.Header().Set("Content-Type", "text/event-stream")
+			if !assert.NoError(t, session.Commit(r.Context(), w)) {
+				return
+			}
+
+			rec := w.(interface{ Unwrap() http.ResponseWriter }).Unwrap().(*httptest.ResponseRecorder)
+			assert.True(t, rec.Flushed, "expected header flushed")
+			assert.Len(t, rec.Result().Cookies(), 1, "expected cookie sent before stream")
+			assert.Equal(t, 1, sets, "expected session saved before stream")
+			fmt.Fprint(w, "data: 1\n\n")
+		}))
+
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "data: 1\n\n", w.Body.String())
+		assert.Equal(t, 1, sets, "expected session not saved again after commit")
+	})
+
+	t.Run("Rolling", func(t *testing.T) {
+		h := session.Middleware(session.Config{
+			Store:   new(store.Memory),
+			Rolling: true,
+		})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			s, _ := session.Get(r.Context(), sessName)
+			if r.URL.Path == "/login" {
+				s.Set("user", "u1")
+				return
+			}
+			assert.NoError(t, session.Commit(r.Context(), w))
+		}))
+
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/login", nil))
+		cookie := w.Header().Get("Set-Cookie")
+
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Set("Cookie", cookie)
+		w = httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		assert.Len(t, w.Header().Values("Set-Cookie"), 1, "expected session not saved again after commit")
+	})
+
+	t.Run("Error", func(t *testing.T) {
+		h := session.Middleware(session.Config{
+			Store: &mock.Store{
+				SetFunc: func(ctx context.Context, key string, value session.Data, opt session.StoreOption) error {
+					return errors.New("store error")
+				},
+			},
+			FailureMode: session.ServeWithEmptySession,
+		})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			s, _ := session.Get(r.Context(), sessName)
+			s.Set("user", "u1")
+			if assert.Error(t, session.Commit(r.Context(), w)) {
+				http.Error(w, "session error", http.StatusServiceUnavailable)
+			}
+		}))
+
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+		assert.Equal(t, http.StatusServiceUnavailable, w.Code, "expected header not written on error")
+	})
+
+	assert.Equal(t, session.ErrNotPassMiddleware, session.Commit(context.Background(), httptest.NewRecorder()))
+}
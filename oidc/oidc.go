@@ -0,0 +1,146 @@
+// Package oidc provides helpers to store OAuth2 and OpenID Connect tokens in session,
+// with middleware that refreshes expired access token
+package oidc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/moonrhythm/session"
+)
+
+// ErrInvalidGrant is the error Refresh returns when refresh token was expired or revoked,
+// the token is removed from session
+var ErrInvalidGrant = errors.New("oidc: invalid grant")
+
+// DefaultKey is the default session key to store token
+const DefaultKey = "oidc/token"
+
+// expiryDelta is the time before expiry that access token is treated as expired
+const expiryDelta = 10 * time.Second
+
+// Token is the token from token endpoint
+type Token struct {
+	AccessToken  string    `json:"access_token"`
+	TokenType    string    `json:"token_type,omitempty"`
+	RefreshToken string    `json:"refresh_token,omitempty"`
+	IDToken      string    `json:"id_token,omitempty"`
+	Expiry       time.Time `json:"expiry,omitempty"`
+}
+
+// Valid checks is access token not empty and not expired
+func (t *Token) Valid() bool {
+	if t == nil || t.AccessToken == "" {
+		return false
+	}
+	return t.Expiry.IsZero() || time.Now().Add(expiryDelta).Before(t.Expiry)
+}
+
+// SetToken stores token in session with key, empty key is DefaultKey
+func SetToken(s *session.Session, key string, t *Token) {
+	if key == "" {
+		key = DefaultKey
+	}
+	// store as json string, to work with any store coder
+	b, _ := json.Marshal(t)
+	s.Set(key, string(b))
+}
+
+// GetToken gets token from session with key, empty key is DefaultKey,
+// returns nil if session does not have token
+func GetToken(s *session.Session, key string) *Token {
+	if key == "" {
+		key = DefaultKey
+	}
+	v := s.GetString(key)
+	if v == "" {
+		return nil
+	}
+	var t Token
+	if err := json.Unmarshal([]byte(v), &t); err != nil {
+		return nil
+	}
+	return &t
+}
+
+// DelToken removes token from session with key, empty key is DefaultKey
+func DelToken(s *session.Session, key string) {
+	if key == "" {
+		key = DefaultKey
+	}
+	s.Del(key)
+}
+
+// Config is the refresh middleware config
+type Config struct {
+	// SessionName is the session name to store token, default is session.DefaultName
+	SessionName string
+
+	// Key is the session key to store token, default is DefaultKey
+	Key string
+
+	// Refresh exchanges refresh token for new token, required,
+	// if new token does not have refresh token, the old refresh token is kept,
+	// returns ErrInvalidGrant to remove token from session
+	Refresh func(ctx context.Context, refreshToken string) (*Token, error)
+
+	// ErrorHandler handles request when Refresh failed with other errors,
+	// default responses 502 Bad Gateway
+	ErrorHandler func(w http.ResponseWriter, r *http.Request, err error)
+}
+
+// Middleware refreshes expired access token in session before handler,
+// session without token or refresh token is not changed
+//
+// Middleware must be used after session middleware
+func Middleware(config Config) func(http.Handler) http.Handler {
+	if config.Refresh == nil {
+		panic("oidc: nil refresh")
+	}
+	if config.SessionName == "" {
+		config.SessionName = session.DefaultName
+	}
+	if config.Key == "" {
+		config.Key = DefaultKey
+	}
+	if config.ErrorHandler == nil {
+		config.ErrorHandler = func(w http.ResponseWriter, r *http.Request, err error) {
+			http.Error(w, "Bad Gateway", http.StatusBadGateway)
+		}
+	}
+
+	return func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			s, err := session.Get(r.Context(), config.SessionName)
+			if err != nil {
+				http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+				return
+			}
+
+			t := GetToken(s, config.Key)
+			if t != nil && !t.Valid() && t.RefreshToken != "" {
+				nt, err := config.Refresh(r.Context(), t.RefreshToken)
+				switch {
+				case err == ErrInvalidGrant:
+					DelToken(s, config.Key)
+				case err != nil:
+					config.ErrorHandler(w, r, err)
+					return
+				default:
+					if nt.RefreshToken == "" {
+						nt.RefreshToken = t.RefreshToken
+					}
+					if nt.IDToken == "" {
+						nt.IDToken = t.IDToken
+					}
+					SetToken(s, config.Key, nt)
+				}
+			}
+
+			h.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,108 @@
+package oidc_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/moonrhythm/session"
+	"github.com/moonrhythm/session/oidc"
+	"github.com/moonrhythm/session/store"
+)
+
+func TestToken(t *testing.T) {
+	t.Parallel()
+
+	s := &session.Session{}
+	assert.Nil(t, oidc.GetToken(s, ""))
+
+	tk := &oidc.Token{
+		AccessToken:  "access",
+		RefreshToken: "refresh",
+		Expiry:       time.Now().Add(time.Hour).Truncate(time.Second),
+	}
+	oidc.SetToken(s, "", tk)
+	got := oidc.GetToken(s, "")
+	if assert.NotNil(t, got) {
+		assert.Equal(t, tk.AccessToken, got.AccessToken)
+		assert.Equal(t, tk.RefreshToken, got.RefreshToken)
+		assert.True(t, tk.Expiry.Equal(got.Expiry))
+		assert.True(t, got.Valid())
+	}
+
+	oidc.DelToken(s, "")
+	assert.Nil(t, oidc.GetToken(s, ""))
+
+	assert.False(t, (&oidc.Token{AccessToken: "a", Expiry: time.Now()}).Valid())
+	assert.True(t, (&oidc.Token{AccessToken: "a"}).Valid())
+	assert.False(t, (*oidc.Token)(nil).Valid())
+}
+
+func TestMiddleware(t *testing.T) {
+	t.Parallel()
+
+	var refreshErr error
+	refreshed := 0
+	h := session.Middleware(session.Config{
+		Store: new(store.Memory),
+	})(oidc.Middleware(oidc.Config{
+		Refresh: func(ctx context.Context, refreshToken string) (*oidc.Token, error) {
+			refreshed++
+			if refreshErr != nil {
+				return nil, refreshErr
+			}
+			assert.Equal(t, "refresh", refreshToken)
+			return &oidc.Token{AccessToken: "new", Expiry: time.Now().Add(time.Hour)}, nil
+		},
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s := session.MustGet(r.Context(), session.DefaultName)
+		if r.URL.Path == "/login" {
+			oidc.SetToken(s, "", &oidc.Token{
+				AccessToken:  "old",
+				RefreshToken: "refresh",
+				Expiry:       time.Now().Add(-time.Minute),
+			})
+		}
+		if tk := oidc.GetToken(s, ""); tk != nil {
+			w.Write([]byte(tk.AccessToken + " " + tk.RefreshToken))
+		}
+	})))
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/login", nil))
+	c := w.Result().Cookies()[0]
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.AddCookie(c)
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	assert.Equal(t, "new refresh", w.Body.String(), "expected token refreshed and refresh token kept")
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	assert.Equal(t, "new refresh", w.Body.String())
+	assert.Equal(t, 1, refreshed, "expected valid token not refreshed")
+
+	t.Run("Error", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/login", nil))
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.AddCookie(w.Result().Cookies()[0])
+
+		refreshErr = errors.New("network error")
+		w = httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		assert.Equal(t, http.StatusBadGateway, w.Code)
+
+		refreshErr = oidc.ErrInvalidGrant
+		w = httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Empty(t, w.Body.String(), "expected token removed")
+	})
+}
@@ -0,0 +1,227 @@
+package session
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"time"
+
+	"github.com/moonrhythm/session/internal/aesgcm"
+)
+
+// ErrInvalidPayload is the error when session payload can not be decoded
+var ErrInvalidPayload = errors.New("session: invalid payload")
+
+// payloadStore encodes session data into single payload before pass to store
+//
+// payload format is [version byte][encoded data],
+// encrypted with AES-GCM as [nonce][sealed payload] if encryption enabled,
+// then appended with [HMAC-SHA256] if signing enabled
+type payloadStore struct {
+	Store
+	coder   StoreCoder
+	version uint8
+	migrate func(version uint8, data Data) (Data, error)
+	cipher  aesgcm.Cipher
+	secrets [][]byte // for sign payload
+	maxSize int
+}
+
+func newPayloadStore(config *Config) Store {
+	payload := config.Coder != nil || config.PayloadVersion != 0 || config.Migrate != nil ||
+		len(config.EncryptionKeys) > 0 || config.SignPayload
+	if _, ok := config.Store.(CookieStore); ok {
+		// cookie store encodes and encrypts session data by itself
+		if payload {
+			panic("session: Coder, PayloadVersion, Migrate, EncryptionKeys and SignPayload are not supported by cookie store")
+		}
+		return config.Store
+	}
+	if !payload && config.MaxDataSize <= 0 {
+		return config.Store
+	}
+
+	s := payloadStore{
+		Store:   config.Store,
+		coder:   config.Coder,
+		version: config.PayloadVersion,
+		migrate: config.Migrate,
+		maxSize: config.MaxDataSize,
+	}
+	if s.coder == nil {
+		s.coder = DefaultStoreCoder
+	}
+	if len(config.EncryptionKeys) > 0 {
+		var err error
+		s.cipher, err = aesgcm.New(config.EncryptionKeys)
+		if err != nil {
+			panic("session: invalid encryption key; " + err.Error())
+		}
+	}
+	if config.SignPayload {
+		s.secrets = config.Secrets
+		if len(s.secrets) == 0 && len(config.Secret) > 0 {
+			s.secrets = [][]byte{config.Secret}
+		}
+		if len(s.secrets) == 0 {
+			panic("session: sign payload requires secret")
+		}
+	}
+	return &s
+}
+
+func (s *payloadStore) encode(key string, data Data) (Data, error) {
+	buf := bytes.NewBuffer([]byte{s.version})
+	err := s.coder.NewEncoder(buf).Encode(data)
+	if err != nil {
+		return nil, err
+	}
+
+	b := buf.Bytes()
+	if len(s.cipher) > 0 {
+		// use store key as additional data to prevent swapping payloads between sessions
+		b, err = s.cipher.Encrypt(b, []byte(key))
+		if err != nil {
+			return nil, err
+		}
+	}
+	if len(s.secrets) > 0 {
+		b = signPayload(key, b, s.secrets[0])
+	}
+	if s.maxSize > 0 && len(b) > s.maxSize {
+		return nil, &dataTooLargeError{size: len(b)}
+	}
+	r := Data{payloadKey: b}
+	// keep version readable by CASStore
+	if v, ok := data[versionKey]; ok {
+		r[versionKey] = v
+	}
+	return r, nil
+}
+
+func (s *payloadStore) decode(key string, data Data) (Data, error) {
+	var version uint8
+
+	b, ok := data[payloadKey].([]byte)
+	if !ok && (len(s.cipher) > 0 || len(s.secrets) > 0) {
+		// do not trust unencrypted or unsigned data
+		return nil, ErrNotFound
+	}
+	if ok {
+		if len(s.secrets) > 0 {
+			// tampered data is treated as not found
+			if b, ok = verifyPayload(key, b, s.secrets); !ok {
+				return nil, ErrNotFound
+			}
+		}
+		if len(s.cipher) > 0 {
+			// data encrypted by unknown key is treated as not found
+			if b, ok = s.cipher.Decrypt(b, []byte(key)); !ok {
+				return nil, ErrNotFound
+			}
+		}
+		if len(b) == 0 {
+			return nil, ErrInvalidPayload
+		}
+		version = b[0]
+
+		data = nil
+		err := s.coder.NewDecoder(bytes.NewReader(b[1:])).Decode(&data)
+		if err != nil {
+			return nil, err
+		}
+	}
+	// data saved before enable payload is version 0
+
+	if version != s.version && s.migrate != nil {
+		return s.migrate(version, data)
+	}
+	return data, nil
+}
+
+func (s *payloadStore) Get(ctx context.Context, key string) (Data, error) {
+	data, err := s.Store.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	return s.decode(key, data)
+}
+
+func (s *payloadStore) Set(ctx context.Context, key string, value Data, opt StoreOption) error {
+	data, err := s.encode(key, value)
+	if err != nil {
+		return err
+	}
+	return s.Store.Set(ctx, key, data, opt)
+}
+
+func (s *payloadStore) CompareAndSet(ctx context.Context, key string, version int64, value Data, opt StoreOption) error {
+	st, ok := s.Store.(CASStore)
+	if !ok {
+		return ErrNotSupported
+	}
+	data, err := s.encode(key, value)
+	if err != nil {
+		return err
+	}
+	return st.CompareAndSet(ctx, key, version, data, opt)
+}
+
+func (s *payloadStore) GetMulti(ctx context.Context, keys []string) (map[string]Data, error) {
+	st, ok := s.Store.(BatchStore)
+	if !ok {
+		return nil, ErrNotSupported
+	}
+	values, err := st.GetMulti(ctx, keys)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range values {
+		data, err := s.decode(k, v)
+		if err == ErrNotFound {
+			delete(values, k)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		values[k] = data
+	}
+	return values, nil
+}
+
+func (s *payloadStore) SetMulti(ctx context.Context, values map[string]Data, opt StoreOption) error {
+	st, ok := s.Store.(BatchStore)
+	if !ok {
+		return ErrNotSupported
+	}
+	encoded := make(map[string]Data, len(values))
+	for k, v := range values {
+		data, err := s.encode(k, v)
+		if err != nil {
+			return err
+		}
+		encoded[k] = data
+	}
+	return st.SetMulti(ctx, encoded, opt)
+}
+
+func (s *payloadStore) DelPrefix(ctx context.Context, prefix string) error {
+	st, ok := s.Store.(PrefixDeleter)
+	if !ok {
+		return ErrNotSupported
+	}
+	return st.DelPrefix(ctx, prefix)
+}
+
+func (s *payloadStore) Unwrap() Store {
+	return s.Store
+}
+
+func (s *payloadStore) Touch(ctx context.Context, key string, ttl time.Duration) error {
+	st, ok := s.Store.(Toucher)
+	if !ok {
+		return ErrNotSupported
+	}
+	return st.Touch(ctx, key, ttl)
+}
@@ -0,0 +1,252 @@
+package session_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/moonrhythm/session"
+	"github.com/moonrhythm/session/store/mock"
+)
+
+func TestPayload(t *testing.T) {
+	t.Parallel()
+
+	var saved session.Data
+	st := &mock.Store{
+		GetFunc: func(ctx context.Context, key string) (session.Data, error) {
+			if saved == nil {
+				return nil, session.ErrNotFound
+			}
+			return saved, nil
+		},
+		SetFunc: func(ctx context.Context, key string, value session.Data, opt session.StoreOption) error {
+			saved = value
+			return nil
+		},
+	}
+
+	m := session.New(session.Config{
+		Store:          st,
+		PayloadVersion: 2,
+	})
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	w := httptest.NewRecorder()
+	s, _ := m.Get(r, sessName)
+	s.Set("a", 1)
+	assert.NoError(t, m.Save(r.Context(), w, s))
+
+	if assert.Len(t, saved, 1, "expected store receives only payload") {
+		for _, v := range saved {
+			b, _ := v.([]byte)
+			if assert.NotEmpty(t, b) {
+				assert.Equal(t, byte(2), b[0], "expected payload prefixed with version")
+			}
+		}
+	}
+
+	cs := w.Result().Cookies()
+	if !assert.Len(t, cs, 1) {
+		return
+	}
+	r = httptest.NewRequest(http.MethodGet, "/", nil)
+	r.AddCookie(cs[0])
+	s, err := m.Get(r, sessName)
+	assert.NoError(t, err)
+	assert.False(t, s.IsNew())
+	assert.Equal(t, 1, s.GetInt("a"))
+}
+
+func TestPayloadMigrate(t *testing.T) {
+	t.Parallel()
+
+	st := &mock.Store{
+		GetFunc: func(ctx context.Context, key string) (session.Data, error) {
+			// saved before enable payload
+			return session.Data{"user": "u1"}, nil
+		},
+	}
+
+	var migrated []uint8
+	m := session.New(session.Config{
+		Store:          st,
+		PayloadVersion: 1,
+		Migrate: func(version uint8, data session.Data) (session.Data, error) {
+			migrated = append(migrated, version)
+			if data["user"] == "u2" {
+				return nil, session.ErrNotFound
+			}
+			return session.Data{"userId": data["user"]}, nil
+		},
+	})
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("Cookie", sessName+"=test")
+	s, err := m.Get(r, sessName)
+	assert.NoError(t, err)
+	assert.Equal(t, []uint8{0}, migrated)
+	assert.False(t, s.IsNew())
+	assert.Equal(t, "u1", s.GetString("userId"))
+
+	st.GetFunc = func(ctx context.Context, key string) (session.Data, error) {
+		return session.Data{"user": "u2"}, nil
+	}
+	s, err = m.Get(r, sessName)
+	assert.NoError(t, err)
+	assert.True(t, s.IsNew(), "expected discarded session")
+}
+
+func TestPayloadEncryption(t *testing.T) {
+	t.Parallel()
+
+	saved := make(map[string]session.Data)
+	st := &mock.Store{
+		GetFunc: func(ctx context.Context, key string) (session.Data, error) {
+			data, ok := saved[key]
+			if !ok {
+				return nil, session.ErrNotFound
+			}
+			return data, nil
+		},
+		SetFunc: func(ctx context.Context, key string, value session.Data, opt session.StoreOption) error {
+			saved[key] = value
+			return nil
+		},
+	}
+
+	oldKey := []byte("0123456789abcdef")
+	newKey := []byte("fedcba9876543210")
+
+	m := session.New(session.Config{
+		Store:          st,
+		EncryptionKeys: [][]byte{oldKey},
+	})
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	w := httptest.NewRecorder()
+	s, _ := m.Get(r, sessName)
+	s.Set("secret", "plaintext")
+	assert.NoError(t, m.Save(r.Context(), w, s))
+
+	for _, data := range saved {
+		for _, v := range data {
+			b, _ := v.([]byte)
+			assert.NotContains(t, string(b), "plaintext", "expected data encrypted")
+		}
+	}
+
+	cs := w.Result().Cookies()
+	if !assert.Len(t, cs, 1) {
+		return
+	}
+	r = httptest.NewRequest(http.MethodGet, "/", nil)
+	r.AddCookie(cs[0])
+
+	t.Run("RotateKey", func(t *testing.T) {
+		m := session.New(session.Config{
+			Store:          st,
+			EncryptionKeys: [][]byte{newKey, oldKey},
+		})
+		s, err := m.Get(r, sessName)
+		assert.NoError(t, err)
+		assert.Equal(t, "plaintext", s.GetString("secret"))
+	})
+
+	t.Run("UnknownKey", func(t *testing.T) {
+		m := session.New(session.Config{
+			Store:          st,
+			EncryptionKeys: [][]byte{newKey},
+		})
+		s, err := m.Get(r, sessName)
+		assert.NoError(t, err)
+		assert.True(t, s.IsNew(), "expected undecryptable session treated as not found")
+	})
+
+	t.Run("Unencrypted", func(t *testing.T) {
+		st := &mock.Store{
+			GetFunc: func(ctx context.Context, key string) (session.Data, error) {
+				return session.Data{"secret": "forged"}, nil
+			},
+		}
+		m := session.New(session.Config{
+			Store:          st,
+			EncryptionKeys: [][]byte{oldKey},
+		})
+		s, err := m.Get(r, sessName)
+		assert.NoError(t, err)
+		assert.True(t, s.IsNew(), "expected unencrypted session treated as not found")
+	})
+
+	t.Run("InvalidKey", func(t *testing.T) {
+		assert.Panics(t, func() {
+			session.New(session.Config{
+				Store:          st,
+				EncryptionKeys: [][]byte{[]byte("short")},
+			})
+		})
+	})
+}
+
+func TestPayloadSign(t *testing.T) {
+	t.Parallel()
+
+	saved := make(map[string]session.Data)
+	st := &mock.Store{
+		GetFunc: func(ctx context.Context, key string) (session.Data, error) {
+			data, ok := saved[key]
+			if !ok {
+				return nil, session.ErrNotFound
+			}
+			return data, nil
+		},
+		SetFunc: func(ctx context.Context, key string, value session.Data, opt session.StoreOption) error {
+			saved[key] = value
+			return nil
+		},
+	}
+
+	m := session.New(session.Config{
+		Store:       st,
+		Secret:      []byte("secret"),
+		SignPayload: true,
+	})
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	w := httptest.NewRecorder()
+	s, _ := m.Get(r, sessName)
+	s.Set("role", "user")
+	assert.NoError(t, m.Save(r.Context(), w, s))
+
+	cs := w.Result().Cookies()
+	if !assert.Len(t, cs, 1) {
+		return
+	}
+	r = httptest.NewRequest(http.MethodGet, "/", nil)
+	r.AddCookie(cs[0])
+
+	s, err := m.Get(r, sessName)
+	assert.NoError(t, err)
+	assert.Equal(t, "user", s.GetString("role"))
+
+	// tamper payload
+	for _, data := range saved {
+		for _, v := range data {
+			b := v.([]byte)
+			b[1] ^= 0xff
+		}
+	}
+	s, err = m.Get(r, sessName)
+	assert.NoError(t, err)
+	assert.True(t, s.IsNew(), "expected tampered session treated as not found")
+
+	assert.Panics(t, func() {
+		session.New(session.Config{
+			Store:       st,
+			SignPayload: true,
+		})
+	}, "expected panic when sign payload without secret")
+}
@@ -0,0 +1,243 @@
+package session
+
+import (
+	"bytes"
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ErrDataTooLarge is the error when encoded session data exceeds Config.MaxDataSize
+var ErrDataTooLarge = errors.New("session: data too large")
+
+// QuotaPolicy is the action when session exceeded its quota
+type QuotaPolicy int
+
+// QuotaPolicy values
+const (
+	QuotaReject      QuotaPolicy = iota // discard the write
+	QuotaEvictOldest                    // evict oldest non-reserved keys until write fits
+	QuotaCallback                       // keep the write, then call Quota.OnExceeded
+)
+
+// Quota is the per session quota config,
+// reserved keys (used internally by session) are not count to the quota
+type Quota struct {
+	// MaxKeys is the maximum number of keys in a session, zero means no limit
+	MaxKeys int
+
+	// MaxBytes is the maximum size of encoded session data
+	// estimated by DefaultStoreCoder, zero means no limit,
+	// each value is measured once when set, so the check does not encode the whole session
+	MaxBytes int
+
+	Policy QuotaPolicy
+
+	// OnExceeded is called when session exceeded quota with QuotaCallback policy
+	OnExceeded func(s *Session, key string)
+}
+
+func (q *Quota) enabled() bool {
+	return q.MaxKeys > 0 || q.MaxBytes > 0
+}
+
+// exceeded checks is session will exceed quota after set key to value,
+// size is the encoded size of value from entrySize
+func (q *Quota) exceeded(s *Session, key string, size int) bool {
+	if q.MaxKeys > 0 {
+		n := 0
+		for k := range s.data {
+			if !isReservedKey(k) && k != key {
+				n++
+			}
+		}
+		if n+1 > q.MaxKeys {
+			return true
+		}
+	}
+
+	if q.MaxBytes > 0 {
+		n := emptyDataSize() + size
+		for k := range s.data {
+			if !isReservedKey(k) && k != key {
+				n += s.dataSize(k)
+			}
+		}
+		if n > q.MaxBytes {
+			return true
+		}
+	}
+
+	return false
+}
+
+var (
+	emptyDataSizeOnce sync.Once
+	emptyDataSizeN    int
+)
+
+// emptyDataSize returns encoded size of empty data
+func emptyDataSize() int {
+	emptyDataSizeOnce.Do(func() {
+		var buf bytes.Buffer
+		if DefaultStoreCoder.NewEncoder(&buf).Encode(Data{}) == nil {
+			emptyDataSizeN = buf.Len()
+		}
+	})
+	return emptyDataSizeN
+}
+
+// entrySize returns encoded size that key and value adds to session data
+func entrySize(key string, value interface{}) int {
+	var buf bytes.Buffer
+	if err := DefaultStoreCoder.NewEncoder(&buf).Encode(Data{key: value}); err != nil {
+		// can not measure, let store reports the error
+		return 0
+	}
+	return buf.Len() - emptyDataSize()
+}
+
+// dataSize returns cached encoded size of key
+func (s *Session) dataSize(key string) int {
+	n, ok := s.sizes[key]
+	if !ok {
+		n = entrySize(key, s.data[key])
+		s.cacheSize(key, n)
+	}
+	return n
+}
+
+func (s *Session) cacheSize(key string, n int) {
+	if s.sizes == nil {
+		s.sizes = make(map[string]int)
+	}
+	s.sizes[key] = n
+}
+
+// dataTooLargeError is the error from store encode path
+// when encoded session data exceeds Config.MaxDataSize
+type dataTooLargeError struct {
+	size int
+}
+
+func (err *dataTooLargeError) Error() string {
+	return ErrDataTooLarge.Error()
+}
+
+// fitDataSize calls save, if encoded session data exceeds Config.MaxDataSize,
+// calls Config.OnDataTooLarge to let session fits the limit then saves again
+func (m *Manager) fitDataSize(s *Session, save func() error) error {
+	err := save()
+	e, ok := err.(*dataTooLargeError)
+	if !ok {
+		return err
+	}
+	if m.config.OnDataTooLarge != nil {
+		m.config.OnDataTooLarge(s, e.size)
+		err = save()
+		if _, ok = err.(*dataTooLargeError); !ok {
+			return err
+		}
+	}
+	return ErrDataTooLarge
+}
+
+func isReservedKey(key string) bool {
+	return strings.HasPrefix(key, reservedKeyPrefix)
+}
+
+// checkQuota checks quota before set key to value,
+// returns false if the write must be discarded,
+// and notify if OnExceeded must be called after the write
+func (s *Session) checkQuota(key string, value interface{}) (ok bool, notify bool) {
+	q := s.quota
+	if q == nil || !q.enabled() || isReservedKey(key) {
+		return true, false
+	}
+
+	size := 0
+	if q.MaxBytes > 0 {
+		size = entrySize(key, value)
+		defer func() {
+			if ok {
+				s.cacheSize(key, size)
+			}
+		}()
+	}
+
+	for q.exceeded(s, key, size) {
+		switch q.Policy {
+		case QuotaEvictOldest:
+			if !s.evictOldest(key) {
+				// nothing left to evict, value itself too large
+				return false, false
+			}
+		case QuotaCallback:
+			return true, q.OnExceeded != nil
+		default:
+			return false, false
+		}
+	}
+	return true, false
+}
+
+// keyOrder returns non-reserved keys ordered by write time, oldest first
+func (s *Session) keyOrder() []string {
+	order := toStrings(s.Get(keyOrderKey))
+
+	// add keys that not tracked (e.g. written before quota enabled)
+	// as the oldest keys, sorted to evict in the same order every time
+	tracked := make(map[string]bool, len(order))
+	for _, k := range order {
+		tracked[k] = true
+	}
+	var untracked []string
+	for k := range s.data {
+		if !isReservedKey(k) && !tracked[k] {
+			untracked = append(untracked, k)
+		}
+	}
+
+	sort.Strings(untracked)
+
+	r := make([]string, 0, len(order)+len(untracked))
+	r = append(r, untracked...)
+	for _, k := range order {
+		if _, ok := s.data[k]; ok {
+			r = append(r, k)
+		}
+	}
+	return r
+}
+
+// touchKeyOrder marks key as the newest key
+func (s *Session) touchKeyOrder(key string) {
+	if !s.trackKeyOrder() || isReservedKey(key) {
+		return
+	}
+	order := s.keyOrder()
+	r := order[:0]
+	for _, k := range order {
+		if k != key {
+			r = append(r, k)
+		}
+	}
+	s.data[keyOrderKey] = append(r, key)
+}
+
+func (s *Session) trackKeyOrder() bool {
+	return s.quota != nil && s.quota.enabled() && s.quota.Policy == QuotaEvictOldest
+}
+
+// evictOldest deletes the oldest non-reserved key except keep,
+// returns false if no key to evict
+func (s *Session) evictOldest(keep string) bool {
+	for _, k := range s.keyOrder() {
+		if k != keep {
+			s.Del(k)
+			return true
+		}
+	}
+	return false
+}
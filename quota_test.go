@@ -0,0 +1,157 @@
+package session
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQuota(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Reject", func(t *testing.T) {
+		s := Session{quota: &Quota{MaxKeys: 2}}
+		s.Set("a", 1)
+		s.Set("b", 2)
+		s.Set("c", 3)
+		s.Set(flashKey, []byte{})
+
+		assert.Nil(t, s.Get("c"), "expected write rejected")
+		assert.Equal(t, 1, s.Get("a"))
+		assert.NotNil(t, s.Get(flashKey), "expected reserved key not count to quota")
+
+		s.Set("a", 4)
+		assert.Equal(t, 4, s.Get("a"), "expected overwrite existing key allowed")
+	})
+
+	t.Run("EvictOldest", func(t *testing.T) {
+		s := Session{quota: &Quota{MaxKeys: 2, Policy: QuotaEvictOldest}}
+		s.Set("a", 1)
+		s.Set("b", 2)
+		s.Set("a", 3)
+		s.Set("c", 4)
+
+		assert.Nil(t, s.Get("b"), "expected oldest key evicted")
+		assert.Equal(t, 3, s.Get("a"))
+		assert.Equal(t, 4, s.Get("c"))
+	})
+
+	t.Run("EvictOldest Decoded", func(t *testing.T) {
+		s := Session{quota: &Quota{MaxKeys: 3, Policy: QuotaEvictOldest}}
+		s.data = Data{
+			"z":         1,
+			"y":         2,
+			"a":         3,
+			keyOrderKey: []interface{}{"y", "a"}, // decoded by json
+		}
+		s.Set("b", 4)
+		assert.Nil(t, s.Get("z"), "expected untracked key evicted first")
+		assert.Equal(t, 2, s.Get("y"))
+
+		s.data = Data{"c": 1, "b": 2, "a": 3}
+		s.Set("d", 4)
+		assert.Nil(t, s.Get("a"), "expected untracked keys evicted in sorted order")
+		assert.Equal(t, []string{"b", "c", "d"}, s.Get(keyOrderKey))
+	})
+
+	t.Run("EvictOldest Bytes", func(t *testing.T) {
+		s := Session{quota: &Quota{MaxBytes: 100, Policy: QuotaEvictOldest}}
+		s.Set("a", "1234567890")
+		s.Set("b", "1234567890")
+		s.Set("c", string(make([]byte, 60)))
+		assert.Nil(t, s.Get("a"))
+		assert.NotNil(t, s.Get("c"))
+
+		s.Set("d", string(make([]byte, 200)))
+		assert.Nil(t, s.Get("d"), "expected too large value rejected")
+	})
+
+	t.Run("Callback", func(t *testing.T) {
+		var called string
+		s := Session{quota: &Quota{
+			MaxKeys: 1,
+			Policy:  QuotaCallback,
+			OnExceeded: func(s *Session, key string) {
+				called = key
+				s.Del("a")
+			},
+		}}
+		s.Set("a", 1)
+		assert.Empty(t, called)
+
+		s.Set("b", 2)
+		assert.Equal(t, "b", called)
+		assert.Nil(t, s.Get("a"))
+		assert.Equal(t, 2, s.Get("b"))
+	})
+}
+
+func TestMaxDataSize(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+
+	t.Run("Fail", func(t *testing.T) {
+		m := New(Config{Store: new(writerTestStore), MaxDataSize: 500})
+		s, _ := m.Get(r, "sess")
+		s.Set("a", string(make([]byte, 1000)))
+
+		w := httptest.NewRecorder()
+		assert.Equal(t, ErrDataTooLarge, m.Save(ctx, w, s))
+		assert.Empty(t, w.Result().Cookies(), "expected cookie not set")
+
+		s.Del("a")
+		s.Set("b", "small")
+		assert.NoError(t, m.Save(ctx, w, s))
+	})
+
+	t.Run("Callback", func(t *testing.T) {
+		var size int
+		m := New(Config{
+			Store:       new(writerTestStore),
+			MaxDataSize: 500,
+			OnDataTooLarge: func(s *Session, n int) {
+				size = n
+				s.Del("a")
+			},
+		})
+		s, _ := m.Get(r, "sess")
+		s.Set("a", string(make([]byte, 1000)))
+		s.Set("b", "small")
+
+		assert.NoError(t, m.Save(ctx, httptest.NewRecorder(), s))
+		assert.Greater(t, size, 500)
+		assert.Nil(t, s.Get("a"), "expected callback truncated session")
+		assert.Equal(t, "small", s.Get("b"))
+	})
+
+	t.Run("Reserved Keys", func(t *testing.T) {
+		st := new(sizeTestStore)
+		save := func(max int) error {
+			m := New(Config{Store: st, MaxDataSize: max})
+			s, _ := m.Get(r, "sess")
+			s.Set("a", "1")
+			return m.Save(ctx, httptest.NewRecorder(), s)
+		}
+
+		assert.NoError(t, save(1<<20))
+		n := len(st.stored[payloadKey].([]byte))
+		assert.NoError(t, save(n))
+		assert.Equal(t, ErrDataTooLarge, save(n-1), "expected reserved keys measured")
+	})
+}
+
+// sizeTestStore records the last saved data
+type sizeTestStore struct {
+	writerTestStore
+	stored Data
+}
+
+func (s *sizeTestStore) Set(ctx context.Context, key string, value Data, opt StoreOption) error {
+	s.stored = value
+	return nil
+}
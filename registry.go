@@ -0,0 +1,201 @@
+package session
+
+import (
+	"bytes"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RegisterType registers type of v for DefaultStoreCoder,
+// returns error if type or its name conflicts with already registered type,
+// use Registry to isolate types between applications
+func RegisterType(v interface{}) (err error) {
+	defer func() {
+		r := recover()
+		if r == nil {
+			return
+		}
+		// gob panics on conflicting registration, other panics are not ours to handle
+		if msg, ok := r.(string); ok && strings.HasPrefix(msg, "gob: registering duplicate") {
+			err = errors.New("session: " + strings.TrimPrefix(msg, "gob: "))
+			return
+		}
+		panic(r)
+	}()
+	gob.Register(v)
+	return nil
+}
+
+// Registry is the store coder that encodes session data
+// using types registered into the registry instead of global gob registry,
+// so conflicting types can be registered into different registries
+//
+// Registry encodes each value using gob,
+// values inside slice or map of interface{} still use global gob registry
+type Registry struct {
+	mu     sync.RWMutex
+	byName map[string]reflect.Type
+	byType map[reflect.Type]string
+}
+
+// NewRegistry creates new registry with basic types registered
+func NewRegistry() *Registry {
+	r := Registry{
+		byName: make(map[string]reflect.Type),
+		byType: make(map[reflect.Type]string),
+	}
+	for _, v := range []interface{}{
+		"", false,
+		int(0), int8(0), int16(0), int32(0), int64(0),
+		uint(0), uint8(0), uint16(0), uint32(0), uint64(0),
+		float32(0), float64(0),
+		[]byte(nil), []string(nil), []int(nil), []int64(nil),
+		map[string]string(nil),
+		time.Time{}, time.Duration(0),
+	} {
+		r.Register(v)
+	}
+	return &r
+}
+
+// Register registers type of v using type name
+func (r *Registry) Register(v interface{}) {
+	t := reflect.TypeOf(v)
+	name := t.String()
+	if t.Name() != "" && t.PkgPath() != "" {
+		name = t.PkgPath() + "." + t.Name()
+	}
+	r.RegisterName(name, v)
+}
+
+// RegisterName registers type of v using given name,
+// registering the same name or type again replaces the old one
+func (r *Registry) RegisterName(name string, v interface{}) {
+	t := reflect.TypeOf(v)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if old, ok := r.byName[name]; ok {
+		delete(r.byType, old)
+	}
+	if old, ok := r.byType[t]; ok {
+		delete(r.byName, old)
+	}
+	r.byName[name] = t
+	r.byType[t] = name
+}
+
+func (r *Registry) typeName(t reflect.Type) (string, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	name, ok := r.byType[t]
+	return name, ok
+}
+
+func (r *Registry) typeOf(name string) (reflect.Type, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	t, ok := r.byName[name]
+	return t, ok
+}
+
+type registryEntry struct {
+	Key   string
+	Type  string // empty for nil value
+	Value []byte
+}
+
+// NewEncoder implements StoreCoder
+func (r *Registry) NewEncoder(w io.Writer) StoreEncoder {
+	return &registryEncoder{r, w}
+}
+
+// NewDecoder implements StoreCoder
+func (r *Registry) NewDecoder(rd io.Reader) StoreDecoder {
+	return &registryDecoder{r, rd}
+}
+
+type registryEncoder struct {
+	r *Registry
+	w io.Writer
+}
+
+func (enc *registryEncoder) Encode(e interface{}) error {
+	var data Data
+	switch e := e.(type) {
+	case Data:
+		data = e
+	case *Data:
+		data = *e
+	default:
+		return fmt.Errorf("session: registry can not encode %T", e)
+	}
+
+	entries := make([]registryEntry, 0, len(data))
+	for k, v := range data {
+		entry := registryEntry{Key: k}
+		if v != nil {
+			t := reflect.TypeOf(v)
+			name, ok := enc.r.typeName(t)
+			if !ok {
+				return fmt.Errorf("session: type not registered: %s", t)
+			}
+
+			var buf bytes.Buffer
+			err := gob.NewEncoder(&buf).EncodeValue(reflect.ValueOf(v))
+			if err != nil {
+				return err
+			}
+			entry.Type = name
+			entry.Value = buf.Bytes()
+		}
+		entries = append(entries, entry)
+	}
+	return gob.NewEncoder(enc.w).Encode(entries)
+}
+
+type registryDecoder struct {
+	r  *Registry
+	rd io.Reader
+}
+
+func (dec *registryDecoder) Decode(e interface{}) error {
+	p, ok := e.(*Data)
+	if !ok {
+		return fmt.Errorf("session: registry can not decode into %T", e)
+	}
+
+	var entries []registryEntry
+	err := gob.NewDecoder(dec.rd).Decode(&entries)
+	if err != nil {
+		return err
+	}
+
+	data := make(Data, len(entries))
+	for _, entry := range entries {
+		if entry.Type == "" {
+			data[entry.Key] = nil
+			continue
+		}
+
+		t, ok := dec.r.typeOf(entry.Type)
+		if !ok {
+			return fmt.Errorf("session: type not registered: %s", entry.Type)
+		}
+		v := reflect.New(t)
+		err = gob.NewDecoder(bytes.NewReader(entry.Value)).DecodeValue(v)
+		if err != nil {
+			return err
+		}
+		data[entry.Key] = v.Elem().Interface()
+	}
+	*p = data
+	return nil
+}
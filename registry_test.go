@@ -0,0 +1,101 @@
+package session
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type registryUserA struct {
+	Name string
+}
+
+type registryUserB struct {
+	ID int
+}
+
+func TestRegistry(t *testing.T) {
+	t.Parallel()
+
+	now := time.Unix(100, 0).UTC()
+
+	r := NewRegistry()
+	r.Register(registryUserA{})
+
+	var buf bytes.Buffer
+	err := r.NewEncoder(&buf).Encode(Data{
+		"user":    registryUserA{Name: "a"},
+		"int":     1,
+		"int64":   int64(2),
+		"string":  "s",
+		"time":    now,
+		"nil":     nil,
+		"strings": []string{"a", "b"},
+	})
+	assert.NoError(t, err)
+
+	var d Data
+	err = r.NewDecoder(&buf).Decode(&d)
+	assert.NoError(t, err)
+	assert.Equal(t, Data{
+		"user":    registryUserA{Name: "a"},
+		"int":     1,
+		"int64":   int64(2),
+		"string":  "s",
+		"time":    now,
+		"nil":     nil,
+		"strings": []string{"a", "b"},
+	}, d)
+}
+
+func TestRegistryConflict(t *testing.T) {
+	t.Parallel()
+
+	// same name for different types in different registries
+	r1 := NewRegistry()
+	r1.RegisterName("user", registryUserA{})
+	r2 := NewRegistry()
+	r2.RegisterName("user", registryUserB{})
+
+	var buf bytes.Buffer
+	assert.NoError(t, r1.NewEncoder(&buf).Encode(Data{"u": registryUserA{Name: "a"}}))
+	var d Data
+	assert.NoError(t, r1.NewDecoder(&buf).Decode(&d))
+	assert.Equal(t, registryUserA{Name: "a"}, d["u"])
+
+	buf.Reset()
+	assert.NoError(t, r2.NewEncoder(&buf).Encode(Data{"u": registryUserB{ID: 1}}))
+	assert.NoError(t, r2.NewDecoder(&buf).Decode(&d))
+	assert.Equal(t, registryUserB{ID: 1}, d["u"])
+}
+
+func TestRegistryNotRegistered(t *testing.T) {
+	t.Parallel()
+
+	r := NewRegistry()
+
+	var buf bytes.Buffer
+	err := r.NewEncoder(&buf).Encode(Data{"u": registryUserB{}})
+	assert.Error(t, err)
+
+	r.Register(registryUserB{})
+	assert.NoError(t, r.NewEncoder(&buf).Encode(Data{"u": registryUserB{}}))
+
+	var d Data
+	err = NewRegistry().NewDecoder(&buf).Decode(&d)
+	assert.Error(t, err, "expected decode unknown type fails")
+}
+
+func TestRegisterType(t *testing.T) {
+	t.Parallel()
+
+	type conflict struct{ A int }
+
+	assert.NoError(t, RegisterType(registryUserA{}))
+	assert.NoError(t, RegisterType(registryUserA{}), "expected register the same type again is no-op")
+	assert.NoError(t, RegisterType(&conflict{}))
+	assert.Error(t, RegisterType(conflict{}))
+	assert.Panics(t, func() { RegisterType(nil) }, "expected not recover unrelated panic")
+}
@@ -0,0 +1,275 @@
+// Package rememberme provides long-lived remember-me token
+// to restore user session after session expired
+//
+// Token is the selector and verifier pair, selector is the store key,
+// only hash of verifier is stored; verifier is rotated every time token is used,
+// token presented with valid selector but wrong verifier is treated as stolen,
+// except the previous verifier within grace period, e.g. parallel requests sent with the same token
+package rememberme
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/moonrhythm/session"
+)
+
+// Config is the remember-me middleware config
+type Config struct {
+	// Store stores token, required
+	Store session.Store
+
+	// SessionName is the session name to restore, default is session.DefaultName
+	SessionName string
+
+	// Key is the session key of user id, default is "user",
+	// session without user id is restored from token
+	Key string
+
+	// Cookie config, default cookie name is "remember", and max age is 30 days,
+	// cookie is always http only
+	CookieName string
+	Domain     string
+	Path       string
+	MaxAge     time.Duration
+	Secure     bool
+	SameSite   http.SameSite
+
+	// Grace is the duration previous verifier is still accepted after rotated,
+	// default is 10 seconds
+	Grace time.Duration
+
+	// OnTheft is called when token verifier mismatch,
+	// the token is deleted, app should destroy all user's sessions
+	OnTheft func(r *http.Request, userID string)
+}
+
+const (
+	storePrefix     = "rememberme/"
+	verifierKey     = "verifier"
+	prevVerifierKey = "prev_verifier"
+	prevExpiresKey  = "prev_expires"
+	userKey         = "user"
+)
+
+type ctxKey struct{}
+
+// Middleware restores user id to new session from remember-me token,
+// and rotates the token
+//
+// Middleware must be used after session middleware
+func Middleware(config Config) func(http.Handler) http.Handler {
+	if config.Store == nil {
+		panic("rememberme: nil store")
+	}
+	if config.SessionName == "" {
+		config.SessionName = session.DefaultName
+	}
+	if config.Key == "" {
+		config.Key = "user"
+	}
+	if config.CookieName == "" {
+		config.CookieName = "remember"
+	}
+	if config.MaxAge <= 0 {
+		config.MaxAge = 30 * 24 * time.Hour
+	}
+	if config.Grace <= 0 {
+		config.Grace = 10 * time.Second
+	}
+
+	return func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			s, err := session.Get(r.Context(), config.SessionName)
+			if err != nil {
+				http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+				return
+			}
+
+			if s.Get(config.Key) == nil {
+				if err := restore(w, r, s, &config); err != nil {
+					http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+					return
+				}
+			}
+
+			ctx := context.WithValue(r.Context(), ctxKey{}, &config)
+			h.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// restore sets user id to session from token
+func restore(w http.ResponseWriter, r *http.Request, s *session.Session, config *Config) error {
+	selector, verifier := readCookie(r, config)
+	if selector == "" {
+		return nil
+	}
+
+	ctx := r.Context()
+	data, err := config.Store.Get(ctx, storePrefix+selector)
+	if err == session.ErrNotFound {
+		clearCookie(w, config)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	userID, _ := data[userKey].(string)
+	if verifyPrev(data, verifier) {
+		// token was rotated by parallel request, new token is sent by that response
+		return login(s, config, userID)
+	}
+	if !verify(data, verifier) {
+		// selector is valid, but verifier mismatch,
+		// token was used by someone else
+		err = config.Store.Del(ctx, storePrefix+selector)
+		clearCookie(w, config)
+		if config.OnTheft != nil {
+			config.OnTheft(r, userID)
+		}
+		return err
+	}
+
+	// rotate verifier, keep selector to detect old verifier
+	err = rotate(ctx, w, config, selector, userID, data[verifierKey])
+	if err != nil {
+		return err
+	}
+	return login(s, config, userID)
+}
+
+// login sets user id to renewed session to prevent session fixation
+func login(s *session.Session, config *Config, userID string) error {
+	err := s.Renew()
+	if err != nil {
+		return err
+	}
+	s.Set(config.Key, userID)
+	return nil
+}
+
+// Remember issues new remember-me token for user id
+func Remember(w http.ResponseWriter, r *http.Request, userID string) error {
+	config, _ := r.Context().Value(ctxKey{}).(*Config)
+	if config == nil {
+		panic("rememberme: request not pass middleware")
+	}
+	return rotate(r.Context(), w, config, generateToken(), userID, nil)
+}
+
+// Forget deletes remember-me token from store and client
+func Forget(w http.ResponseWriter, r *http.Request) error {
+	config, _ := r.Context().Value(ctxKey{}).(*Config)
+	if config == nil {
+		panic("rememberme: request not pass middleware")
+	}
+	clearCookie(w, config)
+
+	selector, _ := readCookie(r, config)
+	if selector == "" {
+		return nil
+	}
+	err := config.Store.Del(r.Context(), storePrefix+selector)
+	if err == session.ErrNotFound {
+		return nil
+	}
+	return err
+}
+
+// rotate saves new verifier for selector, and keeps previous verifier hash for grace period
+func rotate(ctx context.Context, w http.ResponseWriter, config *Config, selector, userID string, prev interface{}) error {
+	verifier := generateToken()
+	data := session.Data{
+		verifierKey: hash(verifier),
+		userKey:     userID,
+	}
+	if prev, _ := prev.(string); prev != "" {
+		data[prevVerifierKey] = prev
+		data[prevExpiresKey] = time.Now().Add(config.Grace).Format(time.RFC3339Nano)
+	}
+	err := config.Store.Set(ctx, storePrefix+selector, data, session.StoreOption{TTL: config.MaxAge})
+	if err != nil {
+		return err
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     config.CookieName,
+		Value:    selector + ":" + verifier,
+		Domain:   config.Domain,
+		Path:     config.Path,
+		MaxAge:   int(config.MaxAge / time.Second),
+		Expires:  time.Now().Add(config.MaxAge),
+		Secure:   config.Secure,
+		HttpOnly: true,
+		SameSite: config.SameSite,
+	})
+	return nil
+}
+
+func readCookie(r *http.Request, config *Config) (selector, verifier string) {
+	c, err := r.Cookie(config.CookieName)
+	if err != nil {
+		return "", ""
+	}
+	selector, verifier, ok := strings.Cut(c.Value, ":")
+	if !ok || selector == "" || verifier == "" {
+		return "", ""
+	}
+	return selector, verifier
+}
+
+func clearCookie(w http.ResponseWriter, config *Config) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     config.CookieName,
+		Domain:   config.Domain,
+		Path:     config.Path,
+		MaxAge:   -1,
+		Expires:  time.Unix(0, 0),
+		Secure:   config.Secure,
+		HttpOnly: true,
+		SameSite: config.SameSite,
+	})
+}
+
+func verify(data session.Data, verifier string) bool {
+	return compare(data[verifierKey], verifier)
+}
+
+// verifyPrev checks verifier with previous verifier in grace period
+func verifyPrev(data session.Data, verifier string) bool {
+	v, _ := data[prevExpiresKey].(string)
+	exp, err := time.Parse(time.RFC3339Nano, v)
+	if err != nil || !time.Now().Before(exp) {
+		return false
+	}
+	return compare(data[prevVerifierKey], verifier)
+}
+
+func compare(h interface{}, verifier string) bool {
+	s, _ := h.(string)
+	if s == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(s), []byte(hash(verifier))) == 1
+}
+
+func hash(verifier string) string {
+	h := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(h[:])
+}
+
+func generateToken() string {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		panic("rememberme: can not generate token; " + err.Error())
+	}
+	return base64.RawURLEncoding.EncodeToString(b)
+}
@@ -0,0 +1,189 @@
+package rememberme_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/moonrhythm/session"
+	"github.com/moonrhythm/session/rememberme"
+	"github.com/moonrhythm/session/store"
+)
+
+// cookie returns the last cookie with the name, as browser applies
+func cookie(cs []*http.Cookie, name string) *http.Cookie {
+	var r *http.Cookie
+	for _, c := range cs {
+		if c.Name == name {
+			r = c
+		}
+	}
+	return r
+}
+
+func TestRememberMe(t *testing.T) {
+	t.Parallel()
+
+	var stolen string
+	h := session.Middleware(session.Config{
+		Store: new(store.Memory),
+	})(rememberme.Middleware(rememberme.Config{
+		Store: new(store.Memory),
+		Grace: 10 * time.Millisecond,
+		OnTheft: func(r *http.Request, userID string) {
+			stolen = userID
+		},
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s := session.MustGet(r.Context(), session.DefaultName)
+		switch r.URL.Path {
+		case "/login":
+			s.Set("user", "u1")
+			assert.NoError(t, rememberme.Remember(w, r, "u1"))
+		case "/logout":
+			s.Destroy()
+			assert.NoError(t, rememberme.Forget(w, r))
+		case "/anonymous":
+			s.Set("cart", "1")
+		}
+		w.Write([]byte(s.GetString("user")))
+	})))
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/login", nil))
+	token := cookie(w.Result().Cookies(), "remember")
+	if !assert.NotNil(t, token) {
+		return
+	}
+	assert.True(t, token.HttpOnly)
+
+	// main session expired, and anonymous session started
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/anonymous", nil))
+	anonymous := cookie(w.Result().Cookies(), session.DefaultName)
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.AddCookie(token)
+	r.AddCookie(anonymous)
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	assert.Equal(t, "u1", w.Body.String(), "expected session restored from token")
+	rotated := cookie(w.Result().Cookies(), "remember")
+	if !assert.NotNil(t, rotated) {
+		return
+	}
+	assert.NotEqual(t, token.Value, rotated.Value, "expected token rotated")
+	if c := cookie(w.Result().Cookies(), session.DefaultName); assert.NotNil(t, c, "expected new session saved") {
+		assert.NotEqual(t, anonymous.Value, c.Value, "expected session id renewed")
+	}
+
+	// old token used again after grace period
+	time.Sleep(20 * time.Millisecond)
+	r = httptest.NewRequest(http.MethodGet, "/", nil)
+	r.AddCookie(token)
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	assert.Empty(t, w.Body.String())
+	assert.Equal(t, "u1", stolen, "expected theft detected")
+	assert.Equal(t, -1, cookie(w.Result().Cookies(), "remember").MaxAge)
+
+	// token was revoked after theft detected
+	r = httptest.NewRequest(http.MethodGet, "/", nil)
+	r.AddCookie(rotated)
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	assert.Empty(t, w.Body.String())
+}
+
+func TestRememberMeGrace(t *testing.T) {
+	t.Parallel()
+
+	var stolen bool
+	h := session.Middleware(session.Config{
+		Store: new(store.Memory),
+	})(rememberme.Middleware(rememberme.Config{
+		Store: new(store.Memory),
+		OnTheft: func(r *http.Request, userID string) {
+			stolen = true
+		},
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s := session.MustGet(r.Context(), session.DefaultName)
+		if r.URL.Path == "/login" {
+			assert.NoError(t, rememberme.Remember(w, r, "u1"))
+		}
+		w.Write([]byte(s.GetString("user")))
+	})))
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/login", nil))
+	token := cookie(w.Result().Cookies(), "remember")
+
+	// parallel requests with the same token
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.AddCookie(token)
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	assert.Equal(t, "u1", w.Body.String())
+	rotated := cookie(w.Result().Cookies(), "remember")
+
+	r = httptest.NewRequest(http.MethodGet, "/", nil)
+	r.AddCookie(token)
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	assert.Equal(t, "u1", w.Body.String(), "expected previous verifier accepted in grace period")
+	assert.Nil(t, cookie(w.Result().Cookies(), "remember"), "expected token not rotated again")
+	assert.False(t, stolen)
+
+	// forged verifier
+	r = httptest.NewRequest(http.MethodGet, "/", nil)
+	r.AddCookie(&http.Cookie{Name: "remember", Value: strings.Split(token.Value, ":")[0] + ":forged"})
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	assert.Empty(t, w.Body.String())
+	assert.True(t, stolen)
+
+	r = httptest.NewRequest(http.MethodGet, "/", nil)
+	r.AddCookie(rotated)
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	assert.Empty(t, w.Body.String(), "expected token revoked")
+}
+
+func TestForget(t *testing.T) {
+	t.Parallel()
+
+	h := session.Middleware(session.Config{
+		Store: new(store.Memory),
+	})(rememberme.Middleware(rememberme.Config{
+		Store: new(store.Memory),
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s := session.MustGet(r.Context(), session.DefaultName)
+		switch r.URL.Path {
+		case "/login":
+			assert.NoError(t, rememberme.Remember(w, r, "u1"))
+		case "/logout":
+			s.Destroy()
+			assert.NoError(t, rememberme.Forget(w, r))
+		}
+		w.Write([]byte(s.GetString("user")))
+	})))
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/login", nil))
+	token := cookie(w.Result().Cookies(), "remember")
+
+	r := httptest.NewRequest(http.MethodPost, "/logout", nil)
+	r.AddCookie(token)
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	assert.Equal(t, -1, cookie(w.Result().Cookies(), "remember").MaxAge)
+
+	r = httptest.NewRequest(http.MethodGet, "/", nil)
+	r.AddCookie(token)
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	assert.Empty(t, w.Body.String(), "expected token deleted")
+}
@@ -1,7 +1,11 @@
 package session
 
 import (
+	"context"
+	"encoding/json"
 	"net/http"
+	"sort"
+	"strings"
 	"time"
 )
 
@@ -12,10 +16,34 @@ type Data map[string]interface{}
 type Session struct {
 	id      string // id is the hashed id if hash enabled
 	rawID   string
+	token   string // signed raw id for client
 	data    Data
 	changed bool
 	isNew   bool
+	created bool   // new session prepared to save, OnCreate is pending
+	oldKey  string // store key of data loaded with old secret, linked to the new key when save
+	touched bool   // force resave and rewrite cookie
+	version int64  // version of data in store, for DetectConflict
 	flash   *Flash
+	quota   *Quota
+	sizes   map[string]int // encoded size by key, for Quota.MaxBytes
+
+	// loadFailed is set when store failed to load the session,
+	// the empty session must not overwrite client's session
+	loadFailed bool
+
+	// for store session data in cookie
+	destroyed bool
+	chunks    int // number of chunk cookies
+
+	// client info from request
+	ip          string
+	userAgent   string
+	fingerprint string
+
+	analyticsID      string
+	analyticsChanged bool
+	analyticsGen     func() string
 
 	// cookie config
 	Name     string
@@ -27,6 +55,10 @@ type Session struct {
 	SameSite http.SameSite
 	Rolling  bool
 
+	// Partitioned sets cookie partitioned by top-level site (CHIPS),
+	// partitioned cookie is always secure
+	Partitioned bool
+
 	m *scopedManager
 }
 
@@ -39,11 +71,23 @@ func (data Data) Clone() Data {
 	return r
 }
 
+// Version returns version of session data,
+// version is incremented on each save when Config.DetectConflict enabled
+func (data Data) Version() int64 {
+	return toInt64(data[versionKey])
+}
+
 // ID returns session id or hashed session id if enable hash id
 func (s *Session) ID() string {
 	return s.id
 }
 
+// Token returns session id value for client,
+// signed if Config.Keys is set
+func (s *Session) Token() string {
+	return s.token
+}
+
 // Changed returns is session data changed
 func (s *Session) Changed() bool {
 	if s.changed {
@@ -58,7 +102,7 @@ func (s *Session) Changed() bool {
 
 // Get gets data from session
 func (s *Session) Get(key string) interface{} {
-	if s.data == nil {
+	if s.data == nil || s.dataExpired(key) {
 		return nil
 	}
 	return s.data[key]
@@ -100,13 +144,58 @@ func (s *Session) GetBool(key string) bool {
 	return r
 }
 
+// GetDefault gets data from session,
+// returns fallback if key not exists
+func (s *Session) GetDefault(key string, fallback interface{}) interface{} {
+	if r, ok := s.data[key]; ok && !s.dataExpired(key) {
+		return r
+	}
+	return fallback
+}
+
+// GetOrSet gets data from session,
+// if key not exists sets value returned from fn to session then returns it
+func (s *Session) GetOrSet(key string, fn func() interface{}) interface{} {
+	if r, ok := s.data[key]; ok && !s.dataExpired(key) {
+		return r
+	}
+	r := fn()
+	s.Set(key, r)
+	return r
+}
+
 // Set sets data to session
 func (s *Session) Set(key string, value interface{}) {
+	s.set(key, value)
+}
+
+// SetTTL sets data to session which expires after ttl independently of session,
+// e.g. short-lived verification code, expired data is not returned by Get,
+// and removed when session is loaded
+func (s *Session) SetTTL(key string, value interface{}, ttl time.Duration) {
+	if s.set(key, value) {
+		s.data[ttlPrefix+key] = time.Now().Add(ttl).UnixNano()
+	}
+}
+
+// set sets data to session, returns false if the write was discarded
+func (s *Session) set(key string, value interface{}) bool {
 	if s.data == nil {
 		s.data = make(Data)
 	}
+	ok, notify := s.checkQuota(key, value)
+	if !ok {
+		return false
+	}
 	s.changed = true
 	s.data[key] = value
+	delete(s.data, ttlPrefix+key)
+	s.touchKeyOrder(key)
+	s.checkLateWrite(key)
+	if notify {
+		s.quota.OnExceeded(s, key)
+	}
+	return true
 }
 
 // Del deletes data from session
@@ -117,6 +206,42 @@ func (s *Session) Del(key string) {
 	if _, ok := s.data[key]; ok {
 		s.changed = true
 		delete(s.data, key)
+		delete(s.data, ttlPrefix+key)
+		s.checkLateWrite(key)
+	}
+}
+
+// Keys returns sorted keys of session data
+func (s *Session) Keys() []string {
+	r := make([]string, 0, len(s.data))
+	for k := range s.data {
+		if !isReservedKey(k) {
+			r = append(r, k)
+		}
+	}
+	sort.Strings(r)
+	return r
+}
+
+// Len returns number of session data
+func (s *Session) Len() int {
+	n := 0
+	for k := range s.data {
+		if !isReservedKey(k) {
+			n++
+		}
+	}
+	return n
+}
+
+// Clear deletes all data and elevated state from session but keeps session id and flash
+func (s *Session) Clear() {
+	for k := range s.data {
+		if !isReservedKey(k) || k == keyOrderKey || k == elevatedKey || strings.HasPrefix(k, ttlPrefix) {
+			s.changed = true
+			delete(s.data, k)
+			s.checkLateWrite(k)
+		}
 	}
 }
 
@@ -126,10 +251,16 @@ func (s *Session) Pop(key string) interface{} {
 		return nil
 	}
 
+	expired := s.dataExpired(key)
 	r, ok := s.data[key]
 	if ok {
 		s.changed = true
 		delete(s.data, key)
+		delete(s.data, ttlPrefix+key)
+		s.checkLateWrite(key)
+	}
+	if expired {
+		return nil
 	}
 	return r
 }
@@ -170,6 +301,87 @@ func (s *Session) PopBool(key string) bool {
 	return r
 }
 
+// Inc increments numeric value in session by delta then returns the result,
+// value is converted to int64, non-numeric or not exists value is treated as 0,
+// expiration set by SetTTL is kept
+func (s *Session) Inc(key string, delta int64) int64 {
+	r := toInt64(s.Get(key)) + delta
+	ttl, hasTTL := s.data[ttlPrefix+key]
+	if s.set(key, r) && hasTTL && toInt64(ttl) > time.Now().UnixNano() {
+		s.data[ttlPrefix+key] = ttl
+	}
+	return r
+}
+
+// Dec decrements numeric value in session by delta then returns the result, see Inc
+func (s *Session) Dec(key string, delta int64) int64 {
+	return s.Inc(key, -delta)
+}
+
+// toStrings converts string list decoded by any store coder to []string
+func toStrings(v interface{}) []string {
+	switch v := v.(type) {
+	case []string:
+		return v
+	case []interface{}:
+		// decoded by coder that does not preserve type, e.g. json
+		r := make([]string, 0, len(v))
+		for _, x := range v {
+			if x, ok := x.(string); ok {
+				r = append(r, x)
+			}
+		}
+		return r
+	}
+	return nil
+}
+
+// toInt64 converts numeric value to int64, returns 0 if value is not numeric
+func toInt64(v interface{}) int64 {
+	n, _ := parseInt64(v)
+	return n
+}
+
+// parseInt64 converts number decoded by any store coder to int64
+func parseInt64(v interface{}) (int64, bool) {
+	switch v := v.(type) {
+	case int:
+		return int64(v), true
+	case int8:
+		return int64(v), true
+	case int16:
+		return int64(v), true
+	case int32:
+		return int64(v), true
+	case int64:
+		return v, true
+	case uint:
+		return int64(v), true
+	case uint8:
+		return int64(v), true
+	case uint16:
+		return int64(v), true
+	case uint32:
+		return int64(v), true
+	case uint64:
+		return int64(v), true
+	case float32:
+		return int64(v), true
+	case float64:
+		return int64(v), true
+	case json.Number:
+		n, err := v.Int64()
+		return n, err == nil
+	}
+	return 0, false
+}
+
+// Touch marks session to resave and rewrite cookie even if data not changed,
+// to extend session expiration like rolling session
+func (s *Session) Touch() {
+	s.touched = true
+}
+
 // IsNew checks is new session
 func (s *Session) IsNew() bool {
 	return s.isNew
@@ -185,12 +397,13 @@ func (s *Session) Flash() *Flash {
 	if b, ok := s.Get(flashKey).([]byte); ok {
 		s.flash.decode(b)
 	}
+	s.flash.onWrite = s.lateWrite
 	return s.flash
 }
 
 // Hijacked checks is session was hijacked
 func (s *Session) Hijacked() bool {
-	if t, ok := s.Get(destroyedKey).(int64); ok {
+	if t, ok := parseInt64(s.Get(destroyedKey)); ok {
 		if t < time.Now().UnixNano()-int64(HijackedTime) {
 			return true
 		}
@@ -198,10 +411,68 @@ func (s *Session) Hijacked() bool {
 	return false
 }
 
+// Elevate marks session as elevated for duration d, e.g. after user re-authenticated,
+// elevated state expires independently of session, d <= 0 removes elevated state
+func (s *Session) Elevate(d time.Duration) {
+	if d <= 0 {
+		s.Del(elevatedKey)
+		return
+	}
+	s.Set(elevatedKey, time.Now().Add(d).UnixNano())
+}
+
+// IsElevated checks is session elevated and not expired
+func (s *Session) IsElevated() bool {
+	return toInt64(s.Get(elevatedKey)) > time.Now().UnixNano()
+}
+
+// dataExpired checks is data set by SetTTL expired
+func (s *Session) dataExpired(key string) bool {
+	t, ok := s.data[ttlPrefix+key]
+	return ok && toInt64(t) <= time.Now().UnixNano()
+}
+
+// removeExpired removes expired data set by SetTTL
+func (s *Session) removeExpired() {
+	now := time.Now().UnixNano()
+	for k, v := range s.data {
+		if !strings.HasPrefix(k, ttlPrefix) {
+			continue
+		}
+		key := k[len(ttlPrefix):]
+		if toInt64(v) > now {
+			if _, ok := s.data[key]; ok {
+				continue
+			}
+		}
+		s.changed = true
+		delete(s.data, key)
+		delete(s.data, k)
+	}
+}
+
 // with scopedManager
 
-// Regenerate regenerates session id
-// use when change user access level to prevent session fixation
+// checkLateWrite reports session changed after middleware saved session,
+// the change will not be saved
+func (s *Session) checkLateWrite(key string) {
+	if isReservedKey(key) {
+		return
+	}
+	s.lateWrite(key)
+}
+
+// lateWrite reports key changed, if middleware already saved session
+func (s *Session) lateWrite(key string) {
+	if s.m == nil || !s.m.wroteHeader || s.m.failed || s.m.config.OnLateWrite == nil {
+		return
+	}
+	s.m.config.OnLateWrite(s, key)
+}
+
+// Regenerate regenerates session id and keeps session data,
+// use when change user access level to prevent session fixation,
+// use Renew to also discard session data
 //
 // Can use only with middleware
 func (s *Session) Regenerate() error {
@@ -211,7 +482,19 @@ func (s *Session) Regenerate() error {
 	return s.m.Regenerate(s)
 }
 
-// Renew clear all data in current session and regenerate session id
+// Rotate is the alias of Regenerate,
+// rotates session id and keeps session data
+//
+// Can use only with middleware
+func (s *Session) Rotate() error {
+	if s.m == nil {
+		return ErrNotPassMiddleware
+	}
+	return s.m.Rotate(s)
+}
+
+// Renew clears all data in current session and regenerates session id,
+// e.g. on login to start with clean session
 //
 // Can use only with middleware
 func (s *Session) Renew() error {
@@ -221,7 +504,30 @@ func (s *Session) Renew() error {
 	return s.m.Renew(s)
 }
 
-// Destroy destroys session from store
+// SaveNow saves session to store and sets cookie to response header immediately,
+// use before hijack connection (e.g. websocket upgrade) because net/http will not write
+// the response header, session data can still be read after hijacked,
+// and will not be saved again by middleware unless changed after SaveNow
+//
+// Can use only with middleware
+func (s *Session) SaveNow(ctx context.Context) error {
+	if s.m == nil {
+		return ErrNotPassMiddleware
+	}
+	return s.m.SaveNow(ctx, s)
+}
+
+// saved marks session as saved
+func (s *Session) saved() {
+	s.changed = false
+	s.touched = false
+	if s.flash != nil {
+		s.flash.changed = false
+	}
+}
+
+// Destroy destroys session from store,
+// data set after destroy will be saved to new session
 //
 // Can use only with middleware
 func (s *Session) Destroy() error {
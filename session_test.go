@@ -1,20 +1,24 @@
 package session_test
 
 import (
+	"context"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 
 	"github.com/moonrhythm/session"
+	"github.com/moonrhythm/session/store"
+	"github.com/moonrhythm/session/store/mock"
 )
 
 func TestSessionRenew(t *testing.T) {
 	t.Parallel()
 
 	h := session.Middleware(session.Config{
-		Store: &mockStore{},
+		Store: &mock.Store{},
 	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		s, _ := session.Get(r.Context(), sessName)
 		s.Set("a", 1)
@@ -27,3 +31,225 @@ func TestSessionRenew(t *testing.T) {
 	r := httptest.NewRequest(http.MethodGet, "/", nil)
 	h.ServeHTTP(w, r)
 }
+
+func TestSessionRegenerate(t *testing.T) {
+	t.Parallel()
+
+	h := session.Middleware(session.Config{
+		Store: &mock.Store{},
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s, _ := session.Get(r.Context(), sessName)
+		s.Set("a", 1)
+		id := s.ID()
+		assert.NoError(t, s.Regenerate())
+		assert.NotEqual(t, id, s.ID())
+		assert.Equal(t, 1, s.GetInt("a"), "expected data kept")
+
+		id = s.ID()
+		assert.NoError(t, s.Rotate())
+		assert.NotEqual(t, id, s.ID())
+		assert.Equal(t, 1, s.GetInt("a"), "expected data kept")
+
+		id = s.ID()
+		assert.NoError(t, s.Renew())
+		assert.NotEqual(t, id, s.ID())
+		assert.Empty(t, s.Keys(), "expected data discarded")
+	}))
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	h.ServeHTTP(w, r)
+}
+
+func TestSessionGetDefault(t *testing.T) {
+	t.Parallel()
+
+	s := session.Session{}
+	assert.Equal(t, 1, s.GetDefault("a", 1))
+	assert.False(t, s.Changed())
+
+	s.Set("a", 2)
+	assert.Equal(t, 2, s.GetDefault("a", 1))
+}
+
+func TestSessionGetOrSet(t *testing.T) {
+	t.Parallel()
+
+	s := session.Session{}
+	called := 0
+	fn := func() interface{} {
+		called++
+		return "token"
+	}
+
+	assert.Equal(t, "token", s.GetOrSet("csrf", fn))
+	assert.True(t, s.Changed())
+	assert.Equal(t, "token", s.GetString("csrf"))
+
+	assert.Equal(t, "token", s.GetOrSet("csrf", fn))
+	assert.Equal(t, 1, called)
+}
+
+func TestSessionKeysLenClear(t *testing.T) {
+	t.Parallel()
+
+	s := session.Session{}
+	assert.Empty(t, s.Keys())
+	assert.Equal(t, 0, s.Len())
+
+	s.Set("b", 1)
+	s.Set("a", 2)
+	s.Flash().Set("msg", "hi")
+	assert.Equal(t, []string{"a", "b"}, s.Keys())
+	assert.Equal(t, 2, s.Len())
+
+	s.Clear()
+	assert.Empty(t, s.Keys())
+	assert.Equal(t, 0, s.Len())
+	assert.Nil(t, s.Get("a"))
+	assert.Equal(t, "hi", s.Flash().Get("msg"), "expected flash kept")
+}
+
+func TestSessionElevate(t *testing.T) {
+	t.Parallel()
+
+	s := session.Session{}
+	assert.False(t, s.IsElevated())
+
+	s.Elevate(time.Minute)
+	assert.True(t, s.IsElevated())
+	assert.Empty(t, s.Keys(), "expected elevated state is internal data")
+
+	s.Elevate(time.Nanosecond)
+	time.Sleep(time.Millisecond)
+	assert.False(t, s.IsElevated(), "expected elevated state expired")
+
+	s.Elevate(time.Minute)
+	s.Elevate(0)
+	assert.False(t, s.IsElevated())
+
+	s.Elevate(time.Minute)
+	s.Clear()
+	assert.False(t, s.IsElevated(), "expected elevated state cleared")
+}
+
+func TestSessionElevateCoder(t *testing.T) {
+	t.Parallel()
+
+	// cbor decodes positive integer as uint64, json as float64
+	for _, v := range []interface{}{
+		uint64(time.Now().Add(time.Minute).UnixNano()),
+		float64(time.Now().Add(time.Minute).UnixNano()),
+	} {
+		v := v
+		m := session.New(session.Config{
+			Store: &mock.Store{
+				GetFunc: func(ctx context.Context, key string) (session.Data, error) {
+					return session.Data{"_session/elevated": v}, nil
+				},
+			},
+		})
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Set("Cookie", sessName+"=test")
+		s, _ := m.Get(r, sessName)
+		assert.True(t, s.IsElevated(), "%T", v)
+	}
+}
+
+func TestSessionSetTTL(t *testing.T) {
+	t.Parallel()
+
+	m := session.New(session.Config{Store: new(store.Memory)})
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	s, _ := m.Get(r, sessName)
+	s.Set("user", "u1")
+	s.SetTTL("code", "123", 10*time.Millisecond)
+	s.SetTTL("a", 1, 10*time.Millisecond)
+	s.Set("a", 2)
+	s.SetTTL("b", 3, time.Minute)
+	s.SetTTL("attempts", 1, 10*time.Millisecond)
+	s.Inc("attempts", 1)
+	assert.Equal(t, []string{"a", "attempts", "b", "code", "user"}, s.Keys(), "expected ttl is internal data")
+	assert.Equal(t, "123", s.Get("code"))
+
+	w := httptest.NewRecorder()
+	if !assert.NoError(t, m.Save(r.Context(), w, s)) {
+		return
+	}
+	time.Sleep(20 * time.Millisecond)
+
+	r = httptest.NewRequest(http.MethodGet, "/", nil)
+	for _, c := range w.Result().Cookies() {
+		r.AddCookie(c)
+	}
+	s, _ = m.Get(r, sessName)
+	assert.False(t, s.IsNew())
+	assert.Nil(t, s.Get("code"), "expected expired data removed")
+	assert.Nil(t, s.Get("attempts"), "expected inc keeps ttl")
+	assert.Equal(t, 2, s.Get("a"), "expected set without ttl removes ttl")
+	assert.Equal(t, 3, s.Get("b"))
+	assert.Equal(t, "u1", s.Get("user"))
+	assert.True(t, s.Changed(), "expected session changed after removed expired data")
+}
+
+func TestSessionSetTTLRead(t *testing.T) {
+	t.Parallel()
+
+	s := session.Session{}
+	s.SetTTL("code", "123", 10*time.Millisecond)
+	s.SetTTL("attempts", int64(1), 10*time.Millisecond)
+	s.SetTTL("token", "t", 10*time.Millisecond)
+	time.Sleep(20 * time.Millisecond)
+
+	assert.Nil(t, s.Get("code"), "expected expired data not returned before reload")
+	assert.Equal(t, "fallback", s.GetDefault("code", "fallback"))
+	assert.Equal(t, "new", s.GetOrSet("code", func() interface{} { return "new" }))
+	assert.Equal(t, "new", s.Get("code"), "expected set without ttl removes ttl")
+
+	assert.Equal(t, int64(1), s.Inc("attempts", 1), "expected expired value treated as 0")
+	assert.Equal(t, int64(1), s.Get("attempts"), "expected expired ttl not kept")
+	assert.Nil(t, s.Pop("token"))
+	assert.NotContains(t, s.Keys(), "token", "expected expired data removed by pop")
+}
+
+func TestSessionSetTTLCoder(t *testing.T) {
+	t.Parallel()
+
+	// cbor decodes positive integer as uint64
+	m := session.New(session.Config{
+		Store: &mock.Store{
+			GetFunc: func(ctx context.Context, key string) (session.Data, error) {
+				return session.Data{
+					"code":              "123",
+					"_session/ttl/code": uint64(time.Now().Add(time.Minute).UnixNano()),
+					"old":               "1",
+					"_session/ttl/old":  uint64(time.Now().Add(-time.Minute).UnixNano()),
+				}, nil
+			},
+		},
+	})
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("Cookie", sessName+"=test")
+	s, _ := m.Get(r, sessName)
+	assert.Equal(t, "123", s.Get("code"), "expected unexpired data kept")
+	assert.Nil(t, s.Get("old"))
+	assert.Equal(t, []string{"code"}, s.Keys())
+}
+
+func TestSessionIncDec(t *testing.T) {
+	t.Parallel()
+
+	s := session.Session{}
+	assert.Equal(t, int64(1), s.Inc("cnt", 1))
+	assert.Equal(t, int64(3), s.Inc("cnt", 2))
+	assert.Equal(t, int64(2), s.Dec("cnt", 1))
+	assert.Equal(t, int64(2), s.Get("cnt"), "expected value stored as int64")
+
+	s.Set("int", 5)
+	assert.Equal(t, int64(6), s.Inc("int", 1))
+	s.Set("float", float64(5))
+	assert.Equal(t, int64(4), s.Dec("float", 1), "expected float converted, e.g. decoded from json")
+	s.Set("str", "a")
+	assert.Equal(t, int64(1), s.Inc("str", 1), "expected non-numeric value treated as 0")
+}
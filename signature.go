@@ -3,6 +3,7 @@ package session
 import (
 	"crypto/hmac"
 	"crypto/sha1"
+	"crypto/sha256"
 	"encoding/base64"
 )
 
@@ -21,3 +22,41 @@ func verify(value, digest string, keys [][]byte) bool {
 	}
 	return false
 }
+
+// HMACHashFunc returns Config.HashFunc that hashes session id using HMAC-SHA256
+func HMACHashFunc(key []byte) func(id string) string {
+	return func(id string) string {
+		h := hmac.New(sha256.New, key)
+		h.Write([]byte(id))
+		return base64.RawURLEncoding.EncodeToString(h.Sum(nil))
+	}
+}
+
+// payloadMACSize is the size of payload mac
+const payloadMACSize = sha256.Size
+
+// signPayload appends HMAC-SHA256 of store key and payload to payload
+func signPayload(key string, payload []byte, secret []byte) []byte {
+	h := hmac.New(sha256.New, secret)
+	h.Write([]byte(key))
+	h.Write(payload)
+	return h.Sum(payload)
+}
+
+// verifyPayload verifies and strips mac from signed payload
+func verifyPayload(key string, signed []byte, secrets [][]byte) ([]byte, bool) {
+	if len(signed) < payloadMACSize {
+		return nil, false
+	}
+	payload, mac := signed[:len(signed)-payloadMACSize], signed[len(signed)-payloadMACSize:]
+
+	for _, secret := range secrets {
+		h := hmac.New(sha256.New, secret)
+		h.Write([]byte(key))
+		h.Write(payload)
+		if hmac.Equal(mac, h.Sum(nil)) {
+			return payload, true
+		}
+	}
+	return nil, false
+}
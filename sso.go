@@ -0,0 +1,94 @@
+package session
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"net/http"
+	"strings"
+)
+
+// SSO defaults
+const (
+	DefaultSSOName      = "sso"
+	DefaultSSONamespace = "sso/"
+)
+
+// SSO is the config for session shared across subdomains of the same parent domain,
+// e.g. app.example.com and admin.example.com share one login session.
+//
+// All applications must use the same Domain, Secret, Store, Namespace and Name
+type SSO struct {
+	// Domain is the parent domain, e.g. example.com
+	Domain string
+
+	// Secret is the shared secret to hash and sign session id,
+	// separate hash and sign keys are derived from the secret,
+	// session id not signed by the secret is rejected without store lookup
+	Secret []byte
+
+	// Store is the store shared by all applications
+	Store Store
+
+	// Namespace is the store key prefix for sso session,
+	// to separate from applications' own sessions in the same store,
+	// default is DefaultSSONamespace
+	Namespace string
+
+	// Name is the session and middleware name, default is DefaultSSOName
+	Name string
+}
+
+// Config returns session config for sso, the config can be customized
+// (e.g. MaxAge, IdleTimeout) before pass to New, but cookie and session id settings
+// must not be changed
+func (sso SSO) Config() Config {
+	domain := strings.TrimPrefix(sso.Domain, ".")
+	if domain == "" {
+		panic("session: sso requires domain")
+	}
+	if len(sso.Secret) == 0 {
+		panic("session: sso requires secret")
+	}
+
+	namespace := sso.Namespace
+	if namespace == "" {
+		namespace = DefaultSSONamespace
+	}
+	name := sso.Name
+	if name == "" {
+		name = DefaultSSOName
+	}
+
+	hashID := hashWithSecret(deriveKey(sso.Secret, "hash"))
+	return Config{
+		Store:    sso.Store,
+		Name:     name,
+		Keys:     [][]byte{deriveKey(sso.Secret, "sign")},
+		Domain:   domain,
+		HTTPOnly: true,
+		Path:     "/",
+		Secure:   ForceSecure,
+		SameSite: http.SameSiteLaxMode,
+		HashFunc: func(id string) string {
+			return namespace + hashID(id)
+		},
+	}
+}
+
+// deriveKey derives key for purpose from secret,
+// so the same secret is not used for different purposes
+func deriveKey(secret []byte, purpose string) []byte {
+	h := hmac.New(sha256.New, secret)
+	h.Write([]byte(purpose))
+	return h.Sum(nil)
+}
+
+// NewSSO creates new manager for session shared across subdomains
+func NewSSO(sso SSO) *Manager {
+	return New(sso.Config())
+}
+
+// SSOMiddleware is the NewSSO(sso).Middleware()
+func SSOMiddleware(sso SSO) func(http.Handler) http.Handler {
+	return NewSSO(sso).Middleware()
+}
@@ -0,0 +1,80 @@
+package session_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/moonrhythm/session"
+	"github.com/moonrhythm/session/store"
+	"github.com/moonrhythm/session/store/mock"
+)
+
+func TestSSO(t *testing.T) {
+	t.Parallel()
+
+	var keys []string
+	st := new(store.Memory)
+	shared := &mock.Store{
+		GetFunc: st.Get,
+		SetFunc: func(ctx context.Context, key string, value session.Data, opt session.StoreOption) error {
+			keys = append(keys, key)
+			return st.Set(ctx, key, value, opt)
+		},
+	}
+	sso := session.SSO{
+		Domain: ".example.com",
+		Secret: []byte("shared secret"),
+		Store:  shared,
+	}
+
+	app := session.SSOMiddleware(sso)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		session.MustGet(r.Context(), session.DefaultSSOName).Set("user", "u1")
+	}))
+	admin := session.SSOMiddleware(sso)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(session.MustGet(r.Context(), session.DefaultSSOName).GetString("user")))
+	}))
+
+	w := httptest.NewRecorder()
+	app.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "https://app.example.com/", nil))
+	cs := w.Result().Cookies()
+	if !assert.Len(t, cs, 1) {
+		return
+	}
+	c := cs[0]
+	assert.Equal(t, "example.com", c.Domain)
+	assert.Equal(t, "/", c.Path)
+	assert.True(t, c.Secure)
+	assert.True(t, c.HttpOnly)
+	assert.Equal(t, http.SameSiteLaxMode, c.SameSite)
+	if assert.Len(t, keys, 1) {
+		assert.True(t, strings.HasPrefix(keys[0], session.DefaultSSONamespace), "expected store key in sso namespace")
+	}
+
+	r := httptest.NewRequest(http.MethodGet, "https://admin.example.com/", nil)
+	r.AddCookie(c)
+	w = httptest.NewRecorder()
+	admin.ServeHTTP(w, r)
+	assert.Equal(t, "u1", w.Body.String(), "expected session shared across subdomains")
+
+	other := sso
+	other.Secret = []byte("other secret")
+	h := session.SSOMiddleware(other)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.True(t, session.MustGet(r.Context(), session.DefaultSSOName).IsNew(), "expected session from different secret rejected")
+	}))
+	r = httptest.NewRequest(http.MethodGet, "https://evil.example.com/", nil)
+	r.AddCookie(c)
+	h.ServeHTTP(httptest.NewRecorder(), r)
+
+	cfg := sso.Config()
+	if assert.Len(t, cfg.Keys, 1) {
+		assert.NotEqual(t, sso.Secret, cfg.Keys[0], "expected sign key derived from secret")
+	}
+
+	assert.Panics(t, func() { session.NewSSO(session.SSO{Secret: sso.Secret, Store: shared}) })
+	assert.Panics(t, func() { session.NewSSO(session.SSO{Domain: sso.Domain, Store: shared}) })
+}
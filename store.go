@@ -13,6 +13,13 @@ var (
 	// ErrNotFound is the error when session not found
 	// store must return ErrNotFound if session data not exists
 	ErrNotFound = errors.New("session: not found")
+
+	// ErrNotSupported is the error when store does not support the operation
+	ErrNotSupported = errors.New("session: operation not supported by store")
+
+	// ErrConflict is the error when session data was changed by other request
+	// after the session was loaded
+	ErrConflict = errors.New("session: conflict")
 )
 
 // Store interface
@@ -22,6 +29,87 @@ type Store interface {
 	Del(ctx context.Context, key string) error
 }
 
+// PrefixDeleter is the optional interface for store
+// that can delete all sessions which key has the given prefix
+type PrefixDeleter interface {
+	DelPrefix(ctx context.Context, prefix string) error
+}
+
+// Toucher is the optional interface for store
+// that can renew session ttl without rewrite session data,
+// Touch must return ErrNotSupported if wrapped store can not touch
+type Toucher interface {
+	Touch(ctx context.Context, key string, ttl time.Duration) error
+}
+
+// BatchStore is the optional interface for store
+// that can get and set multiple sessions in single round-trip,
+// GetMulti returns only found sessions,
+// BatchStore must return ErrNotSupported if wrapped store can not batch
+type BatchStore interface {
+	GetMulti(ctx context.Context, keys []string) (map[string]Data, error)
+	SetMulti(ctx context.Context, values map[string]Data, opt StoreOption) error
+}
+
+// IndexedStore is the optional interface for store
+// that maintains index from user id to store keys of user's sessions,
+// store without IndexedStore keeps the index as data in store
+type IndexedStore interface {
+	// AddUserSession adds store key to user's index as the newest session
+	AddUserSession(ctx context.Context, userID string, key string) error
+
+	// UserSessions returns store keys in user's index, oldest first
+	UserSessions(ctx context.Context, userID string) ([]string, error)
+
+	// DelUserSessions removes store keys from user's index
+	DelUserSessions(ctx context.Context, userID string, keys []string) error
+}
+
+// CASStore is the optional interface for store
+// that detects conflicting writes by data version (Data.Version) for Config.DetectConflict,
+// CompareAndSet sets session data only if version of stored data equals to version,
+// session not exists in store has version 0,
+// CompareAndSet must return ErrConflict if version does not match,
+// and ErrNotSupported if wrapped store can not compare,
+// wrapper store must implement Unwrap to report the wrapped store
+type CASStore interface {
+	CompareAndSet(ctx context.Context, key string, version int64, value Data, opt StoreOption) error
+}
+
+// supportCAS checks is all stores in the chain implement CASStore
+func supportCAS(st Store) bool {
+	for {
+		if _, ok := st.(CASStore); !ok {
+			return false
+		}
+		w, ok := st.(interface{ Unwrap() Store })
+		if !ok {
+			return true
+		}
+		st = w.Unwrap()
+	}
+}
+
+// CookieStore is the optional interface for store
+// that keeps session data in cookie instead of server,
+// manager sets the encoded session data as cookie value
+// and does not call Get and Set
+type CookieStore interface {
+	EncodeCookie(name string, data Data, opt StoreOption) (string, error)
+	DecodeCookie(name string, value string) (Data, error)
+}
+
+// RevocationList keeps revoked session ids,
+// for force logout session which data is kept in cookie
+type RevocationList interface {
+	// Revoke adds session id to list, ttl is the remaining lifetime of the session,
+	// zero ttl means no expiration
+	Revoke(ctx context.Context, id string, ttl time.Duration) error
+
+	// Revoked checks is session id in list
+	Revoked(ctx context.Context, id string) (bool, error)
+}
+
 // StoreOption type
 type StoreOption struct {
 	TTL time.Duration
@@ -0,0 +1,262 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"hash/fnv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/moonrhythm/session"
+)
+
+// Async writes session data to wrapped store in background workers,
+// removes store latency from response, reads return pending writes
+// so the next request sees its own writes
+//
+// Background writes do not use request context,
+// call Flush before shutdown to wait pending writes, then Close to stop workers
+type Async struct {
+	Store session.Store
+
+	// Workers is the number of background workers, default is 1,
+	// writes to the same key are done in order by the same worker
+	Workers int
+
+	// QueueSize is the max pending writes per worker, default is 1000,
+	// Set and Del block when queue is full
+	QueueSize int
+
+	// OnError is called when background write failed
+	OnError func(key string, err error)
+
+	once    sync.Once
+	queues  []chan *asyncJob
+	qm      sync.RWMutex // guards send to queues and close
+	closed  bool
+	m       sync.Mutex
+	done    *sync.Cond           // signaled when pending write done
+	count   int                  // number of pending writes
+	idle    chan struct{}        // closed when no pending write
+	pending map[string]*asyncJob // latest pending write of each key
+}
+
+var errAsyncClosed = errors.New("store/async: closed")
+
+type asyncJob struct {
+	key   string
+	value session.Data // nil for delete
+	opt   session.StoreOption
+}
+
+// WithAsync wraps store with background writes
+func WithAsync(inner session.Store, workers, queueSize int) *Async {
+	return &Async{Store: inner, Workers: workers, QueueSize: queueSize}
+}
+
+func (s *Async) init() {
+	s.once.Do(func() {
+		workers := s.Workers
+		if workers <= 0 {
+			workers = 1
+		}
+		size := s.QueueSize
+		if size <= 0 {
+			size = 1000
+		}
+
+		s.done = sync.NewCond(&s.m)
+		s.idle = make(chan struct{})
+		close(s.idle)
+		s.pending = make(map[string]*asyncJob)
+		s.queues = make([]chan *asyncJob, workers)
+		for i := range s.queues {
+			s.queues[i] = make(chan *asyncJob, size)
+			go s.worker(s.queues[i])
+		}
+	})
+}
+
+func (s *Async) worker(queue <-chan *asyncJob) {
+	for job := range queue {
+		s.do(job)
+	}
+}
+
+func (s *Async) do(job *asyncJob) {
+	var err error
+	if job.value == nil {
+		err = s.Store.Del(context.Background(), job.key)
+	} else {
+		err = s.Store.Set(context.Background(), job.key, job.value, job.opt)
+	}
+
+	s.m.Lock()
+	if s.pending[job.key] == job {
+		delete(s.pending, job.key)
+	}
+	s.count--
+	if s.count == 0 {
+		close(s.idle)
+	}
+	s.done.Broadcast()
+	s.m.Unlock()
+
+	if err != nil && s.OnError != nil {
+		s.OnError(job.key, err)
+	}
+}
+
+func (s *Async) enqueue(job *asyncJob) error {
+	s.init()
+
+	s.qm.RLock()
+	defer s.qm.RUnlock()
+	if s.closed {
+		return errAsyncClosed
+	}
+
+	s.m.Lock()
+	s.pending[job.key] = job
+	if s.count == 0 {
+		s.idle = make(chan struct{})
+	}
+	s.count++
+	s.m.Unlock()
+
+	// writes to the same key go to the same worker to keep order
+	h := fnv.New32a()
+	h.Write([]byte(job.key))
+	s.queues[h.Sum32()%uint32(len(s.queues))] <- job
+	return nil
+}
+
+// Get gets session data from pending writes, or wrapped store
+func (s *Async) Get(ctx context.Context, key string) (session.Data, error) {
+	s.init()
+
+	s.m.Lock()
+	job, ok := s.pending[key]
+	s.m.Unlock()
+	if ok {
+		if job.value == nil {
+			return nil, session.ErrNotFound
+		}
+		return job.value.Clone(), nil
+	}
+	return s.Store.Get(ctx, key)
+}
+
+// Set queues session data to write to wrapped store
+func (s *Async) Set(ctx context.Context, key string, value session.Data, opt session.StoreOption) error {
+	if value == nil {
+		value = make(session.Data)
+	}
+	return s.enqueue(&asyncJob{key: key, value: value.Clone(), opt: opt})
+}
+
+// CompareAndSet waits pending write of the key,
+// then sets session data to wrapped store if version matches without queue
+func (s *Async) CompareAndSet(ctx context.Context, key string, version int64, value session.Data, opt session.StoreOption) error {
+	st, ok := s.Store.(session.CASStore)
+	if !ok {
+		return session.ErrNotSupported
+	}
+	s.init()
+
+	s.m.Lock()
+	for s.pending[key] != nil {
+		s.done.Wait()
+	}
+	s.m.Unlock()
+	return st.CompareAndSet(ctx, key, version, value, opt)
+}
+
+// Unwrap returns wrapped store
+func (s *Async) Unwrap() session.Store {
+	return s.Store
+}
+
+// Del queues session data to delete from wrapped store
+func (s *Async) Del(ctx context.Context, key string) error {
+	return s.enqueue(&asyncJob{key: key})
+}
+
+// DelPrefix waits pending writes of keys with the prefix,
+// then deletes session data by key prefix from wrapped store
+func (s *Async) DelPrefix(ctx context.Context, prefix string) error {
+	st, ok := s.Store.(session.PrefixDeleter)
+	if !ok {
+		return session.ErrNotSupported
+	}
+	s.init()
+
+	s.m.Lock()
+	for s.hasPendingPrefix(prefix) {
+		s.done.Wait()
+	}
+	s.m.Unlock()
+	return st.DelPrefix(ctx, prefix)
+}
+
+func (s *Async) hasPendingPrefix(prefix string) bool {
+	for k := range s.pending {
+		if strings.HasPrefix(k, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// Touch renews session data ttl in wrapped store
+func (s *Async) Touch(ctx context.Context, key string, ttl time.Duration) error {
+	st, ok := s.Store.(session.Toucher)
+	if !ok {
+		return session.ErrNotSupported
+	}
+	s.init()
+
+	s.m.Lock()
+	_, ok = s.pending[key]
+	s.m.Unlock()
+	if ok {
+		// pending write will set new ttl
+		return nil
+	}
+	return st.Touch(ctx, key, ttl)
+}
+
+// Flush waits until all pending writes are done, or ctx is done
+func (s *Async) Flush(ctx context.Context) error {
+	s.init()
+
+	s.m.Lock()
+	idle := s.idle
+	s.m.Unlock()
+
+	select {
+	case <-idle:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Close stops background workers after queued writes are done,
+// Set and Del after Close return error,
+// call Flush to wait queued writes
+func (s *Async) Close() error {
+	s.init()
+
+	s.qm.Lock()
+	defer s.qm.Unlock()
+	if s.closed {
+		return nil
+	}
+	s.closed = true
+	for _, q := range s.queues {
+		close(q)
+	}
+	return nil
+}
@@ -0,0 +1,147 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/moonrhythm/session"
+	"github.com/moonrhythm/session/store/mock"
+)
+
+func TestAsync(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	release := make(chan struct{})
+	inner := new(Memory)
+	s := WithAsync(&mock.Store{
+		GetFunc: inner.Get,
+		SetFunc: func(ctx context.Context, key string, value session.Data, opt session.StoreOption) error {
+			<-release
+			return inner.Set(ctx, key, value, opt)
+		},
+		DelFunc: inner.Del,
+	}, 2, 10)
+
+	data := session.Data{"test": "123"}
+	assert.NoError(t, s.Set(ctx, "a", data, session.StoreOption{TTL: time.Second}))
+
+	_, err := inner.Get(ctx, "a")
+	assert.Equal(t, session.ErrNotFound, err, "expected write not done yet")
+
+	b, err := s.Get(ctx, "a")
+	assert.NoError(t, err, "expected read pending write")
+	assert.Equal(t, data, b)
+
+	assert.NoError(t, s.Del(ctx, "a"))
+	_, err = s.Get(ctx, "a")
+	assert.Equal(t, session.ErrNotFound, err, "expected read pending delete")
+
+	assert.NoError(t, s.Set(ctx, "b", data, session.StoreOption{TTL: time.Second}))
+
+	flushCtx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	assert.Error(t, s.Flush(flushCtx), "expected flush wait pending writes")
+
+	close(release)
+	assert.NoError(t, s.Flush(ctx))
+
+	_, err = inner.Get(ctx, "a")
+	assert.Equal(t, session.ErrNotFound, err, "expected writes done in order")
+	b, err = inner.Get(ctx, "b")
+	assert.NoError(t, err)
+	assert.Equal(t, data, b)
+}
+
+func TestAsyncOnError(t *testing.T) {
+	t.Parallel()
+
+	storeErr := errors.New("store down")
+
+	var (
+		mu     sync.Mutex
+		failed []string
+	)
+	s := &Async{
+		Store: &mock.Store{
+			SetFunc: func(ctx context.Context, key string, value session.Data, opt session.StoreOption) error {
+				return storeErr
+			},
+		},
+		OnError: func(key string, err error) {
+			mu.Lock()
+			failed = append(failed, key)
+			mu.Unlock()
+			assert.Equal(t, storeErr, err)
+		},
+	}
+
+	ctx := context.Background()
+	assert.NoError(t, s.Set(ctx, "a", session.Data{}, session.StoreOption{}))
+	assert.NoError(t, s.Flush(ctx))
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, []string{"a"}, failed)
+}
+
+func TestAsyncClose(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	inner := new(Memory)
+	s := WithAsync(inner, 2, 10)
+	assert.NoError(t, s.Set(ctx, "a", session.Data{"test": "123"}, session.StoreOption{}))
+	assert.NoError(t, s.Close())
+	assert.NoError(t, s.Flush(ctx))
+	assert.NoError(t, s.Close(), "expected close twice not panic")
+
+	_, err := inner.Get(ctx, "a")
+	assert.NoError(t, err, "expected queued write done after close")
+	assert.Error(t, s.Set(ctx, "b", session.Data{}, session.StoreOption{}), "expected write after close failed")
+	assert.Error(t, s.Del(ctx, "a"))
+}
+
+func TestAsyncDelPrefix(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	release := make(chan struct{})
+	inner := new(Memory)
+	s := WithAsync(&asyncPrefixStore{
+		Store: &mock.Store{
+			SetFunc: func(ctx context.Context, key string, value session.Data, opt session.StoreOption) error {
+				<-release
+				return inner.Set(ctx, key, value, opt)
+			},
+		},
+		PrefixDeleter: inner,
+	}, 1, 10)
+	assert.NoError(t, s.Set(ctx, "user1:a", session.Data{}, session.StoreOption{}))
+
+	done := make(chan error)
+	go func() {
+		done <- s.DelPrefix(ctx, "user1:")
+	}()
+	close(release)
+	assert.NoError(t, <-done)
+
+	_, err := inner.Get(ctx, "user1:a")
+	assert.Equal(t, session.ErrNotFound, err, "expected pending write deleted")
+
+	s = WithAsync(&mock.Store{}, 1, 10)
+	assert.Equal(t, session.ErrNotSupported, s.DelPrefix(ctx, "user1:"))
+}
+
+type asyncPrefixStore struct {
+	*mock.Store
+	session.PrefixDeleter
+}
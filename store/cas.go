@@ -0,0 +1,17 @@
+package store
+
+import (
+	"github.com/moonrhythm/session"
+)
+
+// versionKey is the key of session data version, see session.Data.Version
+const versionKey = "_session/version"
+
+// withVersion copies version of session data to encoded data,
+// to keep version readable by wrapped CASStore
+func withVersion(data session.Data, value session.Data) session.Data {
+	if v, ok := value[versionKey]; ok {
+		data[versionKey] = v
+	}
+	return data
+}
@@ -0,0 +1,46 @@
+package store
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/moonrhythm/session"
+)
+
+func TestWrapperCompareAndSet(t *testing.T) {
+	t.Parallel()
+
+	for name, wrap := range map[string]func(st session.Store) session.Store{
+		"Compression": func(st session.Store) session.Store { return WithCompression(st, Gzip{}) },
+		"Encryption": func(st session.Store) session.Store {
+			return WithEncryption(st, []byte("0123456789abcdef"))
+		},
+		"Logging": func(st session.Store) session.Store { return WithLogging(st, nil, 0) },
+		"Tiered":  func(st session.Store) session.Store { return &Tiered{Local: new(Memory), Remote: st} },
+		"Async":   func(st session.Store) session.Store { return WithAsync(st, 1, 0) },
+	} {
+		wrap := wrap
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			opt := session.StoreOption{TTL: time.Minute}
+
+			s := wrap(new(Memory)).(session.CASStore)
+			err := s.CompareAndSet(ctx, "a", 0, session.Data{"k": "1", "_session/version": int64(1)}, opt)
+			assert.NoError(t, err)
+			err = s.CompareAndSet(ctx, "a", 0, session.Data{"k": "2", "_session/version": int64(1)}, opt)
+			assert.Equal(t, session.ErrConflict, err, "expected version readable by wrapped store")
+			err = s.CompareAndSet(ctx, "a", 1, session.Data{"k": "3", "_session/version": int64(2)}, opt)
+			assert.NoError(t, err)
+
+			data, err := s.(session.Store).Get(ctx, "a")
+			assert.NoError(t, err)
+			assert.Equal(t, "3", data["k"])
+
+			err = wrap(&mockStore{}).(session.CASStore).CompareAndSet(ctx, "a", 0, session.Data{}, opt)
+			assert.Equal(t, session.ErrNotSupported, err)
+		})
+	}
+}
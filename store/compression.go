@@ -0,0 +1,245 @@
+package store
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"io/ioutil"
+	"sync"
+	"time"
+
+	"github.com/golang/snappy"
+	"github.com/klauspost/compress/zstd"
+
+	"github.com/moonrhythm/session"
+)
+
+// compressedKey is the key that holds compressed payload in wrapped store
+const compressedKey = "_session/compressed"
+
+// payload flags
+const (
+	flagRaw        byte = 0
+	flagCompressed byte = 1
+)
+
+// Codec compresses and decompresses data
+type Codec interface {
+	Compress(b []byte) ([]byte, error)
+	Decompress(b []byte) ([]byte, error)
+}
+
+// Compression compresses session data before save to wrapped store
+type Compression struct {
+	Store session.Store
+	Coder session.StoreCoder
+
+	// Codec is the compression codec, default is Gzip
+	Codec Codec
+
+	// MinSize is the minimum size of encoded session data to compress,
+	// smaller data is saved as is
+	MinSize int
+}
+
+// WithCompression wraps store with compression
+func WithCompression(inner session.Store, codec Codec) *Compression {
+	return &Compression{Store: inner, Codec: codec}
+}
+
+func (s *Compression) coder() session.StoreCoder {
+	if s.Coder == nil {
+		return session.DefaultStoreCoder
+	}
+	return s.Coder
+}
+
+func (s *Compression) codec() Codec {
+	if s.Codec == nil {
+		return Gzip{}
+	}
+	return s.Codec
+}
+
+// Get gets session data from wrapped store then decompress
+func (s *Compression) Get(ctx context.Context, key string) (session.Data, error) {
+	data, err := s.Store.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+
+	b, _ := data[compressedKey].([]byte)
+	if len(b) == 0 {
+		return nil, errors.New("store/compression: invalid payload")
+	}
+	switch b[0] {
+	case flagRaw:
+		b = b[1:]
+	case flagCompressed:
+		b, err = s.codec().Decompress(b[1:])
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, errors.New("store/compression: invalid payload")
+	}
+
+	var sessData session.Data
+	err = s.coder().NewDecoder(bytes.NewReader(b)).Decode(&sessData)
+	if err != nil {
+		return nil, err
+	}
+	return sessData, nil
+}
+
+func (s *Compression) encode(value session.Data) (session.Data, error) {
+	var buf bytes.Buffer
+	buf.WriteByte(flagRaw)
+	err := s.coder().NewEncoder(&buf).Encode(value)
+	if err != nil {
+		return nil, err
+	}
+
+	b := buf.Bytes()
+	if len(b)-1 >= s.MinSize {
+		c, err := s.codec().Compress(b[1:])
+		if err != nil {
+			return nil, err
+		}
+		// keep raw data if compression does not help
+		if len(c) < len(b)-1 {
+			b = append([]byte{flagCompressed}, c...)
+		}
+	}
+	return withVersion(session.Data{compressedKey: b}, value), nil
+}
+
+// Set compresses session data then save to wrapped store
+func (s *Compression) Set(ctx context.Context, key string, value session.Data, opt session.StoreOption) error {
+	data, err := s.encode(value)
+	if err != nil {
+		return err
+	}
+	return s.Store.Set(ctx, key, data, opt)
+}
+
+// CompareAndSet compresses session data then save to wrapped store if version matches
+func (s *Compression) CompareAndSet(ctx context.Context, key string, version int64, value session.Data, opt session.StoreOption) error {
+	st, ok := s.Store.(session.CASStore)
+	if !ok {
+		return session.ErrNotSupported
+	}
+	data, err := s.encode(value)
+	if err != nil {
+		return err
+	}
+	return st.CompareAndSet(ctx, key, version, data, opt)
+}
+
+// Del deletes session data from wrapped store
+func (s *Compression) Del(ctx context.Context, key string) error {
+	return s.Store.Del(ctx, key)
+}
+
+// DelPrefix deletes session data by key prefix from wrapped store
+func (s *Compression) DelPrefix(ctx context.Context, prefix string) error {
+	st, ok := s.Store.(session.PrefixDeleter)
+	if !ok {
+		return session.ErrNotSupported
+	}
+	return st.DelPrefix(ctx, prefix)
+}
+
+// Gzip is the gzip codec
+type Gzip struct {
+	// Level is the compression level, zero means default compression
+	Level int
+}
+
+// Compress compresses data using gzip
+func (c Gzip) Compress(b []byte) ([]byte, error) {
+	level := c.Level
+	if level == 0 {
+		level = gzip.DefaultCompression
+	}
+
+	var buf bytes.Buffer
+	w, err := gzip.NewWriterLevel(&buf, level)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = w.Write(b); err != nil {
+		return nil, err
+	}
+	if err = w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Decompress decompresses gzip data
+func (Gzip) Decompress(b []byte) ([]byte, error) {
+	r, err := gzip.NewReader(bytes.NewReader(b))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return ioutil.ReadAll(r)
+}
+
+// Snappy is the snappy codec
+type Snappy struct{}
+
+// Compress compresses data using snappy
+func (Snappy) Compress(b []byte) ([]byte, error) {
+	return snappy.Encode(nil, b), nil
+}
+
+// Decompress decompresses snappy data
+func (Snappy) Decompress(b []byte) ([]byte, error) {
+	return snappy.Decode(nil, b)
+}
+
+// Zstd is the zstd codec
+type Zstd struct{}
+
+var (
+	zstdOnce    sync.Once
+	zstdEncoder *zstd.Encoder
+	zstdDecoder *zstd.Decoder
+)
+
+func initZstd() {
+	zstdOnce.Do(func() {
+		// nil writer and reader can not fail
+		zstdEncoder, _ = zstd.NewWriter(nil)
+		zstdDecoder, _ = zstd.NewReader(nil)
+	})
+}
+
+// Compress compresses data using zstd
+func (Zstd) Compress(b []byte) ([]byte, error) {
+	initZstd()
+	return zstdEncoder.EncodeAll(b, nil), nil
+}
+
+// Decompress decompresses zstd data
+func (Zstd) Decompress(b []byte) ([]byte, error) {
+	initZstd()
+	return zstdDecoder.DecodeAll(b, nil)
+}
+
+// Unwrap returns wrapped store
+func (s *Compression) Unwrap() session.Store {
+	return s.Store
+}
+
+// Touch renews session data ttl in wrapped store
+func (s *Compression) Touch(ctx context.Context, key string, ttl time.Duration) error {
+	st, ok := s.Store.(session.Toucher)
+	if !ok {
+		return session.ErrNotSupported
+	}
+	return st.Touch(ctx, key, ttl)
+}
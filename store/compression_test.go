@@ -0,0 +1,54 @@
+package store
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/moonrhythm/session"
+)
+
+func TestCompression(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	codecs := map[string]Codec{
+		"Gzip":   Gzip{},
+		"Snappy": Snappy{},
+		"Zstd":   Zstd{},
+	}
+
+	for name, codec := range codecs {
+		codec := codec
+		t.Run(name, func(t *testing.T) {
+			inner := new(Memory)
+			s := WithCompression(inner, codec)
+			s.MinSize = 100
+
+			small := session.Data{"test": "123"}
+			large := session.Data{"test": strings.Repeat("a", 1000)}
+
+			assert.NoError(t, s.Set(ctx, "small", small, session.StoreOption{}))
+			assert.NoError(t, s.Set(ctx, "large", large, session.StoreOption{}))
+
+			raw, _ := inner.Get(ctx, "small")
+			assert.Equal(t, flagRaw, raw[compressedKey].([]byte)[0], "expected small data not compressed")
+
+			raw, _ = inner.Get(ctx, "large")
+			b := raw[compressedKey].([]byte)
+			assert.Equal(t, flagCompressed, b[0])
+			assert.True(t, len(b) < 1000)
+
+			d, err := s.Get(ctx, "small")
+			assert.NoError(t, err)
+			assert.Equal(t, small, d)
+
+			d, err = s.Get(ctx, "large")
+			assert.NoError(t, err)
+			assert.Equal(t, large, d)
+		})
+	}
+}
@@ -0,0 +1,153 @@
+// Package cookie provides store that keeps session data in cookie
+package cookie
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/moonrhythm/session"
+	"github.com/moonrhythm/session/internal/aesgcm"
+)
+
+// Errors
+var (
+	// ErrTooLarge is the error when encoded session data exceeds MaxSize
+	ErrTooLarge = errors.New("store/cookie: session data too large")
+
+	errNoKey = errors.New("store/cookie: no key")
+)
+
+// DefaultMaxSize is the default max size of encoded session data,
+// value larger than a cookie can hold (4096 bytes including name and attributes)
+// is split into multiple cookies by manager
+const DefaultMaxSize = 12000
+
+// Store keeps session data encrypted with AES-GCM in cookie,
+// no server storage is needed
+//
+// Cookie value is bound to cookie name, and expires with session ttl
+type Store struct {
+	Coder session.StoreCoder
+
+	// Keys is the AES keys (16, 24 or 32 bytes),
+	// the first key is used to encrypt, all keys are used to decrypt
+	Keys [][]byte
+
+	// MaxSize is the max size of encoded session data, default is DefaultMaxSize,
+	// beware of request header size limit of servers and proxies
+	MaxSize int
+
+	once   sync.Once
+	cipher aesgcm.Cipher
+	err    error
+}
+
+// New creates new cookie store
+func New(keys ...[]byte) *Store {
+	return &Store{Keys: keys}
+}
+
+func (s *Store) coder() session.StoreCoder {
+	if s.Coder == nil {
+		return session.DefaultStoreCoder
+	}
+	return s.Coder
+}
+
+func (s *Store) maxSize() int {
+	if s.MaxSize <= 0 {
+		return DefaultMaxSize
+	}
+	return s.MaxSize
+}
+
+func (s *Store) init() error {
+	s.once.Do(func() {
+		if len(s.Keys) == 0 {
+			s.err = errNoKey
+			return
+		}
+		s.cipher, s.err = aesgcm.New(s.Keys)
+	})
+	return s.err
+}
+
+// EncodeCookie implements session.CookieStore
+func (s *Store) EncodeCookie(name string, data session.Data, opt session.StoreOption) (string, error) {
+	if err := s.init(); err != nil {
+		return "", err
+	}
+
+	// payload is [expires unix int64][encoded data]
+	var expires int64
+	if opt.TTL > 0 {
+		expires = time.Now().Add(opt.TTL).Unix()
+	}
+	buf := bytes.NewBuffer(make([]byte, 8))
+	binary.BigEndian.PutUint64(buf.Bytes(), uint64(expires))
+	err := s.coder().NewEncoder(buf).Encode(data)
+	if err != nil {
+		return "", err
+	}
+
+	// use cookie name as additional data to prevent swapping cookies
+	b, err := s.cipher.Encrypt(buf.Bytes(), []byte(name))
+	if err != nil {
+		return "", err
+	}
+
+	value := base64.RawURLEncoding.EncodeToString(b)
+	if len(value) > s.maxSize() {
+		return "", ErrTooLarge
+	}
+	return value, nil
+}
+
+// DecodeCookie implements session.CookieStore
+func (s *Store) DecodeCookie(name string, value string) (session.Data, error) {
+	if err := s.init(); err != nil {
+		return nil, err
+	}
+
+	b, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, session.ErrNotFound
+	}
+
+	payload, ok := s.cipher.Decrypt(b, []byte(name))
+	if !ok || len(payload) < 8 {
+		return nil, session.ErrNotFound
+	}
+
+	expires := int64(binary.BigEndian.Uint64(payload))
+	if expires > 0 && time.Now().Unix() >= expires {
+		return nil, session.ErrNotFound
+	}
+
+	var data session.Data
+	err = s.coder().NewDecoder(bytes.NewReader(payload[8:])).Decode(&data)
+	if err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// Get always returns session.ErrNotFound, session data is loaded from cookie
+func (s *Store) Get(ctx context.Context, key string) (session.Data, error) {
+	return nil, session.ErrNotFound
+}
+
+// Set does nothing, session data is saved to cookie
+func (s *Store) Set(ctx context.Context, key string, value session.Data, opt session.StoreOption) error {
+	return nil
+}
+
+// Del does nothing, session cookie is removed when save destroyed session
+func (s *Store) Del(ctx context.Context, key string) error {
+	return nil
+}
@@ -0,0 +1,72 @@
+package cookie
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/moonrhythm/session"
+)
+
+func TestStore(t *testing.T) {
+	t.Parallel()
+
+	key := []byte("0123456789abcdef")
+	s := New(key)
+
+	value, err := s.EncodeCookie("sess", session.Data{"a": 1}, session.StoreOption{TTL: time.Minute})
+	assert.NoError(t, err)
+	assert.NotContains(t, value, ".")
+
+	data, err := s.DecodeCookie("sess", value)
+	assert.NoError(t, err)
+	assert.Equal(t, session.Data{"a": 1}, data)
+
+	t.Run("OtherName", func(t *testing.T) {
+		_, err := s.DecodeCookie("other", value)
+		assert.Equal(t, session.ErrNotFound, err)
+	})
+
+	t.Run("Tampered", func(t *testing.T) {
+		tampered := "A"
+		if value[0] == 'A' {
+			tampered = "B"
+		}
+		_, err := s.DecodeCookie("sess", tampered+value[1:])
+		assert.Equal(t, session.ErrNotFound, err)
+
+		_, err = s.DecodeCookie("sess", "!invalid")
+		assert.Equal(t, session.ErrNotFound, err)
+	})
+
+	t.Run("RotateKey", func(t *testing.T) {
+		s := New([]byte("fedcba9876543210"), key)
+		data, err := s.DecodeCookie("sess", value)
+		assert.NoError(t, err)
+		assert.Equal(t, session.Data{"a": 1}, data)
+
+		s = New([]byte("fedcba9876543210"))
+		_, err = s.DecodeCookie("sess", value)
+		assert.Equal(t, session.ErrNotFound, err)
+	})
+
+	t.Run("Expired", func(t *testing.T) {
+		value, err := s.EncodeCookie("sess", session.Data{"a": 1}, session.StoreOption{TTL: time.Nanosecond})
+		assert.NoError(t, err)
+		_, err = s.DecodeCookie("sess", value)
+		assert.Equal(t, session.ErrNotFound, err)
+	})
+
+	t.Run("TooLarge", func(t *testing.T) {
+		s := &Store{Keys: [][]byte{key}, MaxSize: 100}
+		_, err := s.EncodeCookie("sess", session.Data{"a": strings.Repeat("x", 100)}, session.StoreOption{})
+		assert.Equal(t, ErrTooLarge, err)
+	})
+
+	t.Run("NoKey", func(t *testing.T) {
+		_, err := new(Store).EncodeCookie("sess", session.Data{}, session.StoreOption{})
+		assert.Error(t, err)
+	})
+}
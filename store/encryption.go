@@ -0,0 +1,148 @@
+package store
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/moonrhythm/session"
+	"github.com/moonrhythm/session/internal/aesgcm"
+)
+
+// encryptedKey is the key that holds encrypted payload in wrapped store
+const encryptedKey = "_session/encrypted"
+
+// Encryption encrypts session data using AES-GCM before save to wrapped store
+//
+// Session data that can not be decrypted by any key is treated as not found
+type Encryption struct {
+	Store session.Store
+	Coder session.StoreCoder
+
+	// Keys is the AES keys (16, 24 or 32 bytes),
+	// the first key is used to encrypt, all keys are used to decrypt
+	Keys [][]byte
+
+	once   sync.Once
+	cipher aesgcm.Cipher
+	err    error
+}
+
+// WithEncryption wraps store with AES-GCM encryption
+func WithEncryption(inner session.Store, keys ...[]byte) *Encryption {
+	return &Encryption{Store: inner, Keys: keys}
+}
+
+func (s *Encryption) coder() session.StoreCoder {
+	if s.Coder == nil {
+		return session.DefaultStoreCoder
+	}
+	return s.Coder
+}
+
+func (s *Encryption) init() error {
+	s.once.Do(func() {
+		if len(s.Keys) == 0 {
+			s.err = errors.New("store/encryption: no key")
+			return
+		}
+		s.cipher, s.err = aesgcm.New(s.Keys)
+	})
+	return s.err
+}
+
+// Get gets session data from wrapped store then decrypt
+func (s *Encryption) Get(ctx context.Context, key string) (session.Data, error) {
+	if err := s.init(); err != nil {
+		return nil, err
+	}
+
+	data, err := s.Store.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+
+	ciphertext, _ := data[encryptedKey].([]byte)
+	b, ok := s.cipher.Decrypt(ciphertext, []byte(key))
+	if !ok {
+		return nil, session.ErrNotFound
+	}
+
+	var sessData session.Data
+	err = s.coder().NewDecoder(bytes.NewReader(b)).Decode(&sessData)
+	if err != nil {
+		return nil, err
+	}
+	return sessData, nil
+}
+
+func (s *Encryption) encrypt(key string, value session.Data) (session.Data, error) {
+	if err := s.init(); err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	err := s.coder().NewEncoder(&buf).Encode(value)
+	if err != nil {
+		return nil, err
+	}
+
+	// use store key as additional data to prevent swapping payloads between keys
+	ciphertext, err := s.cipher.Encrypt(buf.Bytes(), []byte(key))
+	if err != nil {
+		return nil, err
+	}
+	return withVersion(session.Data{encryptedKey: ciphertext}, value), nil
+}
+
+// Set encrypts session data then save to wrapped store
+func (s *Encryption) Set(ctx context.Context, key string, value session.Data, opt session.StoreOption) error {
+	data, err := s.encrypt(key, value)
+	if err != nil {
+		return err
+	}
+	return s.Store.Set(ctx, key, data, opt)
+}
+
+// CompareAndSet encrypts session data then save to wrapped store if version matches
+func (s *Encryption) CompareAndSet(ctx context.Context, key string, version int64, value session.Data, opt session.StoreOption) error {
+	st, ok := s.Store.(session.CASStore)
+	if !ok {
+		return session.ErrNotSupported
+	}
+	data, err := s.encrypt(key, value)
+	if err != nil {
+		return err
+	}
+	return st.CompareAndSet(ctx, key, version, data, opt)
+}
+
+// Del deletes session data from wrapped store
+func (s *Encryption) Del(ctx context.Context, key string) error {
+	return s.Store.Del(ctx, key)
+}
+
+// DelPrefix deletes session data by key prefix from wrapped store
+func (s *Encryption) DelPrefix(ctx context.Context, prefix string) error {
+	st, ok := s.Store.(session.PrefixDeleter)
+	if !ok {
+		return session.ErrNotSupported
+	}
+	return st.DelPrefix(ctx, prefix)
+}
+
+// Unwrap returns wrapped store
+func (s *Encryption) Unwrap() session.Store {
+	return s.Store
+}
+
+// Touch renews session data ttl in wrapped store
+func (s *Encryption) Touch(ctx context.Context, key string, ttl time.Duration) error {
+	st, ok := s.Store.(session.Toucher)
+	if !ok {
+		return session.ErrNotSupported
+	}
+	return st.Touch(ctx, key, ttl)
+}
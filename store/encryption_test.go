@@ -0,0 +1,56 @@
+package store
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/moonrhythm/session"
+)
+
+func TestEncryption(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	oldKey := bytes.Repeat([]byte("a"), 32)
+	newKey := bytes.Repeat([]byte("b"), 32)
+
+	inner := new(Memory)
+	data := session.Data{"test": "123"}
+
+	s := WithEncryption(inner, oldKey)
+	err := s.Set(ctx, "a", data, session.StoreOption{})
+	assert.NoError(t, err)
+
+	raw, err := inner.Get(ctx, "a")
+	assert.NoError(t, err)
+	assert.NotContains(t, raw, "test", "expected wrapped store not see plaintext")
+
+	b, err := s.Get(ctx, "a")
+	assert.NoError(t, err)
+	assert.Equal(t, data, b)
+
+	// rotate key
+	s = WithEncryption(inner, newKey, oldKey)
+	b, err = s.Get(ctx, "a")
+	assert.NoError(t, err, "expected old key can decrypt")
+	assert.Equal(t, data, b)
+
+	// old key removed
+	s = WithEncryption(inner, newKey)
+	_, err = s.Get(ctx, "a")
+	assert.Equal(t, session.ErrNotFound, err)
+
+	// payload moved to another key
+	inner.Set(ctx, "b", raw, session.StoreOption{})
+	s = WithEncryption(inner, oldKey)
+	_, err = s.Get(ctx, "b")
+	assert.Equal(t, session.ErrNotFound, err)
+
+	// invalid key
+	s = WithEncryption(inner, []byte("short"))
+	assert.Error(t, s.Set(ctx, "a", data, session.StoreOption{}))
+}
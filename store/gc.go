@@ -0,0 +1,62 @@
+package store
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// defaultGCBatchSize is the default number of expired sessions to delete per batch
+const defaultGCBatchSize = 1000
+
+func gcBatchSize(n int) int {
+	if n <= 0 {
+		return defaultGCBatchSize
+	}
+	return n
+}
+
+// jitter randomizes duration by +/- 10%,
+// to prevent many instances run gc at the same time
+func jitter(d time.Duration) time.Duration {
+	j := int64(d / 10)
+	if j <= 0 {
+		return d
+	}
+	return d - time.Duration(j) + time.Duration(rand.Int63n(2*j+1))
+}
+
+// latency tracks moving average of store operation latency
+type latency struct {
+	m   sync.Mutex
+	avg time.Duration
+}
+
+func (l *latency) observe(start time.Time) {
+	d := time.Since(start)
+
+	l.m.Lock()
+	if l.avg == 0 {
+		l.avg = d
+	} else {
+		l.avg = (l.avg*7 + d) / 8
+	}
+	l.m.Unlock()
+}
+
+func (l *latency) get() time.Duration {
+	l.m.Lock()
+	defer l.m.Unlock()
+	return l.avg
+}
+
+// shouldPauseGC checks is gc should be skipped for this round
+func shouldPauseGC(pause func() bool, l *latency, maxLatency time.Duration) bool {
+	if pause != nil && pause() {
+		return true
+	}
+	if maxLatency > 0 && l.get() > maxLatency {
+		return true
+	}
+	return false
+}
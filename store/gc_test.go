@@ -0,0 +1,31 @@
+package store
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestShouldPauseGC(t *testing.T) {
+	t.Parallel()
+
+	assert.False(t, shouldPauseGC(nil, nil, 0))
+	assert.True(t, shouldPauseGC(func() bool { return true }, nil, 0))
+	assert.False(t, shouldPauseGC(func() bool { return false }, nil, 0))
+
+	var l latency
+	l.observe(time.Now().Add(-time.Second))
+	assert.True(t, shouldPauseGC(nil, &l, 100*time.Millisecond), "expected pause when latency too high")
+	assert.False(t, shouldPauseGC(nil, &l, 2*time.Second))
+}
+
+func TestJitter(t *testing.T) {
+	t.Parallel()
+
+	for i := 0; i < 100; i++ {
+		d := jitter(time.Second)
+		assert.True(t, d >= 900*time.Millisecond && d <= 1100*time.Millisecond)
+	}
+	assert.Equal(t, time.Nanosecond, jitter(time.Nanosecond))
+}
@@ -3,6 +3,8 @@ package store
 import (
 	"bytes"
 	"context"
+	"strings"
+	"time"
 
 	"github.com/go-redis/redis/v8"
 
@@ -52,7 +54,120 @@ func (s *GoRedis) Set(ctx context.Context, key string, value session.Data, opt s
 	return s.Client.Set(ctx, s.Prefix+key, buf.Bytes(), opt.TTL).Err()
 }
 
+// CompareAndSet sets session data to redis if version of stored data equals to version
+func (s *GoRedis) CompareAndSet(ctx context.Context, key string, version int64, value session.Data, opt session.StoreOption) error {
+	var buf bytes.Buffer
+	err := s.coder().NewEncoder(&buf).Encode(value)
+	if err != nil {
+		return err
+	}
+
+	key = s.Prefix + key
+	err = s.Client.Watch(ctx, func(tx *redis.Tx) error {
+		var current int64
+		data, err := tx.Get(ctx, key).Bytes()
+		if err != nil && err != redis.Nil {
+			return err
+		}
+		if err == nil {
+			var sessData session.Data
+			err = s.coder().NewDecoder(bytes.NewReader(data)).Decode(&sessData)
+			if err != nil {
+				return err
+			}
+			current = sessData.Version()
+		}
+		if current != version {
+			return session.ErrConflict
+		}
+
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.Set(ctx, key, buf.Bytes(), opt.TTL)
+			return nil
+		})
+		return err
+	}, key)
+	if err == redis.TxFailedErr {
+		return session.ErrConflict
+	}
+	return err
+}
+
 // Del deletes session data from redis
 func (s *GoRedis) Del(ctx context.Context, key string) error {
 	return s.Client.Del(ctx, s.Prefix+key).Err()
 }
+
+// DelPrefix deletes all session data which key has the given prefix from redis
+func (s *GoRedis) DelPrefix(ctx context.Context, prefix string) error {
+	var cursor uint64
+	for {
+		keys, next, err := s.Client.Scan(ctx, cursor, matchPrefix(s.Prefix+prefix), 100).Result()
+		if err != nil {
+			return err
+		}
+		if len(keys) > 0 {
+			err = s.Client.Del(ctx, keys...).Err()
+			if err != nil {
+				return err
+			}
+		}
+		if next == 0 {
+			return nil
+		}
+		cursor = next
+	}
+}
+
+// Touch renews session data ttl in redis
+func (s *GoRedis) Touch(ctx context.Context, key string, ttl time.Duration) error {
+	if ttl > 0 {
+		return s.Client.Expire(ctx, s.Prefix+key, ttl).Err()
+	}
+	return s.Client.Persist(ctx, s.Prefix+key).Err()
+}
+
+// GetMulti gets multiple sessions data from redis using MGET
+func (s *GoRedis) GetMulti(ctx context.Context, keys []string) (map[string]session.Data, error) {
+	prefixed := make([]string, len(keys))
+	for i, key := range keys {
+		prefixed[i] = s.Prefix + key
+	}
+
+	values, err := s.Client.MGet(ctx, prefixed...).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	r := make(map[string]session.Data, len(keys))
+	for i, v := range values {
+		b, ok := v.(string)
+		if !ok {
+			// not found
+			continue
+		}
+
+		var sessData session.Data
+		err = s.coder().NewDecoder(strings.NewReader(b)).Decode(&sessData)
+		if err != nil {
+			return nil, err
+		}
+		r[keys[i]] = sessData
+	}
+	return r, nil
+}
+
+// SetMulti sets multiple sessions data to redis in single pipeline
+func (s *GoRedis) SetMulti(ctx context.Context, values map[string]session.Data, opt session.StoreOption) error {
+	pipe := s.Client.Pipeline()
+	for key, value := range values {
+		var buf bytes.Buffer
+		err := s.coder().NewEncoder(&buf).Encode(value)
+		if err != nil {
+			return err
+		}
+		pipe.Set(ctx, s.Prefix+key, buf.Bytes(), opt.TTL)
+	}
+	_, err := pipe.Exec(ctx)
+	return err
+}
@@ -75,3 +75,38 @@ func TestGoRedisWithoutTTL(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, data, b)
 }
+
+func TestGoRedisCompareAndSet(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	s := &GoRedis{
+		Prefix: "session:",
+		Client: redis.NewClient(&redis.Options{
+			Addr: "localhost:6379",
+		}),
+	}
+	s.Del(ctx, "__goredis_cas")
+
+	opt := session.StoreOption{TTL: time.Minute}
+
+	err := s.CompareAndSet(ctx, "__goredis_cas", 1, session.Data{"_session/version": int64(2)}, opt)
+	assert.Equal(t, session.ErrConflict, err, "expected not exists key has version 0")
+
+	err = s.CompareAndSet(ctx, "__goredis_cas", 0, session.Data{"a": "1", "_session/version": int64(1)}, opt)
+	assert.NoError(t, err)
+
+	err = s.CompareAndSet(ctx, "__goredis_cas", 0, session.Data{"a": "2", "_session/version": int64(1)}, opt)
+	assert.Equal(t, session.ErrConflict, err, "expected stale version rejected")
+
+	err = s.CompareAndSet(ctx, "__goredis_cas", 1, session.Data{"a": "3", "_session/version": int64(2)}, opt)
+	assert.NoError(t, err)
+
+	b, err := s.Get(ctx, "__goredis_cas")
+	assert.NoError(t, err)
+	assert.Equal(t, "3", b["a"])
+	assert.Equal(t, int64(2), b.Version())
+
+	s.Del(ctx, "__goredis_cas")
+}
@@ -0,0 +1,305 @@
+// Package jwt provides store that keeps session data in cookie as JWT,
+// no server storage is needed
+package jwt
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/moonrhythm/session"
+)
+
+// Errors
+var (
+	// ErrTooLarge is the error when encoded token exceeds MaxSize
+	ErrTooLarge = errors.New("store/jwt: session data too large")
+
+	errNoKey         = errors.New("store/jwt: no signing key")
+	errEncryptionKey = errors.New("store/jwt: invalid encryption key size")
+)
+
+// DefaultMaxSize is the default max size of encoded token,
+// value larger than a cookie can hold (4096 bytes including name and attributes)
+// is split into multiple cookies by manager
+const DefaultMaxSize = 12000
+
+// Store keeps session data in cookie as JWT signed with HS256,
+// and encrypted as JWE (dir, AES-GCM) when EncryptionKeys is set
+//
+// Session data is encoded by Coder into "dat" claim,
+// "iat" and "exp" claims are set from session ttl,
+// "aud" claim is the cookie name, token moved to another cookie is rejected
+type Store struct {
+	Coder session.StoreCoder
+
+	// SigningKeys is the HMAC-SHA256 keys,
+	// the first key is used to sign, all keys are used to verify
+	SigningKeys [][]byte
+
+	// EncryptionKeys is the AES keys (16, 24 or 32 bytes),
+	// the first key is used to encrypt, all keys are used to decrypt,
+	// if EncryptionKeys is empty, token is signed only and session data is readable by client
+	EncryptionKeys [][]byte
+
+	// Issuer is the "iss" claim, token with different issuer is rejected
+	Issuer string
+
+	// Subject is the session key mapped to "sub" claim, e.g. user id
+	Subject string
+
+	// MaxSize is the max size of encoded token, default is DefaultMaxSize
+	MaxSize int
+}
+
+// New creates new jwt store
+func New(signingKeys ...[]byte) *Store {
+	return &Store{SigningKeys: signingKeys}
+}
+
+type claims struct {
+	Issuer    string `json:"iss,omitempty"`
+	Subject   string `json:"sub,omitempty"`
+	Audience  string `json:"aud"`
+	IssuedAt  int64  `json:"iat"`
+	ExpiresAt int64  `json:"exp,omitempty"`
+	Data      []byte `json:"dat"`
+}
+
+var b64 = base64.RawURLEncoding
+
+func (s *Store) coder() session.StoreCoder {
+	if s.Coder == nil {
+		return session.DefaultStoreCoder
+	}
+	return s.Coder
+}
+
+func (s *Store) maxSize() int {
+	if s.MaxSize <= 0 {
+		return DefaultMaxSize
+	}
+	return s.MaxSize
+}
+
+// EncodeCookie implements session.CookieStore
+func (s *Store) EncodeCookie(name string, data session.Data, opt session.StoreOption) (string, error) {
+	if len(s.SigningKeys) == 0 {
+		return "", errNoKey
+	}
+
+	var buf bytes.Buffer
+	err := s.coder().NewEncoder(&buf).Encode(data)
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	c := claims{
+		Issuer:   s.Issuer,
+		Audience: name,
+		IssuedAt: now.Unix(),
+		Data:     buf.Bytes(),
+	}
+	if s.Subject != "" {
+		c.Subject, _ = data[s.Subject].(string)
+	}
+	if opt.TTL > 0 {
+		c.ExpiresAt = now.Add(opt.TTL).Unix()
+	}
+	payload, err := json.Marshal(c)
+	if err != nil {
+		return "", err
+	}
+
+	token := sign(s.SigningKeys[0], payload)
+	if len(s.EncryptionKeys) > 0 {
+		token, err = encrypt(s.EncryptionKeys[0], token)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	if len(token) > s.maxSize() {
+		return "", ErrTooLarge
+	}
+	return token, nil
+}
+
+// DecodeCookie implements session.CookieStore,
+// invalid or expired token is treated as not found
+func (s *Store) DecodeCookie(name string, value string) (session.Data, error) {
+	if len(s.SigningKeys) == 0 {
+		return nil, errNoKey
+	}
+
+	token := value
+	if len(s.EncryptionKeys) > 0 {
+		token = decrypt(s.EncryptionKeys, value)
+	}
+	payload := verify(s.SigningKeys, token)
+	if payload == nil {
+		return nil, session.ErrNotFound
+	}
+
+	var c claims
+	if err := json.Unmarshal(payload, &c); err != nil {
+		return nil, session.ErrNotFound
+	}
+	if c.Issuer != s.Issuer || c.Audience != name {
+		return nil, session.ErrNotFound
+	}
+	if c.ExpiresAt > 0 && time.Now().Unix() >= c.ExpiresAt {
+		return nil, session.ErrNotFound
+	}
+
+	var data session.Data
+	err := s.coder().NewDecoder(bytes.NewReader(c.Data)).Decode(&data)
+	if err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// Get always returns session.ErrNotFound, session data is loaded from cookie
+func (s *Store) Get(ctx context.Context, key string) (session.Data, error) {
+	return nil, session.ErrNotFound
+}
+
+// Set does nothing, session data is saved to cookie
+func (s *Store) Set(ctx context.Context, key string, value session.Data, opt session.StoreOption) error {
+	return nil
+}
+
+// Del does nothing, session cookie is removed when save destroyed session
+func (s *Store) Del(ctx context.Context, key string) error {
+	return nil
+}
+
+var signHeader = b64.EncodeToString([]byte(`{"alg":"HS256","typ":"JWT"}`))
+
+// sign returns JWS compact serialization
+func sign(key, payload []byte) string {
+	signingInput := signHeader + "." + b64.EncodeToString(payload)
+	return signingInput + "." + b64.EncodeToString(mac(key, signingInput))
+}
+
+// verify returns payload if token is signed by any key
+func verify(keys [][]byte, token string) []byte {
+	i := strings.LastIndexByte(token, '.')
+	if i < 0 || !strings.HasPrefix(token, signHeader+".") {
+		return nil
+	}
+	signingInput := token[:i]
+	sig, err := b64.DecodeString(token[i+1:])
+	if err != nil {
+		return nil
+	}
+
+	for _, key := range keys {
+		if hmac.Equal(sig, mac(key, signingInput)) {
+			payload, err := b64.DecodeString(signingInput[len(signHeader)+1:])
+			if err != nil {
+				return nil
+			}
+			return payload
+		}
+	}
+	return nil
+}
+
+func mac(key []byte, s string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(s))
+	return h.Sum(nil)
+}
+
+func encryptionHeader(key []byte) (string, error) {
+	var enc string
+	switch len(key) {
+	case 16:
+		enc = "A128GCM"
+	case 24:
+		enc = "A192GCM"
+	case 32:
+		enc = "A256GCM"
+	default:
+		return "", errEncryptionKey
+	}
+	return b64.EncodeToString([]byte(`{"alg":"dir","enc":"` + enc + `","cty":"JWT"}`)), nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// encrypt returns JWE compact serialization with direct encryption
+func encrypt(key []byte, token string) (string, error) {
+	header, err := encryptionHeader(key)
+	if err != nil {
+		return "", err
+	}
+	aead, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+
+	iv := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(iv); err != nil {
+		return "", err
+	}
+	sealed := aead.Seal(nil, iv, []byte(token), []byte(header))
+	n := len(sealed) - aead.Overhead()
+
+	return header + ".." + b64.EncodeToString(iv) + "." +
+		b64.EncodeToString(sealed[:n]) + "." + b64.EncodeToString(sealed[n:]), nil
+}
+
+// decrypt returns decrypted token, or empty string if any key can not decrypt
+func decrypt(keys [][]byte, value string) string {
+	parts := strings.Split(value, ".")
+	if len(parts) != 5 || parts[1] != "" {
+		return ""
+	}
+	iv, err := b64.DecodeString(parts[2])
+	if err != nil {
+		return ""
+	}
+	ciphertext, err := b64.DecodeString(parts[3])
+	if err != nil {
+		return ""
+	}
+	tag, err := b64.DecodeString(parts[4])
+	if err != nil {
+		return ""
+	}
+	sealed := append(ciphertext, tag...)
+
+	for _, key := range keys {
+		header, err := encryptionHeader(key)
+		if err != nil || header != parts[0] {
+			continue
+		}
+		aead, err := newGCM(key)
+		if err != nil || len(iv) != aead.NonceSize() {
+			continue
+		}
+		if b, err := aead.Open(nil, iv, sealed, []byte(header)); err == nil {
+			return string(b)
+		}
+	}
+	return ""
+}
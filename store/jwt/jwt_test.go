@@ -0,0 +1,127 @@
+package jwt
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/moonrhythm/session"
+)
+
+func TestStore(t *testing.T) {
+	t.Parallel()
+
+	key := []byte("secret")
+	s := &Store{SigningKeys: [][]byte{key}, Issuer: "app", Subject: "user"}
+
+	value, err := s.EncodeCookie("sess", session.Data{"a": 1, "user": "u1"}, session.StoreOption{TTL: time.Minute})
+	assert.NoError(t, err)
+	parts := strings.Split(value, ".")
+	if !assert.Len(t, parts, 3) {
+		return
+	}
+
+	var c map[string]interface{}
+	b, _ := base64.RawURLEncoding.DecodeString(parts[1])
+	assert.NoError(t, json.Unmarshal(b, &c))
+	assert.Equal(t, "app", c["iss"])
+	assert.Equal(t, "u1", c["sub"])
+	assert.Equal(t, "sess", c["aud"])
+	assert.NotZero(t, c["iat"])
+	assert.NotZero(t, c["exp"])
+
+	data, err := s.DecodeCookie("sess", value)
+	assert.NoError(t, err)
+	assert.Equal(t, session.Data{"a": 1, "user": "u1"}, data)
+
+	t.Run("Tampered", func(t *testing.T) {
+		_, err := s.DecodeCookie("sess", parts[0]+"."+parts[1]+"x."+parts[2])
+		assert.Equal(t, session.ErrNotFound, err)
+
+		_, err = s.DecodeCookie("sess", "invalid")
+		assert.Equal(t, session.ErrNotFound, err)
+	})
+
+	t.Run("OtherIssuer", func(t *testing.T) {
+		s := &Store{SigningKeys: [][]byte{key}, Issuer: "other"}
+		_, err := s.DecodeCookie("sess", value)
+		assert.Equal(t, session.ErrNotFound, err)
+	})
+
+	t.Run("OtherCookie", func(t *testing.T) {
+		_, err := s.DecodeCookie("other", value)
+		assert.Equal(t, session.ErrNotFound, err, "expected token from another cookie rejected")
+	})
+
+	t.Run("RotateKey", func(t *testing.T) {
+		s := &Store{SigningKeys: [][]byte{[]byte("new"), key}, Issuer: "app"}
+		_, err := s.DecodeCookie("sess", value)
+		assert.NoError(t, err)
+
+		s = &Store{SigningKeys: [][]byte{[]byte("new")}, Issuer: "app"}
+		_, err = s.DecodeCookie("sess", value)
+		assert.Equal(t, session.ErrNotFound, err)
+	})
+
+	t.Run("Expired", func(t *testing.T) {
+		value, err := s.EncodeCookie("sess", session.Data{"a": 1}, session.StoreOption{TTL: time.Nanosecond})
+		assert.NoError(t, err)
+		_, err = s.DecodeCookie("sess", value)
+		assert.Equal(t, session.ErrNotFound, err)
+	})
+
+	t.Run("TooLarge", func(t *testing.T) {
+		s := &Store{SigningKeys: [][]byte{key}, MaxSize: 100}
+		_, err := s.EncodeCookie("sess", session.Data{"a": strings.Repeat("x", 100)}, session.StoreOption{})
+		assert.Equal(t, ErrTooLarge, err)
+	})
+
+	t.Run("NoKey", func(t *testing.T) {
+		_, err := new(Store).EncodeCookie("sess", session.Data{}, session.StoreOption{})
+		assert.Error(t, err)
+	})
+}
+
+func TestStoreEncryption(t *testing.T) {
+	t.Parallel()
+
+	key := []byte("0123456789abcdef0123456789abcdef")
+	s := &Store{SigningKeys: [][]byte{[]byte("secret")}, EncryptionKeys: [][]byte{key}}
+
+	value, err := s.EncodeCookie("sess", session.Data{"a": 1}, session.StoreOption{TTL: time.Minute})
+	assert.NoError(t, err)
+	assert.Len(t, strings.Split(value, "."), 5)
+
+	data, err := s.DecodeCookie("sess", value)
+	assert.NoError(t, err)
+	assert.Equal(t, session.Data{"a": 1}, data)
+
+	t.Run("RotateKey", func(t *testing.T) {
+		s := &Store{
+			SigningKeys:    [][]byte{[]byte("secret")},
+			EncryptionKeys: [][]byte{[]byte("fedcba9876543210"), key},
+		}
+		_, err := s.DecodeCookie("sess", value)
+		assert.NoError(t, err)
+
+		s.EncryptionKeys = [][]byte{[]byte("fedcba9876543210")}
+		_, err = s.DecodeCookie("sess", value)
+		assert.Equal(t, session.ErrNotFound, err)
+	})
+
+	t.Run("SignedOnly", func(t *testing.T) {
+		signed, _ := (&Store{SigningKeys: [][]byte{[]byte("secret")}}).EncodeCookie("sess", session.Data{"a": 1}, session.StoreOption{})
+		_, err := s.DecodeCookie("sess", signed)
+		assert.Equal(t, session.ErrNotFound, err, "expected unencrypted token rejected")
+	})
+
+	t.Run("InvalidKey", func(t *testing.T) {
+		s := &Store{SigningKeys: [][]byte{[]byte("secret")}, EncryptionKeys: [][]byte{[]byte("short")}}
+		_, err := s.EncodeCookie("sess", session.Data{}, session.StoreOption{})
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,128 @@
+package store
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"log"
+	"time"
+
+	"github.com/moonrhythm/session"
+)
+
+// Logger is the logger for Logging store, *log.Logger implements Logger
+type Logger interface {
+	Printf(format string, v ...interface{})
+}
+
+// keyHash hashes store key, to not leak session key to log
+func keyHash(key string) string {
+	h := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(h[:8])
+}
+
+type stdLogger struct{}
+
+func (stdLogger) Printf(format string, v ...interface{}) {
+	log.Printf(format, v...)
+}
+
+// Logging logs slow or failed wrapped store operations
+type Logging struct {
+	Store session.Store
+
+	// Logger is the logger, if nil, standard logger will be used
+	Logger Logger
+
+	// SlowThreshold logs operations that take longer than SlowThreshold,
+	// zero means do not log slow operations
+	SlowThreshold time.Duration
+}
+
+// WithLogging wraps store with logging
+func WithLogging(inner session.Store, logger Logger, slowThreshold time.Duration) *Logging {
+	return &Logging{Store: inner, Logger: logger, SlowThreshold: slowThreshold}
+}
+
+func (s *Logging) logger() Logger {
+	if s.Logger == nil {
+		return stdLogger{}
+	}
+	return s.Logger
+}
+
+func (s *Logging) log(op string, key string, start time.Time, err error) {
+	d := time.Since(start)
+	if err != nil && err != session.ErrNotFound && err != session.ErrConflict {
+		s.logger().Printf("store/logging: %s key_hash=%s failed after %v: %v", op, keyHash(key), d, err)
+		return
+	}
+	if s.SlowThreshold > 0 && d >= s.SlowThreshold {
+		s.logger().Printf("store/logging: %s key_hash=%s slow, took %v", op, keyHash(key), d)
+	}
+}
+
+// Get gets session data from wrapped store
+func (s *Logging) Get(ctx context.Context, key string) (session.Data, error) {
+	start := time.Now()
+	data, err := s.Store.Get(ctx, key)
+	s.log("get", key, start, err)
+	return data, err
+}
+
+// Set sets session data to wrapped store
+func (s *Logging) Set(ctx context.Context, key string, value session.Data, opt session.StoreOption) error {
+	start := time.Now()
+	err := s.Store.Set(ctx, key, value, opt)
+	s.log("set", key, start, err)
+	return err
+}
+
+// CompareAndSet sets session data to wrapped store if version matches
+func (s *Logging) CompareAndSet(ctx context.Context, key string, version int64, value session.Data, opt session.StoreOption) error {
+	st, ok := s.Store.(session.CASStore)
+	if !ok {
+		return session.ErrNotSupported
+	}
+	start := time.Now()
+	err := st.CompareAndSet(ctx, key, version, value, opt)
+	s.log("compare_and_set", key, start, err)
+	return err
+}
+
+// Del deletes session data from wrapped store
+func (s *Logging) Del(ctx context.Context, key string) error {
+	start := time.Now()
+	err := s.Store.Del(ctx, key)
+	s.log("del", key, start, err)
+	return err
+}
+
+// DelPrefix deletes session data by key prefix from wrapped store
+func (s *Logging) DelPrefix(ctx context.Context, prefix string) error {
+	st, ok := s.Store.(session.PrefixDeleter)
+	if !ok {
+		return session.ErrNotSupported
+	}
+	start := time.Now()
+	err := st.DelPrefix(ctx, prefix)
+	s.log("del_prefix", prefix, start, err)
+	return err
+}
+
+// Unwrap returns wrapped store
+func (s *Logging) Unwrap() session.Store {
+	return s.Store
+}
+
+// Touch renews session data ttl in wrapped store
+func (s *Logging) Touch(ctx context.Context, key string, ttl time.Duration) error {
+	st, ok := s.Store.(session.Toucher)
+	if !ok {
+		return session.ErrNotSupported
+	}
+	start := time.Now()
+	err := st.Touch(ctx, key, ttl)
+	s.log("touch", key, start, err)
+	return err
+}
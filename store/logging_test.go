@@ -0,0 +1,43 @@
+package store
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/moonrhythm/session"
+)
+
+type slowStore struct {
+	Memory
+}
+
+func (s *slowStore) Set(ctx context.Context, key string, value session.Data, opt session.StoreOption) error {
+	time.Sleep(10 * time.Millisecond)
+	return s.Memory.Set(ctx, key, value, opt)
+}
+
+func TestLogging(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	var buf bytes.Buffer
+	s := WithLogging(new(slowStore), log.New(&buf, "", 0), 5*time.Millisecond)
+
+	s.Get(ctx, "a")
+	assert.Empty(t, buf.String(), "expected not found not logged")
+
+	s.Set(ctx, "a", session.Data{}, session.StoreOption{})
+	assert.Contains(t, buf.String(), "set key_hash="+keyHash("a")+" slow")
+	assert.NotContains(t, buf.String(), "key_hash=a ")
+
+	buf.Reset()
+	s.Store = &mockStore{}
+	s.Del(ctx, "a")
+	assert.Contains(t, buf.String(), "del key_hash="+keyHash("a")+" failed")
+}
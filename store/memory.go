@@ -2,7 +2,9 @@ package store
 
 import (
 	"bytes"
+	"container/list"
 	"context"
+	"strings"
 	"sync"
 	"time"
 
@@ -13,13 +15,37 @@ import (
 type Memory struct {
 	Coder session.StoreCoder
 
-	m sync.RWMutex
-	l map[interface{}]*memoryItem
+	// MaxEntries is the maximum number of sessions to keep in memory,
+	// least recently used sessions will be evicted when exceeded.
+	// Zero means no limit
+	MaxEntries int
+
+	// MaxBytes is the maximum total size of encoded session data,
+	// least recently used sessions will be evicted when exceeded.
+	// Zero means no limit
+	MaxBytes int64
+
+	// GCBatchSize is the maximum number of expired sessions to delete
+	// while holding the lock, default is 1000
+	GCBatchSize int
+
+	// GCPause reports whether gc worker should skip the current round,
+	// e.g. when the process is under heavy load
+	GCPause func() bool
+
+	m     sync.RWMutex
+	l     map[interface{}]*memoryItem
+	lru   *list.List // front is the most recently used
+	bytes int64
+	users map[string][]string // user id => session keys, oldest first
 }
 
 type memoryItem struct {
-	data []byte
-	exp  time.Time
+	key     string
+	data    []byte
+	version int64
+	exp     time.Time
+	elem    *list.Element
 }
 
 func (s *Memory) coder() session.StoreCoder {
@@ -30,74 +56,311 @@ func (s *Memory) coder() session.StoreCoder {
 }
 
 func (s *Memory) gcWorker(d time.Duration) {
-	s.GC()
-	time.AfterFunc(d, func() { s.gcWorker(d) })
+	if !shouldPauseGC(s.GCPause, nil, 0) {
+		s.GC()
+	}
+	time.AfterFunc(jitter(d), func() { s.gcWorker(d) })
 }
 
 // GCEvery starts gc every given duration
 func (s *Memory) GCEvery(d time.Duration) *Memory {
-	time.AfterFunc(d, func() { s.gcWorker(d) })
+	time.AfterFunc(jitter(d), func() { s.gcWorker(d) })
 	return s
 }
 
-// GC runs gc
+// GC runs gc,
+// expired sessions are deleted in batches to not hold the lock for too long
 func (s *Memory) GC() {
+	batch := gcBatchSize(s.GCBatchSize)
+	for {
+		if s.gcBatch(batch) < batch {
+			return
+		}
+	}
+}
+
+// gcBatch deletes at most n expired sessions, returns number of deleted sessions
+func (s *Memory) gcBatch(n int) int {
 	s.m.Lock()
 	defer s.m.Unlock()
 
 	now := time.Now()
-	for k, v := range s.l {
+	deleted := 0
+	for _, v := range s.l {
 		if !v.exp.IsZero() && v.exp.Before(now) {
-			delete(s.l, k)
+			s.remove(v)
+			deleted++
+			if deleted >= n {
+				break
+			}
+		}
+	}
+	return deleted
+}
+
+func (s *Memory) limited() bool {
+	return s.MaxEntries > 0 || s.MaxBytes > 0
+}
+
+// remove removes item from memory, must hold write lock
+func (s *Memory) remove(it *memoryItem) {
+	delete(s.l, it.key)
+	if it.elem != nil {
+		s.lru.Remove(it.elem)
+	}
+	s.bytes -= int64(len(it.data))
+}
+
+// evict removes least recently used items until memory is under limits,
+// must hold write lock
+func (s *Memory) evict() {
+	for s.lru.Len() > 0 {
+		if (s.MaxEntries <= 0 || len(s.l) <= s.MaxEntries) && (s.MaxBytes <= 0 || s.bytes <= s.MaxBytes) {
+			return
 		}
+		s.remove(s.lru.Back().Value.(*memoryItem))
 	}
 }
 
 // Get gets session data from memory
 func (s *Memory) Get(_ context.Context, key string) (session.Data, error) {
-	s.m.RLock()
-	defer s.m.RUnlock()
-
-	v := s.l[key]
-	if v == nil {
-		return nil, session.ErrNotFound
+	var data []byte
+	if s.limited() {
+		// need write lock to update recently used list
+		s.m.Lock()
+		v := s.l[key]
+		if v != nil && v.elem != nil {
+			s.lru.MoveToFront(v.elem)
+		}
+		data = s.valid(v)
+		s.m.Unlock()
+	} else {
+		s.m.RLock()
+		data = s.valid(s.l[key])
+		s.m.RUnlock()
 	}
-	if !v.exp.IsZero() && v.exp.Before(time.Now()) {
+	if data == nil {
 		return nil, session.ErrNotFound
 	}
+
 	var sessData session.Data
-	err := s.coder().NewDecoder(bytes.NewReader(v.data)).Decode(&sessData)
+	err := s.coder().NewDecoder(bytes.NewReader(data)).Decode(&sessData)
 	if err != nil {
 		return nil, err
 	}
 	return sessData, nil
 }
 
+// valid returns item's data if item not expired
+func (s *Memory) valid(it *memoryItem) []byte {
+	if it == nil {
+		return nil
+	}
+	if !it.exp.IsZero() && it.exp.Before(time.Now()) {
+		return nil
+	}
+	return it.data
+}
+
 // Set sets session data to memory
 func (s *Memory) Set(_ context.Context, key string, value session.Data, opt session.StoreOption) error {
-	var buf bytes.Buffer
-	err := s.coder().NewEncoder(&buf).Encode(value)
+	it, err := s.item(key, value, opt)
 	if err != nil {
 		return err
 	}
 
 	s.m.Lock()
-	it := &memoryItem{data: buf.Bytes()}
+	s.put(it)
+	s.m.Unlock()
+	return nil
+}
+
+// CompareAndSet sets session data to memory if version of stored data equals to version
+func (s *Memory) CompareAndSet(_ context.Context, key string, version int64, value session.Data, opt session.StoreOption) error {
+	it, err := s.item(key, value, opt)
+	if err != nil {
+		return err
+	}
+
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	var current int64
+	if old := s.l[key]; s.valid(old) != nil {
+		current = old.version
+	}
+	if current != version {
+		return session.ErrConflict
+	}
+	s.put(it)
+	return nil
+}
+
+// item encodes session data into memory item
+func (s *Memory) item(key string, value session.Data, opt session.StoreOption) (*memoryItem, error) {
+	var buf bytes.Buffer
+	err := s.coder().NewEncoder(&buf).Encode(value)
+	if err != nil {
+		return nil, err
+	}
+
+	it := &memoryItem{key: key, data: buf.Bytes(), version: value.Version()}
 	if opt.TTL > 0 {
 		it.exp = time.Now().Add(opt.TTL)
 	}
+	return it, nil
+}
+
+// put puts item into memory, must hold write lock
+func (s *Memory) put(it *memoryItem) {
+	key := it.key
 	if s.l == nil {
 		s.l = make(map[interface{}]*memoryItem)
 	}
+	if s.lru == nil {
+		s.lru = list.New()
+	}
+	if old := s.l[key]; old != nil {
+		s.remove(old)
+	}
 	s.l[key] = it
-	s.m.Unlock()
-	return nil
+	s.bytes += int64(len(it.data))
+	if s.limited() {
+		it.elem = s.lru.PushFront(it)
+		s.evict()
+	}
 }
 
 // Del deletes session data from memory
 func (s *Memory) Del(_ context.Context, key string) error {
 	s.m.Lock()
-	delete(s.l, key)
+	if it := s.l[key]; it != nil {
+		s.remove(it)
+	}
+	s.m.Unlock()
+	return nil
+}
+
+// DelPrefix deletes all session data which key has the given prefix from memory
+func (s *Memory) DelPrefix(_ context.Context, prefix string) error {
+	s.m.Lock()
+	for _, it := range s.l {
+		if strings.HasPrefix(it.key, prefix) {
+			s.remove(it)
+		}
+	}
 	s.m.Unlock()
 	return nil
 }
+
+// Touch renews session data ttl in memory
+func (s *Memory) Touch(_ context.Context, key string, ttl time.Duration) error {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	it := s.l[key]
+	if it == nil {
+		return nil
+	}
+	if s.valid(it) == nil {
+		// expired item must not come back to life
+		return session.ErrNotFound
+	}
+	if it.elem != nil {
+		s.lru.MoveToFront(it.elem)
+	}
+	if ttl > 0 {
+		it.exp = time.Now().Add(ttl)
+	} else {
+		it.exp = time.Time{}
+	}
+	return nil
+}
+
+// GetMulti gets multiple sessions data from memory
+func (s *Memory) GetMulti(ctx context.Context, keys []string) (map[string]session.Data, error) {
+	r := make(map[string]session.Data, len(keys))
+	for _, key := range keys {
+		data, err := s.Get(ctx, key)
+		if err == session.ErrNotFound {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		r[key] = data
+	}
+	return r, nil
+}
+
+// SetMulti sets multiple sessions data to memory
+func (s *Memory) SetMulti(ctx context.Context, values map[string]session.Data, opt session.StoreOption) error {
+	for key, value := range values {
+		err := s.Set(ctx, key, value, opt)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AddUserSession adds session key to user's index
+func (s *Memory) AddUserSession(_ context.Context, userID string, key string) error {
+	s.m.Lock()
+	if s.users == nil {
+		s.users = make(map[string][]string)
+	}
+	s.users[userID] = append(removeKeys(s.users[userID], key), key)
+	s.m.Unlock()
+	return nil
+}
+
+// UserSessions returns session keys in user's index, oldest first,
+// expired or deleted sessions are removed from index
+func (s *Memory) UserSessions(_ context.Context, userID string) ([]string, error) {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	keys := s.users[userID][:0]
+	for _, k := range s.users[userID] {
+		if s.valid(s.l[k]) != nil {
+			keys = append(keys, k)
+		}
+	}
+	if len(keys) == 0 {
+		delete(s.users, userID)
+		return nil, nil
+	}
+	s.users[userID] = keys
+	return append([]string{}, keys...), nil
+}
+
+// DelUserSessions removes session keys from user's index
+func (s *Memory) DelUserSessions(_ context.Context, userID string, keys []string) error {
+	s.m.Lock()
+	r := removeKeys(s.users[userID], keys...)
+	if len(r) == 0 {
+		delete(s.users, userID)
+	} else {
+		s.users[userID] = r
+	}
+	s.m.Unlock()
+	return nil
+}
+
+func removeKeys(keys []string, remove ...string) []string {
+	r := keys[:0]
+	for _, k := range keys {
+		found := false
+		for _, x := range remove {
+			if k == x {
+				found = true
+				break
+			}
+		}
+		if !found {
+			r = append(r, k)
+		}
+	}
+	return r
+}
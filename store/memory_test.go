@@ -1,7 +1,10 @@
 package store
 
 import (
+	"bytes"
 	"context"
+	"fmt"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -64,3 +67,206 @@ func TestMemoryWithoutTTL(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, data, b)
 }
+
+func TestMemoryMaxEntries(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	s := &Memory{MaxEntries: 2}
+
+	data := session.Data{"test": "123"}
+	opt := session.StoreOption{}
+
+	s.Set(ctx, "a", data, opt)
+	s.Set(ctx, "b", data, opt)
+
+	// use a, so b is the least recently used
+	_, err := s.Get(ctx, "a")
+	assert.NoError(t, err)
+
+	s.Set(ctx, "c", data, opt)
+
+	_, err = s.Get(ctx, "a")
+	assert.NoError(t, err)
+	_, err = s.Get(ctx, "b")
+	assert.Equal(t, session.ErrNotFound, err, "expected least recently used session evicted")
+	_, err = s.Get(ctx, "c")
+	assert.NoError(t, err)
+}
+
+func TestMemoryMaxBytes(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	var buf bytes.Buffer
+	data := session.Data{"test": "123"}
+	session.DefaultStoreCoder.NewEncoder(&buf).Encode(data)
+
+	s := &Memory{MaxBytes: int64(buf.Len()) * 2}
+	opt := session.StoreOption{}
+
+	s.Set(ctx, "a", data, opt)
+	s.Set(ctx, "b", data, opt)
+	s.Set(ctx, "b", data, opt)
+	_, err := s.Get(ctx, "a")
+	assert.NoError(t, err, "expected overwrite not count as new entry")
+
+	s.Set(ctx, "c", data, opt)
+	_, err = s.Get(ctx, "b")
+	assert.Equal(t, session.ErrNotFound, err)
+	_, err = s.Get(ctx, "a")
+	assert.NoError(t, err)
+
+	s.Del(ctx, "a")
+	s.Set(ctx, "d", data, opt)
+	_, err = s.Get(ctx, "c")
+	assert.NoError(t, err)
+}
+
+func TestMemoryGCPause(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	var paused int32 = 1
+	s := (&Memory{
+		GCPause: func() bool { return atomic.LoadInt32(&paused) == 1 },
+	}).GCEvery(5 * time.Millisecond)
+
+	s.Set(ctx, "a", session.Data{"test": "123"}, session.StoreOption{TTL: time.Millisecond})
+	time.Sleep(20 * time.Millisecond)
+
+	s.m.RLock()
+	assert.Len(t, s.l, 1, "expected gc paused")
+	s.m.RUnlock()
+
+	atomic.StoreInt32(&paused, 0)
+	time.Sleep(20 * time.Millisecond)
+
+	s.m.RLock()
+	assert.Empty(t, s.l, "expected gc resumed")
+	s.m.RUnlock()
+}
+
+func TestMemoryDelPrefix(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	s := new(Memory)
+	data := session.Data{"test": "123"}
+
+	s.Set(ctx, "tenant1:a", data, session.StoreOption{})
+	s.Set(ctx, "tenant1:b", data, session.StoreOption{})
+	s.Set(ctx, "tenant2:a", data, session.StoreOption{})
+
+	assert.NoError(t, s.DelPrefix(ctx, "tenant1:"))
+
+	_, err := s.Get(ctx, "tenant1:a")
+	assert.Equal(t, session.ErrNotFound, err)
+	_, err = s.Get(ctx, "tenant1:b")
+	assert.Equal(t, session.ErrNotFound, err)
+	_, err = s.Get(ctx, "tenant2:a")
+	assert.NoError(t, err)
+}
+
+func TestMemoryTouch(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	s := new(Memory)
+	s.Set(ctx, "a", session.Data{"test": "123"}, session.StoreOption{TTL: 10 * time.Millisecond})
+	assert.NoError(t, s.Touch(ctx, "a", time.Second))
+	assert.NoError(t, s.Touch(ctx, "b", time.Second), "expected touch not exists key not error")
+
+	time.Sleep(20 * time.Millisecond)
+	_, err := s.Get(ctx, "a")
+	assert.NoError(t, err, "expected ttl renewed")
+
+	s.Set(ctx, "c", session.Data{"test": "123"}, session.StoreOption{TTL: time.Millisecond})
+	time.Sleep(5 * time.Millisecond)
+	assert.Equal(t, session.ErrNotFound, s.Touch(ctx, "c", time.Second), "expected expired session not renewed")
+	_, err = s.Get(ctx, "c")
+	assert.Equal(t, session.ErrNotFound, err)
+
+	// touch marks session as recently used
+	s = &Memory{MaxEntries: 2}
+	s.Set(ctx, "a", session.Data{}, session.StoreOption{})
+	s.Set(ctx, "b", session.Data{}, session.StoreOption{})
+	assert.NoError(t, s.Touch(ctx, "a", time.Second))
+	s.Set(ctx, "c", session.Data{}, session.StoreOption{})
+	_, err = s.Get(ctx, "a")
+	assert.NoError(t, err)
+	_, err = s.Get(ctx, "b")
+	assert.Equal(t, session.ErrNotFound, err, "expected least recently used session evicted")
+}
+
+func TestMemoryGCBatch(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	s := &Memory{GCBatchSize: 10}
+	for i := 0; i < 25; i++ {
+		s.Set(ctx, fmt.Sprintf("k%d", i), session.Data{}, session.StoreOption{TTL: time.Millisecond})
+	}
+	s.Set(ctx, "alive", session.Data{}, session.StoreOption{TTL: time.Second})
+	time.Sleep(5 * time.Millisecond)
+
+	assert.Equal(t, 10, s.gcBatch(10))
+
+	s.GC()
+	s.m.RLock()
+	assert.Len(t, s.l, 1)
+	s.m.RUnlock()
+}
+
+func TestMemoryUserSessions(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	s := new(Memory)
+
+	s.Set(ctx, "a", session.Data{"k": 1}, session.StoreOption{})
+	s.Set(ctx, "b", session.Data{"k": 1}, session.StoreOption{TTL: time.Millisecond})
+	s.Set(ctx, "c", session.Data{"k": 1}, session.StoreOption{})
+
+	s.AddUserSession(ctx, "u1", "a")
+	s.AddUserSession(ctx, "u1", "b")
+	s.AddUserSession(ctx, "u1", "c")
+	s.AddUserSession(ctx, "u1", "a")
+
+	time.Sleep(2 * time.Millisecond)
+	keys, err := s.UserSessions(ctx, "u1")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"c", "a"}, keys, "expected expired session removed and re-added session newest")
+
+	s.DelUserSessions(ctx, "u1", []string{"c"})
+	keys, _ = s.UserSessions(ctx, "u1")
+	assert.Equal(t, []string{"a"}, keys)
+
+	s.Del(ctx, "a")
+	keys, _ = s.UserSessions(ctx, "u1")
+	assert.Empty(t, keys)
+}
+
+func TestMemoryCompareAndSet(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	s := new(Memory)
+	opt := session.StoreOption{}
+
+	assert.Equal(t, session.ErrConflict, s.CompareAndSet(ctx, "a", 1, session.Data{}, opt), "expected not exists session has version 0")
+	assert.NoError(t, s.CompareAndSet(ctx, "a", 0, session.Data{"_session/version": int64(1)}, opt))
+	assert.Equal(t, session.ErrConflict, s.CompareAndSet(ctx, "a", 0, session.Data{"_session/version": int64(1)}, opt))
+	assert.NoError(t, s.CompareAndSet(ctx, "a", 1, session.Data{"_session/version": int64(2), "k": "v"}, opt))
+
+	data, err := s.Get(ctx, "a")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), data.Version())
+	assert.Equal(t, "v", data["k"])
+}
@@ -0,0 +1,198 @@
+// Package metrics provides prometheus metrics for session store
+package metrics
+
+import (
+	"context"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/moonrhythm/session"
+)
+
+// Metrics collects prometheus metrics for wrapped store operations
+type Metrics struct {
+	Store session.Store
+
+	operations *prometheus.CounterVec
+	duration   *prometheus.HistogramVec
+}
+
+// metrics results
+const (
+	resultOK       = "ok"
+	resultHit      = "hit"
+	resultMiss     = "miss"
+	resultError    = "error"
+	resultConflict = "conflict"
+)
+
+// WithMetrics wraps store with prometheus metrics,
+// collectors will be registered into reg,
+// or reuse the already registered collectors
+func WithMetrics(inner session.Store, reg prometheus.Registerer) *Metrics {
+	operations := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "session",
+		Subsystem: "store",
+		Name:      "operations_total",
+		Help:      "Total number of session store operations",
+	}, []string{"operation", "result"})
+	duration := prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "session",
+		Subsystem: "store",
+		Name:      "operation_duration_seconds",
+		Help:      "Latency of session store operations",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"operation"})
+
+	return &Metrics{
+		Store:      inner,
+		operations: register(reg, operations).(*prometheus.CounterVec),
+		duration:   register(reg, duration).(*prometheus.HistogramVec),
+	}
+}
+
+func register(reg prometheus.Registerer, c prometheus.Collector) prometheus.Collector {
+	err := reg.Register(c)
+	if err == nil {
+		return c
+	}
+	if err, ok := err.(prometheus.AlreadyRegisteredError); ok {
+		return err.ExistingCollector
+	}
+	panic("store/metrics: " + err.Error())
+}
+
+func (s *Metrics) observe(op string, start time.Time, result string) {
+	s.duration.WithLabelValues(op).Observe(time.Since(start).Seconds())
+	s.operations.WithLabelValues(op, result).Inc()
+}
+
+func errorResult(err error) string {
+	if err != nil {
+		return resultError
+	}
+	return resultOK
+}
+
+// Get gets session data from wrapped store
+func (s *Metrics) Get(ctx context.Context, key string) (session.Data, error) {
+	start := time.Now()
+	data, err := s.Store.Get(ctx, key)
+	switch err {
+	case nil:
+		s.observe("get", start, resultHit)
+	case session.ErrNotFound:
+		s.observe("get", start, resultMiss)
+	default:
+		s.observe("get", start, resultError)
+	}
+	return data, err
+}
+
+// Set sets session data to wrapped store
+func (s *Metrics) Set(ctx context.Context, key string, value session.Data, opt session.StoreOption) error {
+	start := time.Now()
+	err := s.Store.Set(ctx, key, value, opt)
+	s.observe("set", start, errorResult(err))
+	return err
+}
+
+// Del deletes session data from wrapped store
+func (s *Metrics) Del(ctx context.Context, key string) error {
+	start := time.Now()
+	err := s.Store.Del(ctx, key)
+	s.observe("del", start, errorResult(err))
+	return err
+}
+
+// GC runs gc on wrapped store if supported
+func (s *Metrics) GC() error {
+	start := time.Now()
+	var err error
+	switch st := s.Store.(type) {
+	case interface{ GC() error }:
+		err = st.GC()
+	case interface{ GC() }:
+		st.GC()
+	default:
+		return session.ErrNotSupported
+	}
+	s.observe("gc", start, errorResult(err))
+	return err
+}
+
+// DelPrefix deletes session data by key prefix from wrapped store
+func (s *Metrics) DelPrefix(ctx context.Context, prefix string) error {
+	st, ok := s.Store.(session.PrefixDeleter)
+	if !ok {
+		return session.ErrNotSupported
+	}
+	start := time.Now()
+	err := st.DelPrefix(ctx, prefix)
+	s.observe("del_prefix", start, errorResult(err))
+	return err
+}
+
+// Touch renews session data ttl in wrapped store
+func (s *Metrics) Touch(ctx context.Context, key string, ttl time.Duration) error {
+	st, ok := s.Store.(session.Toucher)
+	if !ok {
+		return session.ErrNotSupported
+	}
+	start := time.Now()
+	err := st.Touch(ctx, key, ttl)
+	s.observe("touch", start, errorResult(err))
+	return err
+}
+
+// GetMulti gets multiple sessions data from wrapped store
+func (s *Metrics) GetMulti(ctx context.Context, keys []string) (map[string]session.Data, error) {
+	st, ok := s.Store.(session.BatchStore)
+	if !ok {
+		return nil, session.ErrNotSupported
+	}
+	start := time.Now()
+	r, err := st.GetMulti(ctx, keys)
+	if err != session.ErrNotSupported {
+		s.observe("get_multi", start, errorResult(err))
+	}
+	return r, err
+}
+
+// SetMulti sets multiple sessions data to wrapped store
+func (s *Metrics) SetMulti(ctx context.Context, values map[string]session.Data, opt session.StoreOption) error {
+	st, ok := s.Store.(session.BatchStore)
+	if !ok {
+		return session.ErrNotSupported
+	}
+	start := time.Now()
+	err := st.SetMulti(ctx, values, opt)
+	if err != session.ErrNotSupported {
+		s.observe("set_multi", start, errorResult(err))
+	}
+	return err
+}
+
+// CompareAndSet sets session data to wrapped store if version matches
+func (s *Metrics) CompareAndSet(ctx context.Context, key string, version int64, value session.Data, opt session.StoreOption) error {
+	st, ok := s.Store.(session.CASStore)
+	if !ok {
+		return session.ErrNotSupported
+	}
+	start := time.Now()
+	err := st.CompareAndSet(ctx, key, version, value, opt)
+	switch err {
+	case session.ErrNotSupported:
+	case session.ErrConflict:
+		s.observe("compare_and_set", start, resultConflict)
+	default:
+		s.observe("compare_and_set", start, errorResult(err))
+	}
+	return err
+}
+
+// Unwrap returns wrapped store
+func (s *Metrics) Unwrap() session.Store {
+	return s.Store
+}
@@ -0,0 +1,66 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/moonrhythm/session"
+	"github.com/moonrhythm/session/store"
+	"github.com/moonrhythm/session/store/mock"
+)
+
+func TestMetrics(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	reg := prometheus.NewRegistry()
+	s := WithMetrics(new(store.Memory), reg)
+
+	s.Get(ctx, "a")
+	s.Set(ctx, "a", session.Data{"test": "123"}, session.StoreOption{})
+	s.Get(ctx, "a")
+	s.Del(ctx, "a")
+	assert.NoError(t, s.GC())
+
+	assert.Equal(t, float64(1), testutil.ToFloat64(s.operations.WithLabelValues("get", "miss")))
+	assert.Equal(t, float64(1), testutil.ToFloat64(s.operations.WithLabelValues("get", "hit")))
+	assert.Equal(t, float64(1), testutil.ToFloat64(s.operations.WithLabelValues("set", "ok")))
+	assert.Equal(t, float64(1), testutil.ToFloat64(s.operations.WithLabelValues("del", "ok")))
+	assert.Equal(t, float64(1), testutil.ToFloat64(s.operations.WithLabelValues("gc", "ok")))
+
+	// wrap another store with the same registry
+	p := WithMetrics(&mock.Store{DelFunc: func(ctx context.Context, key string) error {
+		return errors.New("error")
+	}}, reg)
+	p.Del(ctx, "a")
+	assert.Equal(t, float64(1), testutil.ToFloat64(s.operations.WithLabelValues("del", "error")))
+	assert.Equal(t, session.ErrNotSupported, p.GC())
+}
+
+func TestMetricsForward(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	s := WithMetrics(new(store.Memory), prometheus.NewRegistry())
+
+	assert.NoError(t, s.SetMulti(ctx, map[string]session.Data{"a": {}}, session.StoreOption{}))
+	_, err := s.GetMulti(ctx, []string{"a"})
+	assert.NoError(t, err)
+	assert.NoError(t, s.CompareAndSet(ctx, "b", 0, session.Data{"_session/version": int64(1)}, session.StoreOption{}))
+	assert.Equal(t, session.ErrConflict, s.CompareAndSet(ctx, "b", 0, session.Data{}, session.StoreOption{}))
+
+	assert.Equal(t, float64(1), testutil.ToFloat64(s.operations.WithLabelValues("set_multi", "ok")))
+	assert.Equal(t, float64(1), testutil.ToFloat64(s.operations.WithLabelValues("get_multi", "ok")))
+	assert.Equal(t, float64(1), testutil.ToFloat64(s.operations.WithLabelValues("compare_and_set", "ok")))
+	assert.Equal(t, float64(1), testutil.ToFloat64(s.operations.WithLabelValues("compare_and_set", "conflict")))
+
+	s.Store = &mock.Store{}
+	assert.Equal(t, session.ErrNotSupported, s.CompareAndSet(ctx, "b", 0, session.Data{}, session.StoreOption{}))
+}
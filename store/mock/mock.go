@@ -0,0 +1,89 @@
+// Package mock provides mock store for testing
+package mock
+
+import (
+	"context"
+	"sync"
+
+	"github.com/moonrhythm/session"
+)
+
+// Call is the recorded store call
+type Call struct {
+	Method string // Get, Set or Del
+	Key    string
+	Value  session.Data        // for Set
+	Option session.StoreOption // for Set
+}
+
+// Store is the mock store,
+// nil function field returns zero value
+type Store struct {
+	GetFunc func(ctx context.Context, key string) (session.Data, error)
+	SetFunc func(ctx context.Context, key string, value session.Data, opt session.StoreOption) error
+	DelFunc func(ctx context.Context, key string) error
+
+	m     sync.Mutex
+	calls []Call
+}
+
+func (s *Store) record(c Call) {
+	s.m.Lock()
+	s.calls = append(s.calls, c)
+	s.m.Unlock()
+}
+
+// Calls returns recorded calls
+func (s *Store) Calls() []Call {
+	s.m.Lock()
+	defer s.m.Unlock()
+	return append([]Call(nil), s.calls...)
+}
+
+// CallCount returns number of recorded calls for given method
+func (s *Store) CallCount(method string) int {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	n := 0
+	for _, c := range s.calls {
+		if c.Method == method {
+			n++
+		}
+	}
+	return n
+}
+
+// Reset clears recorded calls
+func (s *Store) Reset() {
+	s.m.Lock()
+	s.calls = nil
+	s.m.Unlock()
+}
+
+// Get implements session.Store
+func (s *Store) Get(ctx context.Context, key string) (session.Data, error) {
+	s.record(Call{Method: "Get", Key: key})
+	if s.GetFunc == nil {
+		return nil, nil
+	}
+	return s.GetFunc(ctx, key)
+}
+
+// Set implements session.Store
+func (s *Store) Set(ctx context.Context, key string, value session.Data, opt session.StoreOption) error {
+	s.record(Call{Method: "Set", Key: key, Value: value, Option: opt})
+	if s.SetFunc == nil {
+		return nil
+	}
+	return s.SetFunc(ctx, key, value, opt)
+}
+
+// Del implements session.Store
+func (s *Store) Del(ctx context.Context, key string) error {
+	s.record(Call{Method: "Del", Key: key})
+	if s.DelFunc == nil {
+		return nil
+	}
+	return s.DelFunc(ctx, key)
+}
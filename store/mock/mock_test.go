@@ -0,0 +1,42 @@
+package mock
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/moonrhythm/session"
+)
+
+func TestStore(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	s := &Store{
+		GetFunc: func(ctx context.Context, key string) (session.Data, error) {
+			return nil, session.ErrNotFound
+		},
+		DelFunc: func(ctx context.Context, key string) error {
+			return fmt.Errorf("error")
+		},
+	}
+
+	_, err := s.Get(ctx, "a")
+	assert.Equal(t, session.ErrNotFound, err)
+	assert.NoError(t, s.Set(ctx, "a", session.Data{"k": 1}, session.StoreOption{}))
+	assert.Error(t, s.Del(ctx, "a"))
+
+	calls := s.Calls()
+	if assert.Len(t, calls, 3) {
+		assert.Equal(t, Call{Method: "Get", Key: "a"}, calls[0])
+		assert.Equal(t, session.Data{"k": 1}, calls[1].Value)
+		assert.Equal(t, "Del", calls[2].Method)
+	}
+	assert.Equal(t, 1, s.CallCount("Set"))
+
+	s.Reset()
+	assert.Empty(t, s.Calls())
+}
@@ -3,6 +3,7 @@ package store
 import (
 	"bytes"
 	"context"
+	"strings"
 	"time"
 
 	"github.com/gomodule/redigo/redis"
@@ -80,3 +81,113 @@ func (s *Redigo) Del(ctx context.Context, key string) error {
 	c.Close()
 	return err
 }
+
+// DelPrefix deletes all session data which key has the given prefix from redis
+func (s *Redigo) DelPrefix(ctx context.Context, prefix string) error {
+	c, err := s.Pool.GetContext(ctx)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	cursor := 0
+	for {
+		r, err := redis.Values(c.Do("SCAN", cursor, "MATCH", matchPrefix(s.Prefix+prefix), "COUNT", 100))
+		if err != nil {
+			return err
+		}
+		cursor, _ = redis.Int(r[0], nil)
+		keys, _ := redis.Values(r[1], nil)
+		if len(keys) > 0 {
+			_, err = c.Do("DEL", keys...)
+			if err != nil {
+				return err
+			}
+		}
+		if cursor == 0 {
+			return nil
+		}
+	}
+}
+
+// matchPrefix escapes prefix for redis SCAN MATCH pattern
+func matchPrefix(prefix string) string {
+	r := strings.NewReplacer(`\`, `\\`, `*`, `\*`, `?`, `\?`, `[`, `\[`, `]`, `\]`)
+	return r.Replace(prefix) + "*"
+}
+
+// Touch renews session data ttl in redis
+func (s *Redigo) Touch(ctx context.Context, key string, ttl time.Duration) error {
+	c, err := s.Pool.GetContext(ctx)
+	if err != nil {
+		return err
+	}
+	if ttl > 0 {
+		_, err = c.Do("EXPIRE", s.Prefix+key, int64(ttl/time.Second))
+	} else {
+		_, err = c.Do("PERSIST", s.Prefix+key)
+	}
+	c.Close()
+	return err
+}
+
+// GetMulti gets multiple sessions data from redis using MGET
+func (s *Redigo) GetMulti(ctx context.Context, keys []string) (map[string]session.Data, error) {
+	args := make([]interface{}, len(keys))
+	for i, key := range keys {
+		args[i] = s.Prefix + key
+	}
+
+	c, err := s.Pool.GetContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	values, err := redis.ByteSlices(c.Do("MGET", args...))
+	c.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	r := make(map[string]session.Data, len(keys))
+	for i, b := range values {
+		if b == nil {
+			// not found
+			continue
+		}
+
+		var sessData session.Data
+		err = s.coder().NewDecoder(bytes.NewReader(b)).Decode(&sessData)
+		if err != nil {
+			return nil, err
+		}
+		r[keys[i]] = sessData
+	}
+	return r, nil
+}
+
+// SetMulti sets multiple sessions data to redis in single pipeline
+func (s *Redigo) SetMulti(ctx context.Context, values map[string]session.Data, opt session.StoreOption) error {
+	c, err := s.Pool.GetContext(ctx)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	for key, value := range values {
+		var buf bytes.Buffer
+		err = s.coder().NewEncoder(&buf).Encode(value)
+		if err != nil {
+			return err
+		}
+		if opt.TTL > 0 {
+			err = c.Send("SETEX", s.Prefix+key, int64(opt.TTL/time.Second), buf.Bytes())
+		} else {
+			err = c.Send("SET", s.Prefix+key, buf.Bytes())
+		}
+		if err != nil {
+			return err
+		}
+	}
+	_, err = c.Do("")
+	return err
+}
@@ -73,3 +73,10 @@ func TestRedigoWithoutTTL(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, data, b)
 }
+
+func TestMatchPrefix(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, `session:*`, matchPrefix("session:"))
+	assert.Equal(t, `a\*b\?c\[d\]\\*`, matchPrefix(`a*b?c[d]\`))
+}
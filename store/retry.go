@@ -2,6 +2,8 @@ package store
 
 import (
 	"context"
+	"math"
+	"math/rand"
 	"time"
 
 	"github.com/moonrhythm/session"
@@ -11,51 +13,190 @@ import (
 type Retry struct {
 	Store       session.Store
 	MaxAttempts int
+
+	// Policy is the retry policy for all operations
+	Policy RetryPolicy
+
+	// GetPolicy, SetPolicy and DelPolicy override Policy for each operation
+	GetPolicy *RetryPolicy
+	SetPolicy *RetryPolicy
+	DelPolicy *RetryPolicy
+}
+
+// RetryPolicy configures retry attempts and exponential backoff
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of attempts,
+	// if zero, Retry.MaxAttempts or 3 will be used
+	MaxAttempts int
+
+	// BaseDelay is the delay after the first failed attempt,
+	// the delay doubles after each failed attempt,
+	// if zero, 100ms will be used
+	BaseDelay time.Duration
+
+	// MaxDelay caps the delay, zero means no cap
+	MaxDelay time.Duration
+
+	// DisableJitter disables randomize delay
+	DisableJitter bool
+}
+
+// WithRetry wraps store with retry policy
+func WithRetry(inner session.Store, policy RetryPolicy) *Retry {
+	return &Retry{Store: inner, Policy: policy}
 }
 
-func (s *Retry) maxAttempts() int {
+func (s *Retry) maxAttempts(p *RetryPolicy) int {
+	if p.MaxAttempts > 0 {
+		return p.MaxAttempts
+	}
 	if s.MaxAttempts <= 0 {
 		return 3
 	}
 	return s.MaxAttempts
 }
 
-func (s *Retry) backOffDuration() time.Duration {
-	return 100 * time.Millisecond
+func (s *Retry) policy(p *RetryPolicy) *RetryPolicy {
+	if p == nil {
+		return &s.Policy
+	}
+	return p
 }
 
-// Get gets session data from wrapped store with retry
-func (s *Retry) Get(ctx context.Context, key string) (r session.Data, err error) {
-	for i := 0; i < s.MaxAttempts; i++ {
-		r, err = s.Store.Get(ctx, key)
-		if err == nil || err == session.ErrNotFound {
+// backOffDuration returns the delay after given failed attempt (start from 0)
+func (p *RetryPolicy) backOffDuration(attempt int) time.Duration {
+	d := p.BaseDelay
+	if d <= 0 {
+		d = 100 * time.Millisecond
+	}
+	for i := 0; i < attempt; i++ {
+		if d > math.MaxInt64/2 {
+			// prevent overflow when MaxDelay is not set
+			d = math.MaxInt64
+			break
+		}
+		d *= 2
+		if p.MaxDelay > 0 && d >= p.MaxDelay {
 			break
 		}
-		time.Sleep(s.backOffDuration())
 	}
-	return
+	if p.MaxDelay > 0 && d > p.MaxDelay {
+		d = p.MaxDelay
+	}
+	if !p.DisableJitter {
+		// equal jitter, delay in [d/2, d)
+		d = d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+	}
+	return d
 }
 
-// Set sets session data to wrapped store with retry
-func (s *Retry) Set(ctx context.Context, key string, value session.Data, opt session.StoreOption) (err error) {
-	for i := 0; i < s.MaxAttempts; i++ {
-		err = s.Store.Set(ctx, key, value, opt)
-		if err == nil {
+// retryable checks is error transient
+func retryable(err error) bool {
+	return err != nil && err != session.ErrNotFound && err != session.ErrNotSupported && err != session.ErrConflict
+}
+
+func (s *Retry) do(ctx context.Context, p *RetryPolicy, f func() error) (err error) {
+	p = s.policy(p)
+	n := s.maxAttempts(p)
+	for i := 0; i < n; i++ {
+		err = f()
+		if !retryable(err) || i == n-1 {
 			break
 		}
-		time.Sleep(s.backOffDuration())
+
+		t := time.NewTimer(p.backOffDuration(i))
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return
+		case <-t.C:
+		}
 	}
 	return
 }
 
+// Get gets session data from wrapped store with retry
+func (s *Retry) Get(ctx context.Context, key string) (r session.Data, err error) {
+	err = s.do(ctx, s.GetPolicy, func() (err error) {
+		r, err = s.Store.Get(ctx, key)
+		return
+	})
+	return
+}
+
+// Set sets session data to wrapped store with retry
+func (s *Retry) Set(ctx context.Context, key string, value session.Data, opt session.StoreOption) error {
+	return s.do(ctx, s.SetPolicy, func() error {
+		return s.Store.Set(ctx, key, value, opt)
+	})
+}
+
 // Del deletes session data from wrapped store with retry
-func (s *Retry) Del(ctx context.Context, key string) (err error) {
-	for i := 0; i < s.MaxAttempts; i++ {
-		err = s.Store.Del(ctx, key)
-		if err == nil {
-			break
-		}
-		time.Sleep(s.backOffDuration())
+func (s *Retry) Del(ctx context.Context, key string) error {
+	return s.do(ctx, s.DelPolicy, func() error {
+		return s.Store.Del(ctx, key)
+	})
+}
+
+// DelPrefix deletes session data by key prefix from wrapped store with retry
+func (s *Retry) DelPrefix(ctx context.Context, prefix string) error {
+	st, ok := s.Store.(session.PrefixDeleter)
+	if !ok {
+		return session.ErrNotSupported
+	}
+	return s.do(ctx, s.DelPolicy, func() error {
+		return st.DelPrefix(ctx, prefix)
+	})
+}
+
+// Touch renews session data ttl in wrapped store with retry
+func (s *Retry) Touch(ctx context.Context, key string, ttl time.Duration) error {
+	st, ok := s.Store.(session.Toucher)
+	if !ok {
+		return session.ErrNotSupported
 	}
+	return s.do(ctx, s.SetPolicy, func() error {
+		return st.Touch(ctx, key, ttl)
+	})
+}
+
+// GetMulti gets multiple sessions data from wrapped store with retry
+func (s *Retry) GetMulti(ctx context.Context, keys []string) (r map[string]session.Data, err error) {
+	st, ok := s.Store.(session.BatchStore)
+	if !ok {
+		return nil, session.ErrNotSupported
+	}
+	err = s.do(ctx, s.GetPolicy, func() (err error) {
+		r, err = st.GetMulti(ctx, keys)
+		return
+	})
 	return
 }
+
+// SetMulti sets multiple sessions data to wrapped store with retry
+func (s *Retry) SetMulti(ctx context.Context, values map[string]session.Data, opt session.StoreOption) error {
+	st, ok := s.Store.(session.BatchStore)
+	if !ok {
+		return session.ErrNotSupported
+	}
+	return s.do(ctx, s.SetPolicy, func() error {
+		return st.SetMulti(ctx, values, opt)
+	})
+}
+
+// CompareAndSet sets session data to wrapped store if version matches with retry,
+// conflict is not retried
+func (s *Retry) CompareAndSet(ctx context.Context, key string, version int64, value session.Data, opt session.StoreOption) error {
+	st, ok := s.Store.(session.CASStore)
+	if !ok {
+		return session.ErrNotSupported
+	}
+	return s.do(ctx, s.SetPolicy, func() error {
+		return st.CompareAndSet(ctx, key, version, value, opt)
+	})
+}
+
+// Unwrap returns wrapped store
+func (s *Retry) Unwrap() session.Store {
+	return s.Store
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 
@@ -81,3 +82,79 @@ func TestRetryFail(t *testing.T) {
 		assert.Error(t, err)
 	})
 }
+
+func TestRetryPolicy(t *testing.T) {
+	t.Run("BackOff", func(t *testing.T) {
+		p := RetryPolicy{BaseDelay: 10 * time.Millisecond, MaxDelay: 50 * time.Millisecond, DisableJitter: true}
+		assert.Equal(t, 10*time.Millisecond, p.backOffDuration(0))
+		assert.Equal(t, 20*time.Millisecond, p.backOffDuration(1))
+		assert.Equal(t, 40*time.Millisecond, p.backOffDuration(2))
+		assert.Equal(t, 50*time.Millisecond, p.backOffDuration(3))
+		assert.Equal(t, 50*time.Millisecond, p.backOffDuration(100))
+	})
+
+	t.Run("Many Attempts", func(t *testing.T) {
+		p := RetryPolicy{BaseDelay: 10 * time.Millisecond}
+		for _, attempt := range []int{30, 63, 64, 1000} {
+			assert.NotPanics(t, func() {
+				d := p.backOffDuration(attempt)
+				assert.True(t, d > 0, "expected delay not overflow")
+			})
+		}
+	})
+
+	t.Run("Jitter", func(t *testing.T) {
+		p := RetryPolicy{BaseDelay: 10 * time.Millisecond}
+		for i := 0; i < 100; i++ {
+			d := p.backOffDuration(1)
+			assert.True(t, d >= 10*time.Millisecond && d <= 20*time.Millisecond)
+		}
+	})
+
+	t.Run("Per Operation", func(t *testing.T) {
+		ctx := context.Background()
+		s := WithRetry(&mockStore{}, RetryPolicy{MaxAttempts: 1})
+		s.GetPolicy = &RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond}
+
+		_, err := s.Get(ctx, "")
+		assert.NoError(t, err)
+
+		s.Store = &mockStore{}
+		err = s.Set(ctx, "", session.Data{}, session.StoreOption{})
+		assert.Error(t, err)
+	})
+
+	t.Run("Context Canceled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		s := WithRetry(&mockStore{}, RetryPolicy{MaxAttempts: 3, BaseDelay: time.Hour})
+		err := s.Del(ctx, "")
+		assert.Error(t, err, "expected stop retry when context canceled")
+	})
+}
+
+func TestRetryForward(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	s := WithRetry(new(Memory), RetryPolicy{})
+	err := s.SetMulti(ctx, map[string]session.Data{"a": {"k": "1"}}, session.StoreOption{})
+	assert.NoError(t, err)
+
+	r, err := s.GetMulti(ctx, []string{"a", "b"})
+	assert.NoError(t, err)
+	assert.Len(t, r, 1)
+
+	err = s.CompareAndSet(ctx, "c", 0, session.Data{"_session/version": int64(1)}, session.StoreOption{})
+	assert.NoError(t, err)
+	err = s.CompareAndSet(ctx, "c", 0, session.Data{"_session/version": int64(1)}, session.StoreOption{})
+	assert.Equal(t, session.ErrConflict, err)
+
+	s = WithRetry(&mockStore{}, RetryPolicy{})
+	_, err = s.GetMulti(ctx, []string{"a"})
+	assert.Equal(t, session.ErrNotSupported, err)
+	err = s.CompareAndSet(ctx, "a", 0, session.Data{}, session.StoreOption{})
+	assert.Equal(t, session.ErrNotSupported, err)
+}
@@ -0,0 +1,41 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"github.com/moonrhythm/session"
+)
+
+// RevocationList keeps revoked session ids in store,
+// use with server store e.g. Memory or Redis, when session data is kept in cookie
+type RevocationList struct {
+	Store session.Store
+
+	// Prefix is the store key prefix, default is "revoked/"
+	Prefix string
+}
+
+func (s *RevocationList) key(id string) string {
+	if s.Prefix == "" {
+		return "revoked/" + id
+	}
+	return s.Prefix + id
+}
+
+// Revoke implements session.RevocationList
+func (s *RevocationList) Revoke(ctx context.Context, id string, ttl time.Duration) error {
+	return s.Store.Set(ctx, s.key(id), session.Data{"revoked": true}, session.StoreOption{TTL: ttl})
+}
+
+// Revoked implements session.RevocationList
+func (s *RevocationList) Revoked(ctx context.Context, id string) (bool, error) {
+	_, err := s.Store.Get(ctx, s.key(id))
+	if err == session.ErrNotFound {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
@@ -0,0 +1,33 @@
+package store
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRevocationList(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	l := &RevocationList{Store: new(Memory)}
+
+	revoked, err := l.Revoked(ctx, "a")
+	assert.NoError(t, err)
+	assert.False(t, revoked)
+
+	assert.NoError(t, l.Revoke(ctx, "a", time.Minute))
+	revoked, err = l.Revoked(ctx, "a")
+	assert.NoError(t, err)
+	assert.True(t, revoked)
+
+	revoked, _ = l.Revoked(ctx, "b")
+	assert.False(t, revoked)
+
+	assert.NoError(t, l.Revoke(ctx, "b", time.Millisecond))
+	time.Sleep(5 * time.Millisecond)
+	revoked, _ = l.Revoked(ctx, "b")
+	assert.False(t, revoked, "expected revocation expired with session")
+}
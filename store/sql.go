@@ -7,6 +7,7 @@ import (
 	"errors"
 	"fmt"
 	"log"
+	"strings"
 	"time"
 
 	"github.com/moonrhythm/session"
@@ -17,10 +18,54 @@ type SQL struct {
 	DB    *sql.DB
 	Coder session.StoreCoder
 
+	// ReadDB is the database handle for Get, e.g. read replica,
+	// if ReadDB is nil, DB will be used.
+	// Session not found in ReadDB, or ReadDB failed, is retried on DB,
+	// since replica may lag behind or be unavailable
+	ReadDB *sql.DB
+
 	SetStatement string
 	GetStatement string
 	DelStatement string
 	GCStatement  string
+
+	// GCBatchStatement deletes expired sessions in batch,
+	// receives batch size as the first argument,
+	// if empty, GCStatement is used to delete all expired sessions at once
+	GCBatchStatement string
+
+	// GCBatchSize is the number of expired sessions to delete per batch,
+	// default is 1000
+	GCBatchSize int
+
+	// GCLockStatement tries to acquire transaction level lock for gc,
+	// returns true if acquired, so only one instance runs gc at a time,
+	// if empty, gc runs without lock
+	GCLockStatement string
+
+	// DelPrefixStatement deletes sessions by key pattern,
+	// receives LIKE pattern as the first argument
+	DelPrefixStatement string
+
+	// TouchStatement updates session expire time,
+	// receives key and expire time as arguments
+	TouchStatement string
+
+	// CompactStatement runs table maintenance
+	CompactStatement string
+
+	// SizeStatement returns table size in bytes
+	SizeStatement string
+
+	// GCPause reports whether gc worker should skip the current round,
+	// e.g. when the database is under heavy load
+	GCPause func() bool
+
+	// GCMaxLatency skips gc round when average latency of Get and Set
+	// is higher than GCMaxLatency, zero means never skip
+	GCMaxLatency time.Duration
+
+	latency latency
 }
 
 const (
@@ -40,8 +85,25 @@ set value = excluded.value,
 	pgsqlGet = `select value from %s where id = $1 and (expires_at is null or expires_at > now())`
 	pgsqlDel = `delete from %s where id = $1`
 	pgsqlGC  = `delete from %s where expires_at <= now()`
+
+	pgsqlGCBatch = `delete from %s where id in (
+    select id from %s where expires_at <= now() limit $1
+)`
+	pgsqlGCLock = `select pg_try_advisory_xact_lock(hashtext('session_gc:%s'))`
+
+	pgsqlDelPrefix = `delete from %s where id like $1`
+	pgsqlTouch     = `update %s set expires_at = $2 where id = $1`
+	pgsqlCompact   = `vacuum %s`
+	pgsqlSize      = `select pg_total_relation_size('%s')`
 )
 
+func (s *SQL) readDB() *sql.DB {
+	if s.ReadDB == nil {
+		return s.DB
+	}
+	return s.ReadDB
+}
+
 func (s *SQL) coder() session.StoreCoder {
 	if s.Coder == nil {
 		return session.DefaultStoreCoder
@@ -63,16 +125,31 @@ func (s *SQL) GeneratePostgreSQLStatement(table string, initSchema bool) *SQL {
 	s.GetStatement = fmt.Sprintf(pgsqlGet, table)
 	s.DelStatement = fmt.Sprintf(pgsqlDel, table)
 	s.GCStatement = fmt.Sprintf(pgsqlGC, table)
+	s.GCBatchStatement = fmt.Sprintf(pgsqlGCBatch, table, table)
+	s.GCLockStatement = fmt.Sprintf(pgsqlGCLock, table)
+	s.DelPrefixStatement = fmt.Sprintf(pgsqlDelPrefix, table)
+	s.TouchStatement = fmt.Sprintf(pgsqlTouch, table)
+	s.CompactStatement = fmt.Sprintf(pgsqlCompact, table)
+	s.SizeStatement = fmt.Sprintf(pgsqlSize, table)
 	return s
 }
 
 // Get gets session data from sql db
 func (s *SQL) Get(ctx context.Context, key string) (session.Data, error) {
+	defer s.latency.observe(time.Now())
+
 	var b []byte
-	err := s.DB.QueryRowContext(ctx, s.GetStatement, key).Scan(&b)
+	err := s.readDB().QueryRowContext(ctx, s.GetStatement, key).Scan(&b)
+	if err != nil && s.ReadDB != nil {
+		// session may not be replicated yet, or replica is unavailable
+		err = s.DB.QueryRowContext(ctx, s.GetStatement, key).Scan(&b)
+	}
 	if errors.Is(err, sql.ErrNoRows) {
 		return nil, session.ErrNotFound
 	}
+	if err != nil {
+		return nil, err
+	}
 
 	var sessData session.Data
 	err = s.coder().NewDecoder(bytes.NewReader(b)).Decode(&sessData)
@@ -91,6 +168,8 @@ func (s *SQL) Set(ctx context.Context, key string, value session.Data, opt sessi
 	}
 
 	now := time.Now()
+	defer s.latency.observe(now)
+
 	var exp sql.NullTime
 	if opt.TTL > 0 {
 		exp.Valid = true
@@ -107,19 +186,153 @@ func (s *SQL) Del(ctx context.Context, key string) error {
 	return err
 }
 
-// GC runs gc
-func (s *SQL) GC() error {
-	_, err := s.DB.Exec(s.GCStatement)
+// Touch renews session data expire time in sql db
+func (s *SQL) Touch(ctx context.Context, key string, ttl time.Duration) error {
+	if s.TouchStatement == "" {
+		return session.ErrNotSupported
+	}
+
+	now := time.Now()
+	defer s.latency.observe(now)
+
+	var exp sql.NullTime
+	if ttl > 0 {
+		exp.Valid = true
+		exp.Time = now.Add(ttl)
+	}
+	_, err := s.DB.ExecContext(ctx, s.TouchStatement, key, exp)
 	return err
 }
 
+// DelPrefix deletes all session data which key has the given prefix from sql db
+func (s *SQL) DelPrefix(ctx context.Context, prefix string) error {
+	if s.DelPrefixStatement == "" {
+		return session.ErrNotSupported
+	}
+	_, err := s.DB.ExecContext(ctx, s.DelPrefixStatement, likePrefix(prefix))
+	return err
+}
+
+// likePrefix escapes prefix for LIKE pattern
+func likePrefix(prefix string) string {
+	r := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+	return r.Replace(prefix) + "%"
+}
+
+// GC runs gc,
+// if other instance holding gc lock, GC returns without running gc
+func (s *SQL) GC() error {
+	if s.GCBatchStatement == "" {
+		_, err := s.gc(s.GCStatement)
+		return err
+	}
+
+	batch := gcBatchSize(s.GCBatchSize)
+	for {
+		n, err := s.gc(s.GCBatchStatement, batch)
+		if err != nil {
+			return err
+		}
+		if n < int64(batch) {
+			return nil
+		}
+	}
+}
+
+// gc runs gc statement, returns number of deleted sessions
+func (s *SQL) gc(query string, args ...interface{}) (int64, error) {
+	if s.GCLockStatement == "" {
+		res, err := s.DB.Exec(query, args...)
+		if err != nil {
+			return 0, err
+		}
+		return res.RowsAffected()
+	}
+
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var locked bool
+	err = tx.QueryRow(s.GCLockStatement).Scan(&locked)
+	if err != nil {
+		return 0, err
+	}
+	if !locked {
+		return 0, nil
+	}
+
+	res, err := tx.Exec(query, args...)
+	if err != nil {
+		return 0, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	return n, tx.Commit()
+}
+
 func (s *SQL) gcWorker(d time.Duration) {
-	s.GC()
-	time.AfterFunc(d, func() { s.gcWorker(d) })
+	if !shouldPauseGC(s.GCPause, &s.latency, s.GCMaxLatency) {
+		s.GC()
+	}
+	time.AfterFunc(jitter(d), func() { s.gcWorker(d) })
 }
 
 // GCEvery runs gc every given duration
 func (s *SQL) GCEvery(d time.Duration) *SQL {
-	time.AfterFunc(d, func() { s.gcWorker(d) })
+	time.AfterFunc(jitter(d), func() { s.gcWorker(d) })
+	return s
+}
+
+// Compact runs table maintenance and returns reclaimed space in bytes
+//
+// for postgresql, plain vacuum makes space reusable but rarely returns
+// it to operating system, set CompactStatement to "vacuum full" to
+// reclaim all space (requires exclusive lock)
+func (s *SQL) Compact(ctx context.Context) (reclaimed int64, err error) {
+	if s.CompactStatement == "" {
+		return 0, session.ErrNotSupported
+	}
+
+	var before, after int64
+	if s.SizeStatement != "" {
+		err = s.DB.QueryRowContext(ctx, s.SizeStatement).Scan(&before)
+		if err != nil {
+			return
+		}
+	}
+
+	_, err = s.DB.ExecContext(ctx, s.CompactStatement)
+	if err != nil {
+		return
+	}
+
+	if s.SizeStatement != "" {
+		err = s.DB.QueryRowContext(ctx, s.SizeStatement).Scan(&after)
+		if err != nil {
+			return
+		}
+	}
+	reclaimed = before - after
+	return
+}
+
+func (s *SQL) compactWorker(d time.Duration) {
+	if !shouldPauseGC(s.GCPause, &s.latency, s.GCMaxLatency) {
+		_, err := s.Compact(context.Background())
+		if err != nil {
+			log.Printf("store/sql: compact error: %v", err)
+		}
+	}
+	time.AfterFunc(d, func() { s.compactWorker(d) })
+}
+
+// CompactEvery runs compact every given duration
+func (s *SQL) CompactEvery(d time.Duration) *SQL {
+	time.AfterFunc(d, func() { s.compactWorker(d) })
 	return s
 }
@@ -14,7 +14,7 @@ import (
 	"github.com/moonrhythm/session"
 )
 
-func openPostgreSQL(t *testing.T) *sql.DB {
+func openPostgreSQL(t *testing.T, params ...string) *sql.DB {
 	t.Helper()
 
 	host := os.Getenv("POSTGRES_HOST")
@@ -32,6 +32,9 @@ func openPostgreSQL(t *testing.T) *sql.DB {
 	password := os.Getenv("POSTGRES_PASSWORD")
 
 	dsn := fmt.Sprintf("postgres://%s:%s@%s:%s/postgres?sslmode=disable", user, password, host, port)
+	for _, p := range params {
+		dsn += "&" + p
+	}
 	db, err := sql.Open("postgres", dsn)
 	if err != nil {
 		t.Fatalf("can not open postgres database: %v", err)
@@ -107,3 +110,128 @@ func TestSQLWithoutTTL(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, data, b)
 }
+
+func TestSQLReadDB(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	db := openPostgreSQL(t)
+	defer db.Close()
+
+	// use another schema as replica which never receives writes
+	db.Exec(`create schema if not exists __sql_replica`)
+	readDB := openPostgreSQL(t, "search_path=__sql_replica")
+	defer readDB.Close()
+
+	db.Exec(`drop table if exists __sql_postgresql_read_db`)
+	readDB.Exec(`drop table if exists __sql_postgresql_read_db`)
+
+	s := (&SQL{DB: db, ReadDB: readDB}).
+		GeneratePostgreSQLStatement("__sql_postgresql_read_db", true)
+	replica := (&SQL{DB: readDB}).
+		GeneratePostgreSQLStatement("__sql_postgresql_read_db", true)
+
+	data := make(session.Data)
+	data["test"] = "123"
+
+	err := replica.Set(ctx, "a", data, session.StoreOption{})
+	assert.NoError(t, err)
+
+	b, err := s.Get(ctx, "a")
+	assert.NoError(t, err, "expected read from replica")
+	assert.Equal(t, data, b)
+
+	err = s.Set(ctx, "b", data, session.StoreOption{})
+	assert.NoError(t, err)
+
+	b, err = s.Get(ctx, "b")
+	assert.NoError(t, err, "expected fallback to primary when replica lags")
+	assert.Equal(t, data, b)
+
+	_, err = s.Get(ctx, "c")
+	assert.Equal(t, session.ErrNotFound, err)
+
+	// replica unavailable
+	down := openPostgreSQL(t, "search_path=__sql_replica")
+	down.Close()
+	s.ReadDB = down
+	b, err = s.Get(ctx, "b")
+	assert.NoError(t, err, "expected fallback to primary when replica failed")
+	assert.Equal(t, data, b)
+
+	// primary unavailable
+	s.DB = down
+	_, err = s.Get(ctx, "b")
+	assert.Error(t, err)
+	assert.NotEqual(t, session.ErrNotFound, err, "expected error returned")
+}
+
+func TestLikePrefix(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, `abc%`, likePrefix("abc"))
+	assert.Equal(t, `a\%b\_c\\%`, likePrefix(`a%b_c\`))
+}
+
+func TestSQLCompact(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	db := openPostgreSQL(t)
+	defer db.Close()
+
+	db.Exec(`drop table if exists __sql_postgresql_compact`)
+
+	s := (&SQL{DB: db}).
+		GeneratePostgreSQLStatement("__sql_postgresql_compact", true)
+
+	for i := 0; i < 100; i++ {
+		s.Set(ctx, fmt.Sprintf("k%d", i), session.Data{"test": "123"}, session.StoreOption{})
+		s.Del(ctx, fmt.Sprintf("k%d", i))
+	}
+
+	reclaimed, err := s.Compact(ctx)
+	assert.NoError(t, err)
+	assert.True(t, reclaimed >= 0)
+
+	_, err = (&SQL{DB: db}).Compact(ctx)
+	assert.Equal(t, session.ErrNotSupported, err)
+}
+
+func TestSQLGCLock(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	db := openPostgreSQL(t)
+	defer db.Close()
+
+	db.Exec(`drop table if exists __sql_postgresql_gc_lock`)
+
+	s := (&SQL{DB: db}).
+		GeneratePostgreSQLStatement("__sql_postgresql_gc_lock", true)
+
+	s.Set(ctx, "a", session.Data{"test": "123"}, session.StoreOption{TTL: time.Millisecond})
+	time.Sleep(10 * time.Millisecond)
+
+	// other instance holding the lock
+	tx, err := db.Begin()
+	if !assert.NoError(t, err) {
+		return
+	}
+	var locked bool
+	tx.QueryRow(s.GCLockStatement).Scan(&locked)
+	assert.True(t, locked)
+
+	assert.NoError(t, s.GC())
+	var cnt int
+	db.QueryRow(`select count(*) from __sql_postgresql_gc_lock`).Scan(&cnt)
+	assert.Equal(t, 1, cnt, "expected gc skipped while lock held")
+
+	tx.Rollback()
+	assert.NoError(t, s.GC())
+	db.QueryRow(`select count(*) from __sql_postgresql_gc_lock`).Scan(&cnt)
+	assert.Equal(t, 0, cnt)
+}
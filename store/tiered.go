@@ -0,0 +1,132 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"github.com/moonrhythm/session"
+)
+
+// DefaultTieredLocalTTL is the default ttl for session data in local store
+const DefaultTieredLocalTTL = time.Minute
+
+// Tiered composes a fast local store in front of a slow remote store,
+// writes go through both stores, reads try local store first.
+//
+// Local store is not invalidated when other instances update or delete
+// the session from remote store, so local data can be stale at most LocalTTL
+type Tiered struct {
+	Local  session.Store // e.g. Memory
+	Remote session.Store // e.g. SQL, Redis
+
+	// LocalTTL is the maximum ttl for session data in local store,
+	// zero means DefaultTieredLocalTTL
+	LocalTTL time.Duration
+}
+
+func (s *Tiered) localTTL() time.Duration {
+	if s.LocalTTL <= 0 {
+		return DefaultTieredLocalTTL
+	}
+	return s.LocalTTL
+}
+
+func (s *Tiered) localOption(opt session.StoreOption) session.StoreOption {
+	if ttl := s.localTTL(); opt.TTL <= 0 || opt.TTL > ttl {
+		opt.TTL = ttl
+	}
+	return opt
+}
+
+// Get gets session data from local store,
+// if not found gets from remote store then save to local store
+func (s *Tiered) Get(ctx context.Context, key string) (session.Data, error) {
+	data, err := s.Local.Get(ctx, key)
+	if err == nil {
+		return data, nil
+	}
+
+	data, err = s.Remote.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+
+	// remote store does not tell remaining ttl,
+	// so local copy lives at most LocalTTL
+	s.Local.Set(ctx, key, data, session.StoreOption{TTL: s.localTTL()})
+	return data, nil
+}
+
+// Set sets session data to remote store then local store
+func (s *Tiered) Set(ctx context.Context, key string, value session.Data, opt session.StoreOption) error {
+	err := s.Remote.Set(ctx, key, value, opt)
+	if err != nil {
+		// remove stale local data
+		s.Local.Del(ctx, key)
+		return err
+	}
+	return s.Local.Set(ctx, key, value, s.localOption(opt))
+}
+
+// CompareAndSet sets session data to remote store if version matches, then to local store
+func (s *Tiered) CompareAndSet(ctx context.Context, key string, version int64, value session.Data, opt session.StoreOption) error {
+	st, ok := s.Remote.(session.CASStore)
+	if !ok {
+		return session.ErrNotSupported
+	}
+	err := st.CompareAndSet(ctx, key, version, value, opt)
+	if err != nil {
+		// remove stale local data
+		s.Local.Del(ctx, key)
+		return err
+	}
+	return s.Local.Set(ctx, key, value, s.localOption(opt))
+}
+
+// Unwrap returns remote store, local store is cache of remote store
+func (s *Tiered) Unwrap() session.Store {
+	return s.Remote
+}
+
+// Del deletes session data from both stores
+func (s *Tiered) Del(ctx context.Context, key string) error {
+	err := s.Local.Del(ctx, key)
+	if err != nil {
+		return err
+	}
+	return s.Remote.Del(ctx, key)
+}
+
+// DelPrefix deletes all session data which key has the given prefix from both stores
+func (s *Tiered) DelPrefix(ctx context.Context, prefix string) error {
+	local, ok := s.Local.(session.PrefixDeleter)
+	if !ok {
+		return session.ErrNotSupported
+	}
+	remote, ok := s.Remote.(session.PrefixDeleter)
+	if !ok {
+		return session.ErrNotSupported
+	}
+	err := local.DelPrefix(ctx, prefix)
+	if err != nil {
+		return err
+	}
+	return remote.DelPrefix(ctx, prefix)
+}
+
+// Touch renews session data ttl in both stores
+func (s *Tiered) Touch(ctx context.Context, key string, ttl time.Duration) error {
+	local, ok := s.Local.(session.Toucher)
+	if !ok {
+		return session.ErrNotSupported
+	}
+	remote, ok := s.Remote.(session.Toucher)
+	if !ok {
+		return session.ErrNotSupported
+	}
+	err := remote.Touch(ctx, key, ttl)
+	if err != nil {
+		return err
+	}
+	return local.Touch(ctx, key, s.localOption(session.StoreOption{TTL: ttl}).TTL)
+}
@@ -0,0 +1,56 @@
+package store
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/moonrhythm/session"
+)
+
+func TestTiered(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	local := new(Memory)
+	remote := new(Memory)
+	s := &Tiered{Local: local, Remote: remote, LocalTTL: 20 * time.Millisecond}
+
+	data := session.Data{"test": "123"}
+
+	err := s.Set(ctx, "a", data, session.StoreOption{TTL: time.Second})
+	assert.NoError(t, err)
+
+	b, err := local.Get(ctx, "a")
+	assert.NoError(t, err, "expected write through to local store")
+	assert.Equal(t, data, b)
+
+	time.Sleep(30 * time.Millisecond)
+	_, err = local.Get(ctx, "a")
+	assert.Error(t, err, "expected local data expired by local ttl")
+
+	b, err = s.Get(ctx, "a")
+	assert.NoError(t, err)
+	assert.Equal(t, data, b)
+
+	_, err = local.Get(ctx, "a")
+	assert.NoError(t, err, "expected remote data cached in local store")
+
+	s.Del(ctx, "a")
+	_, err = local.Get(ctx, "a")
+	assert.Error(t, err)
+	_, err = s.Get(ctx, "a")
+	assert.Equal(t, session.ErrNotFound, err)
+}
+
+func TestTieredDefaultLocalTTL(t *testing.T) {
+	t.Parallel()
+
+	s := &Tiered{Local: new(Memory), Remote: new(Memory)}
+	assert.Equal(t, DefaultTieredLocalTTL, s.localOption(session.StoreOption{}).TTL, "expected local data not kept forever")
+	assert.Equal(t, DefaultTieredLocalTTL, s.localOption(session.StoreOption{TTL: time.Hour}).TTL)
+	assert.Equal(t, time.Second, s.localOption(session.StoreOption{TTL: time.Second}).TTL)
+}
@@ -0,0 +1,164 @@
+// Package tracing provides opentelemetry tracing for session store
+package tracing
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/moonrhythm/session"
+)
+
+const tracerName = "github.com/moonrhythm/session/store/tracing"
+
+// Tracing creates opentelemetry spans for wrapped store operations
+type Tracing struct {
+	Store session.Store
+
+	// Tracer is the tracer to create spans,
+	// if nil, tracer from global tracer provider will be used
+	Tracer trace.Tracer
+}
+
+// WithTracing wraps store with opentelemetry tracing
+func WithTracing(inner session.Store) *Tracing {
+	return &Tracing{Store: inner}
+}
+
+func (s *Tracing) tracer() trace.Tracer {
+	if s.Tracer == nil {
+		return otel.Tracer(tracerName)
+	}
+	return s.Tracer
+}
+
+// keyHash hashes store key, to not leak session key to tracing backend
+func keyHash(key string) string {
+	h := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(h[:8])
+}
+
+func (s *Tracing) start(ctx context.Context, op string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return s.tracer().Start(ctx, "session.store."+op,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attrs...),
+	)
+}
+
+func endSpan(span trace.Span, err error) {
+	if err != nil && err != session.ErrNotFound {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// Get gets session data from wrapped store
+func (s *Tracing) Get(ctx context.Context, key string) (session.Data, error) {
+	ctx, span := s.start(ctx, "get", attribute.String("session.key_hash", keyHash(key)))
+	data, err := s.Store.Get(ctx, key)
+	span.SetAttributes(attribute.Bool("session.found", err == nil))
+	endSpan(span, err)
+	return data, err
+}
+
+// Set sets session data to wrapped store
+func (s *Tracing) Set(ctx context.Context, key string, value session.Data, opt session.StoreOption) error {
+	ctx, span := s.start(ctx, "set",
+		attribute.String("session.key_hash", keyHash(key)),
+		attribute.Int64("session.ttl_ms", opt.TTL.Milliseconds()),
+	)
+	err := s.Store.Set(ctx, key, value, opt)
+	endSpan(span, err)
+	return err
+}
+
+// Del deletes session data from wrapped store
+func (s *Tracing) Del(ctx context.Context, key string) error {
+	ctx, span := s.start(ctx, "del", attribute.String("session.key_hash", keyHash(key)))
+	err := s.Store.Del(ctx, key)
+	endSpan(span, err)
+	return err
+}
+
+// DelPrefix deletes session data by key prefix from wrapped store
+func (s *Tracing) DelPrefix(ctx context.Context, prefix string) error {
+	st, ok := s.Store.(session.PrefixDeleter)
+	if !ok {
+		return session.ErrNotSupported
+	}
+	ctx, span := s.start(ctx, "del_prefix")
+	err := st.DelPrefix(ctx, prefix)
+	endSpan(span, err)
+	return err
+}
+
+// Touch renews session data ttl in wrapped store
+func (s *Tracing) Touch(ctx context.Context, key string, ttl time.Duration) error {
+	st, ok := s.Store.(session.Toucher)
+	if !ok {
+		return session.ErrNotSupported
+	}
+	ctx, span := s.start(ctx, "touch",
+		attribute.String("session.key_hash", keyHash(key)),
+		attribute.Int64("session.ttl_ms", ttl.Milliseconds()),
+	)
+	err := st.Touch(ctx, key, ttl)
+	endSpan(span, err)
+	return err
+}
+
+// GetMulti gets multiple sessions data from wrapped store
+func (s *Tracing) GetMulti(ctx context.Context, keys []string) (map[string]session.Data, error) {
+	st, ok := s.Store.(session.BatchStore)
+	if !ok {
+		return nil, session.ErrNotSupported
+	}
+	ctx, span := s.start(ctx, "get_multi", attribute.Int("session.keys", len(keys)))
+	r, err := st.GetMulti(ctx, keys)
+	span.SetAttributes(attribute.Int("session.found", len(r)))
+	endSpan(span, err)
+	return r, err
+}
+
+// SetMulti sets multiple sessions data to wrapped store
+func (s *Tracing) SetMulti(ctx context.Context, values map[string]session.Data, opt session.StoreOption) error {
+	st, ok := s.Store.(session.BatchStore)
+	if !ok {
+		return session.ErrNotSupported
+	}
+	ctx, span := s.start(ctx, "set_multi",
+		attribute.Int("session.keys", len(values)),
+		attribute.Int64("session.ttl_ms", opt.TTL.Milliseconds()),
+	)
+	err := st.SetMulti(ctx, values, opt)
+	endSpan(span, err)
+	return err
+}
+
+// CompareAndSet sets session data to wrapped store if version matches
+func (s *Tracing) CompareAndSet(ctx context.Context, key string, version int64, value session.Data, opt session.StoreOption) error {
+	st, ok := s.Store.(session.CASStore)
+	if !ok {
+		return session.ErrNotSupported
+	}
+	ctx, span := s.start(ctx, "compare_and_set",
+		attribute.String("session.key_hash", keyHash(key)),
+		attribute.Int64("session.version", version),
+		attribute.Int64("session.ttl_ms", opt.TTL.Milliseconds()),
+	)
+	err := st.CompareAndSet(ctx, key, version, value, opt)
+	endSpan(span, err)
+	return err
+}
+
+// Unwrap returns wrapped store
+func (s *Tracing) Unwrap() session.Store {
+	return s.Store
+}
@@ -0,0 +1,56 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"github.com/moonrhythm/session"
+	"github.com/moonrhythm/session/store"
+	"github.com/moonrhythm/session/store/mock"
+)
+
+func TestTracing(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	sr := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(sr))
+
+	s := WithTracing(new(store.Memory))
+	s.Tracer = tp.Tracer("test")
+
+	s.Set(ctx, "a", session.Data{"test": "123"}, session.StoreOption{TTL: time.Second})
+	s.Get(ctx, "a")
+	s.Del(ctx, "a")
+
+	s.Store = &mock.Store{DelFunc: func(ctx context.Context, key string) error {
+		return errors.New("error")
+	}}
+	s.Del(ctx, "a")
+
+	spans := sr.Ended()
+	if assert.Len(t, spans, 4) {
+		assert.Equal(t, "session.store.set", spans[0].Name())
+		assert.Equal(t, "session.store.get", spans[1].Name())
+		assert.Equal(t, "session.store.del", spans[2].Name())
+		assert.Equal(t, codes.Error, spans[3].Status().Code)
+
+		for _, attr := range spans[0].Attributes() {
+			if attr.Key == "session.key_hash" {
+				assert.Equal(t, keyHash("a"), attr.Value.AsString())
+				assert.NotEqual(t, "a", attr.Value.AsString())
+			}
+			if attr.Key == "session.ttl_ms" {
+				assert.EqualValues(t, 1000, attr.Value.AsInt64())
+			}
+		}
+	}
+}
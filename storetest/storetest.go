@@ -0,0 +1,214 @@
+// Package storetest provides conformance tests for session store implementations
+//
+//	func TestMyStore(t *testing.T) {
+//		storetest.Run(t, func() session.Store {
+//			return &MyStore{...}
+//		})
+//	}
+package storetest
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/moonrhythm/session"
+)
+
+// Run runs conformance tests for the store created by newStore,
+// newStore is called once per test case
+func Run(t *testing.T, newStore func() session.Store) {
+	t.Helper()
+
+	tests := []struct {
+		name string
+		f    func(t *testing.T, s session.Store)
+	}{
+		{"NotFound", testNotFound},
+		{"SetGet", testSetGet},
+		{"Overwrite", testOverwrite},
+		{"Del", testDel},
+		{"TTL", testTTL},
+		{"WithoutTTL", testWithoutTTL},
+		{"Touch", testTouch},
+		{"Concurrent", testConcurrent},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			tc.f(t, newStore())
+		})
+	}
+}
+
+// key generates random key to not collide with other tests using the same backend
+func key() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return "storetest:" + hex.EncodeToString(b)
+}
+
+func testNotFound(t *testing.T, s session.Store) {
+	data, err := s.Get(context.Background(), key())
+	if err != session.ErrNotFound {
+		t.Errorf("expected ErrNotFound; got %v", err)
+	}
+	if data != nil {
+		t.Errorf("expected nil data; got %v", data)
+	}
+}
+
+func testSetGet(t *testing.T, s session.Store) {
+	ctx := context.Background()
+	k := key()
+
+	data := session.Data{
+		"string":  "text",
+		"int":     10,
+		"int64":   int64(10),
+		"float64": 1.5,
+		"bool":    true,
+		"bytes":   []byte("bytes"),
+	}
+	if err := s.Set(ctx, k, data, session.StoreOption{TTL: time.Minute}); err != nil {
+		t.Fatalf("expected set no error; got %v", err)
+	}
+
+	r, err := s.Get(ctx, k)
+	if err != nil {
+		t.Fatalf("expected get no error; got %v", err)
+	}
+	assertData(t, data, r)
+}
+
+func testOverwrite(t *testing.T, s session.Store) {
+	ctx := context.Background()
+	k := key()
+
+	s.Set(ctx, k, session.Data{"a": "1", "b": "2"}, session.StoreOption{TTL: time.Minute})
+	if err := s.Set(ctx, k, session.Data{"a": "3"}, session.StoreOption{TTL: time.Minute}); err != nil {
+		t.Fatalf("expected set no error; got %v", err)
+	}
+
+	r, err := s.Get(ctx, k)
+	if err != nil {
+		t.Fatalf("expected get no error; got %v", err)
+	}
+	assertData(t, session.Data{"a": "3"}, r)
+}
+
+func testDel(t *testing.T, s session.Store) {
+	ctx := context.Background()
+	k := key()
+
+	s.Set(ctx, k, session.Data{"a": "1"}, session.StoreOption{TTL: time.Minute})
+	if err := s.Del(ctx, k); err != nil {
+		t.Fatalf("expected del no error; got %v", err)
+	}
+	if _, err := s.Get(ctx, k); err != session.ErrNotFound {
+		t.Errorf("expected ErrNotFound after del; got %v", err)
+	}
+	if err := s.Del(ctx, k); err != nil {
+		t.Errorf("expected del not exists key no error; got %v", err)
+	}
+}
+
+func testTTL(t *testing.T, s session.Store) {
+	ctx := context.Background()
+	k := key()
+
+	s.Set(ctx, k, session.Data{"a": "1"}, session.StoreOption{TTL: time.Second})
+	if _, err := s.Get(ctx, k); err != nil {
+		t.Fatalf("expected get before expired no error; got %v", err)
+	}
+
+	time.Sleep(2 * time.Second)
+	if _, err := s.Get(ctx, k); err != session.ErrNotFound {
+		t.Errorf("expected ErrNotFound after expired; got %v", err)
+	}
+}
+
+func testWithoutTTL(t *testing.T, s session.Store) {
+	ctx := context.Background()
+	k := key()
+	defer s.Del(ctx, k)
+
+	s.Set(ctx, k, session.Data{"a": "1"}, session.StoreOption{})
+	time.Sleep(1100 * time.Millisecond)
+	if _, err := s.Get(ctx, k); err != nil {
+		t.Errorf("expected session without ttl not expired; got %v", err)
+	}
+}
+
+func testTouch(t *testing.T, s session.Store) {
+	st, ok := s.(session.Toucher)
+	if !ok {
+		t.Skip("store does not implement session.Toucher")
+	}
+
+	ctx := context.Background()
+	k := key()
+
+	s.Set(ctx, k, session.Data{"a": "1"}, session.StoreOption{TTL: time.Second})
+	err := st.Touch(ctx, k, 3*time.Second)
+	if err == session.ErrNotSupported {
+		t.Skip("wrapped store does not support touch")
+	}
+	if err != nil {
+		t.Fatalf("expected touch no error; got %v", err)
+	}
+
+	time.Sleep(2 * time.Second)
+	r, err := s.Get(ctx, k)
+	if err != nil {
+		t.Fatalf("expected touched session not expired; got %v", err)
+	}
+	assertData(t, session.Data{"a": "1"}, r)
+}
+
+func testConcurrent(t *testing.T, s session.Store) {
+	ctx := context.Background()
+	prefix := key()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			k := fmt.Sprintf("%s:%d", prefix, i)
+			data := session.Data{"i": i}
+			if err := s.Set(ctx, k, data, session.StoreOption{TTL: time.Minute}); err != nil {
+				t.Errorf("expected set no error; got %v", err)
+				return
+			}
+			r, err := s.Get(ctx, k)
+			if err != nil {
+				t.Errorf("expected get no error; got %v", err)
+				return
+			}
+			assertData(t, data, r)
+			s.Del(ctx, k)
+		}(i)
+	}
+	wg.Wait()
+}
+
+func assertData(t *testing.T, expected, actual session.Data) {
+	t.Helper()
+
+	if len(expected) != len(actual) {
+		t.Errorf("expected %d keys; got %d", len(expected), len(actual))
+	}
+	for k, v := range expected {
+		if fmt.Sprintf("%#v", actual[k]) != fmt.Sprintf("%#v", v) {
+			t.Errorf("expected %s to be %#v; got %#v", k, v, actual[k])
+		}
+	}
+}
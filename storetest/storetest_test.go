@@ -0,0 +1,43 @@
+package storetest_test
+
+import (
+	"testing"
+
+	"github.com/moonrhythm/session"
+	"github.com/moonrhythm/session/store"
+	"github.com/moonrhythm/session/storetest"
+)
+
+func TestMemory(t *testing.T) {
+	storetest.Run(t, func() session.Store {
+		return new(store.Memory)
+	})
+}
+
+func TestWrappers(t *testing.T) {
+	key := []byte("0123456789abcdef0123456789abcdef")
+
+	t.Run("Tiered", func(t *testing.T) {
+		storetest.Run(t, func() session.Store {
+			return &store.Tiered{Local: new(store.Memory), Remote: new(store.Memory)}
+		})
+	})
+
+	t.Run("Encryption", func(t *testing.T) {
+		storetest.Run(t, func() session.Store {
+			return store.WithEncryption(new(store.Memory), key)
+		})
+	})
+
+	t.Run("Compression", func(t *testing.T) {
+		storetest.Run(t, func() session.Store {
+			return store.WithCompression(new(store.Memory), store.Gzip{})
+		})
+	})
+
+	t.Run("Retry", func(t *testing.T) {
+		storetest.Run(t, func() session.Store {
+			return store.WithRetry(new(store.Memory), store.RetryPolicy{})
+		})
+	})
+}
@@ -0,0 +1,60 @@
+package session
+
+import (
+	"net/http"
+	"strings"
+)
+
+// Transport is the way session id is sent between client and server
+type Transport int
+
+// Transport values
+const (
+	CookieTransport Transport = iota // session id in cookie
+	BearerTransport                  // session id in Authorization: Bearer header, returned in TokenHeader
+)
+
+// DefaultTokenHeader is the default response header for bearer transport
+const DefaultTokenHeader = "X-Session-Token"
+
+// readIDValue reads session id value sent by client
+func (m *Manager) readIDValue(r *http.Request, name string) string {
+	if m.config.IDExtractor != nil {
+		return m.config.IDExtractor(r)
+	}
+	if m.config.Transport == BearerTransport {
+		auth := r.Header.Get("Authorization")
+		if len(auth) > 7 && strings.EqualFold(auth[:7], "bearer ") {
+			return strings.TrimSpace(auth[7:])
+		}
+		return ""
+	}
+
+	cookie, err := r.Cookie(m.cookieName(name))
+	if err != nil {
+		return ""
+	}
+	return cookie.Value
+}
+
+// writeIDValue sends session id value to client
+func (m *Manager) writeIDValue(w http.ResponseWriter, s *Session, value string) {
+	if m.config.IDWriter != nil {
+		m.config.IDWriter(w, s)
+		return
+	}
+	if m.config.Transport == BearerTransport {
+		w.Header().Set(m.tokenHeader(), value)
+		return
+	}
+
+	cs := m.cookie(s, value)
+	addCookie(w, &cs, s.Partitioned)
+}
+
+func (m *Manager) tokenHeader() string {
+	if m.config.TokenHeader == "" {
+		return DefaultTokenHeader
+	}
+	return m.config.TokenHeader
+}
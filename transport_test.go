@@ -0,0 +1,85 @@
+package session_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/moonrhythm/session"
+	"github.com/moonrhythm/session/store"
+)
+
+func TestBearerTransport(t *testing.T) {
+	t.Parallel()
+
+	h := session.Middleware(session.Config{
+		Store:     new(store.Memory),
+		Transport: session.BearerTransport,
+		Keys:      [][]byte{[]byte("key")},
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s, _ := session.Get(r.Context(), sessName)
+		cnt := s.GetInt("cnt") + 1
+		s.Set("cnt", cnt)
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte{byte('0' + cnt)})
+	}))
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	h.ServeHTTP(w, r)
+	assert.Empty(t, w.Result().Cookies(), "expected no cookie")
+	token := w.Header().Get(session.DefaultTokenHeader)
+	assert.NotEmpty(t, token)
+	assert.Equal(t, "1", w.Body.String())
+
+	w = httptest.NewRecorder()
+	r = httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("Authorization", "Bearer "+token)
+	h.ServeHTTP(w, r)
+	assert.Equal(t, "2", w.Body.String())
+	assert.Empty(t, w.Header().Get(session.DefaultTokenHeader), "expected token not resent")
+
+	// forged token
+	w = httptest.NewRecorder()
+	r = httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("Authorization", "Bearer invalid")
+	h.ServeHTTP(w, r)
+	assert.Equal(t, "1", w.Body.String())
+}
+
+func TestIDExtractorWriter(t *testing.T) {
+	t.Parallel()
+
+	h := session.Middleware(session.Config{
+		Store: new(store.Memory),
+		Keys:  [][]byte{[]byte("key")},
+		IDExtractor: func(r *http.Request) string {
+			return r.URL.Query().Get("sid")
+		},
+		IDWriter: func(w http.ResponseWriter, s *session.Session) {
+			w.Header().Set("Location", "/download?sid="+s.Token())
+		},
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s, _ := session.Get(r.Context(), sessName)
+		if r.URL.Path == "/sign" {
+			s.Set("file", "report.pdf")
+			w.WriteHeader(http.StatusFound)
+			return
+		}
+		w.Write([]byte(s.GetString("file")))
+	}))
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/sign", nil)
+	h.ServeHTTP(w, r)
+	assert.Empty(t, w.Result().Cookies())
+	loc := w.Header().Get("Location")
+	assert.Contains(t, loc, ".", "expected signed token")
+
+	w = httptest.NewRecorder()
+	r = httptest.NewRequest(http.MethodGet, loc, nil)
+	h.ServeHTTP(w, r)
+	assert.Equal(t, "report.pdf", w.Body.String())
+}
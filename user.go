@@ -0,0 +1,204 @@
+package session
+
+import (
+	"context"
+	"time"
+)
+
+// userIndexKey returns store key for user's session index
+func userIndexKey(userID string) string {
+	return userIndexPrefix + userID
+}
+
+// loadUserIndex loads store keys of user's sessions, oldest first,
+// sessions expired or deleted from store are excluded
+func loadUserIndex(ctx context.Context, st Store, userID string) ([]string, error) {
+	data, err := st.Get(ctx, userIndexKey(userID))
+	if err == ErrNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return pruneUserIndex(ctx, st, toStrings(data[userIndexSessionsKey]))
+}
+
+// pruneUserIndex returns only keys of sessions that still exist in store
+func pruneUserIndex(ctx context.Context, st Store, keys []string) ([]string, error) {
+	if len(keys) == 0 {
+		return nil, nil
+	}
+
+	r := make([]string, 0, len(keys))
+	if bs, ok := st.(BatchStore); ok {
+		found, err := bs.GetMulti(ctx, keys)
+		if err == nil {
+			for _, k := range keys {
+				if _, ok := found[k]; ok {
+					r = append(r, k)
+				}
+			}
+			return r, nil
+		}
+		if err != ErrNotSupported {
+			return nil, err
+		}
+	}
+
+	for _, k := range keys {
+		_, err := st.Get(ctx, k)
+		if err == ErrNotFound {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		r = append(r, k)
+	}
+	return r, nil
+}
+
+// saveUserIndex saves user's session index,
+// ttl must be at least session MaxAge, so the index does not expire before its sessions
+func saveUserIndex(ctx context.Context, st Store, userID string, keys []string, ttl time.Duration) error {
+	if len(keys) == 0 {
+		return st.Del(ctx, userIndexKey(userID))
+	}
+	return st.Set(ctx, userIndexKey(userID), Data{userIndexSessionsKey: keys}, StoreOption{TTL: ttl})
+}
+
+// delUserSession deletes session by store key,
+// and its copies saved with other secrets
+func delUserSession(ctx context.Context, st Store, key string) error {
+	data, err := st.Get(ctx, key)
+	if err == ErrNotFound {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	for _, k := range append(toStrings(data[aliasKey]), key) {
+		err = st.Del(ctx, k)
+		if err != nil && err != ErrNotFound {
+			return err
+		}
+	}
+	return nil
+}
+
+// userSessions returns store keys of user's sessions, oldest first
+func userSessions(ctx context.Context, st Store, userID string) ([]string, error) {
+	if idx, ok := st.(IndexedStore); ok {
+		return idx.UserSessions(ctx, userID)
+	}
+	return loadUserIndex(ctx, st, userID)
+}
+
+// removeUserSession removes store key from user's session index,
+// returns false if key not in the index
+func removeUserSession(ctx context.Context, st Store, userID string, key string, ttl time.Duration) (bool, error) {
+	keys, err := userSessions(ctx, st, userID)
+	if err != nil {
+		return false, err
+	}
+	r := make([]string, 0, len(keys))
+	for _, k := range keys {
+		if k != key {
+			r = append(r, k)
+		}
+	}
+	if len(r) == len(keys) {
+		return false, nil
+	}
+	if idx, ok := st.(IndexedStore); ok {
+		return true, idx.DelUserSessions(ctx, userID, []string{key})
+	}
+	return true, saveUserIndex(ctx, st, userID, r, ttl)
+}
+
+// AddUserSession adds session to user's session index in store,
+// call AddUserSession after login, and after regenerate session id,
+// the index expires after session MaxAge since the last call
+func AddUserSession(ctx context.Context, st Store, userID string, s *Session) error {
+	return LimitPerUser(ctx, st, userID, s, 0)
+}
+
+// DestroyAllForUser destroys all sessions in user's session index,
+// e.g. after password changed
+func DestroyAllForUser(ctx context.Context, st Store, userID string) error {
+	keys, err := userSessions(ctx, st, userID)
+	if err != nil {
+		return err
+	}
+	for _, k := range keys {
+		err = delUserSession(ctx, st, k)
+		if err != nil {
+			return err
+		}
+	}
+	if idx, ok := st.(IndexedStore); ok {
+		return idx.DelUserSessions(ctx, userID, keys)
+	}
+	return saveUserIndex(ctx, st, userID, nil, 0)
+}
+
+// LimitPerUser adds session to user's session index in store,
+// then destroys the oldest sessions of the user when user has more than max sessions
+//
+// Call LimitPerUser after login, and after regenerate session id;
+// concurrent logins of the same user may exceed max
+func LimitPerUser(ctx context.Context, st Store, userID string, s *Session, max int) error {
+	if idx, ok := st.(IndexedStore); ok {
+		return limitIndexedStore(ctx, st, idx, userID, s, max)
+	}
+
+	keys, err := loadUserIndex(ctx, st, userID)
+	if err != nil {
+		return err
+	}
+
+	id := s.ID()
+	for i, k := range keys {
+		if k == id {
+			keys = append(keys[:i], keys[i+1:]...)
+			break
+		}
+	}
+	keys = append(keys, id)
+
+	for max > 0 && len(keys) > max {
+		err = delUserSession(ctx, st, keys[0])
+		if err != nil {
+			return err
+		}
+		keys = keys[1:]
+	}
+	return saveUserIndex(ctx, st, userID, keys, s.MaxAge)
+}
+
+func limitIndexedStore(ctx context.Context, st Store, idx IndexedStore, userID string, s *Session, max int) error {
+	err := idx.AddUserSession(ctx, userID, s.ID())
+	if err != nil {
+		return err
+	}
+	if max <= 0 {
+		return nil
+	}
+
+	keys, err := idx.UserSessions(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if len(keys) <= max {
+		return nil
+	}
+	evict := keys[:len(keys)-max]
+	for _, k := range evict {
+		err = delUserSession(ctx, st, k)
+		if err != nil {
+			return err
+		}
+	}
+	return idx.DelUserSessions(ctx, userID, evict)
+}
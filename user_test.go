@@ -0,0 +1,208 @@
+package session_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/moonrhythm/session"
+	"github.com/moonrhythm/session/store"
+	"github.com/moonrhythm/session/store/mock"
+)
+
+// plainStore hides optional interfaces of wrapped store
+type plainStore struct {
+	session.Store
+}
+
+func userTestStores() map[string]session.Store {
+	return map[string]session.Store{
+		"IndexedStore": new(store.Memory),
+		"Store":        plainStore{new(store.Memory)},
+	}
+}
+
+func TestLimitPerUser(t *testing.T) {
+	t.Parallel()
+
+	for name, st := range userTestStores() {
+		st := st
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			m := session.New(session.Config{Store: st})
+
+			login := func() *session.Session {
+				r := httptest.NewRequest(http.MethodGet, "/", nil)
+				s, _ := m.Get(r, sessName)
+				s.Set("user", "u1")
+				assert.NoError(t, m.Save(ctx, httptest.NewRecorder(), s))
+				assert.NoError(t, session.LimitPerUser(ctx, st, "u1", s, 2))
+				return s
+			}
+
+			s1 := login()
+			s2 := login()
+			assert.NoError(t, session.LimitPerUser(ctx, st, "u1", s1, 2), "expected re-add moves session to newest")
+			s3 := login()
+
+			_, err := st.Get(ctx, s2.ID())
+			assert.Equal(t, session.ErrNotFound, err, "expected oldest session destroyed")
+			_, err = st.Get(ctx, s1.ID())
+			assert.NoError(t, err)
+			_, err = st.Get(ctx, s3.ID())
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestDestroyAllForUser(t *testing.T) {
+	t.Parallel()
+
+	for name, st := range userTestStores() {
+		st := st
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			m := session.New(session.Config{Store: st})
+
+			login := func(user string) *session.Session {
+				r := httptest.NewRequest(http.MethodGet, "/", nil)
+				s, _ := m.Get(r, sessName)
+				s.Set("user", user)
+				assert.NoError(t, m.Save(ctx, httptest.NewRecorder(), s))
+				assert.NoError(t, session.AddUserSession(ctx, st, user, s))
+				return s
+			}
+
+			s1 := login("u1")
+			s2 := login("u1")
+			s3 := login("u2")
+
+			assert.NoError(t, session.DestroyAllForUser(ctx, st, "u1"))
+			_, err := st.Get(ctx, s1.ID())
+			assert.Equal(t, session.ErrNotFound, err)
+			_, err = st.Get(ctx, s2.ID())
+			assert.Equal(t, session.ErrNotFound, err)
+			_, err = st.Get(ctx, s3.ID())
+			assert.NoError(t, err, "expected other user's session kept")
+
+			assert.NoError(t, session.DestroyAllForUser(ctx, st, "u1"), "expected destroy empty index")
+		})
+	}
+}
+
+func TestLimitPerUserPruneIndex(t *testing.T) {
+	t.Parallel()
+
+	for name, st := range userTestStores() {
+		st := st
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			m := session.New(session.Config{Store: st})
+
+			login := func() *session.Session {
+				r := httptest.NewRequest(http.MethodGet, "/", nil)
+				s, _ := m.Get(r, sessName)
+				s.Set("user", "u1")
+				assert.NoError(t, m.Save(ctx, httptest.NewRecorder(), s))
+				assert.NoError(t, session.LimitPerUser(ctx, st, "u1", s, 2))
+				return s
+			}
+
+			s1 := login()
+			s2 := login()
+			assert.NoError(t, st.Del(ctx, s2.ID()), "expected session removed from store, e.g. expired")
+			s3 := login()
+
+			_, err := st.Get(ctx, s1.ID())
+			assert.NoError(t, err, "expected removed session not counted")
+			_, err = st.Get(ctx, s3.ID())
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestUserIndexReservedKey(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	st := plainStore{new(store.Memory)}
+	m := session.New(session.Config{Store: st, DisableHashID: true})
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	s, _ := m.Get(r, sessName)
+	s.Set("user", "u1")
+	assert.NoError(t, m.Save(ctx, httptest.NewRecorder(), s))
+	assert.NoError(t, session.AddUserSession(ctx, st, "u1", s))
+
+	r = httptest.NewRequest(http.MethodGet, "/", nil)
+	r.AddCookie(&http.Cookie{Name: sessName, Value: "_session/user/u1"})
+	s, err := m.Get(r, sessName)
+	assert.NoError(t, err)
+	assert.True(t, s.IsNew(), "expected reserved store key not loaded as session")
+	assert.Nil(t, s.Get("sessions"))
+}
+
+func TestDestroyAllForUserOldSecret(t *testing.T) {
+	t.Parallel()
+
+	for name, st := range userTestStores() {
+		st := st
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+
+			// login before secret rotated
+			m := session.New(session.Config{Store: st, Secret: []byte("old")})
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			s, _ := m.Get(r, sessName)
+			s.Set("user", "u1")
+			w := httptest.NewRecorder()
+			assert.NoError(t, m.Save(ctx, w, s))
+			assert.NoError(t, session.AddUserSession(ctx, st, "u1", s))
+			oldKey := s.ID()
+
+			// session moved to the new secret
+			m = session.New(session.Config{Store: st, Secrets: [][]byte{[]byte("new"), []byte("old")}})
+			r = httptest.NewRequest(http.MethodGet, "/", nil)
+			r.Header.Set("Cookie", w.Header().Get("Set-Cookie"))
+			s, _ = m.Get(r, sessName)
+			assert.Equal(t, "u1", s.GetString("user"))
+			assert.NotEqual(t, oldKey, s.ID())
+			assert.NoError(t, m.Save(ctx, httptest.NewRecorder(), s))
+
+			assert.NoError(t, session.DestroyAllForUser(ctx, st, "u1"))
+			_, err := st.Get(ctx, oldKey)
+			assert.Equal(t, session.ErrNotFound, err)
+			_, err = st.Get(ctx, s.ID())
+			assert.Equal(t, session.ErrNotFound, err, "expected copy saved with new secret destroyed")
+		})
+	}
+}
+
+func TestUserIndexTTL(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	var ttl time.Duration
+	mem := new(store.Memory)
+	st := &mock.Store{
+		GetFunc: mem.Get,
+		SetFunc: func(ctx context.Context, key string, value session.Data, opt session.StoreOption) error {
+			if key == "_session/user/u1" {
+				ttl = opt.TTL
+			}
+			return mem.Set(ctx, key, value, opt)
+		},
+		DelFunc: mem.Del,
+	}
+	m := session.New(session.Config{Store: st, MaxAge: time.Hour})
+
+	s, _ := m.Get(httptest.NewRequest(http.MethodGet, "/", nil), sessName)
+	s.Set("user", "u1")
+	assert.NoError(t, m.Save(ctx, httptest.NewRecorder(), s))
+	assert.NoError(t, session.AddUserSession(ctx, st, "u1", s))
+	assert.Equal(t, time.Hour, ttl, "expected index expires with session")
+}
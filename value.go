@@ -0,0 +1,33 @@
+package session
+
+// Value gets typed value from session,
+// returns false if key not exists or value is not type T
+func Value[T any](s *Session, key string) (T, bool) {
+	r, ok := s.Get(key).(T)
+	return r, ok
+}
+
+// SetValue sets typed value to session
+func SetValue[T any](s *Session, key string, value T) {
+	s.Set(key, value)
+}
+
+// FlashValue gets typed value from flash then delete the key,
+// returns false if key not exists or value is not type T
+func FlashValue[T any](f *Flash, key string) (T, bool) {
+	r, ok := f.Get(key).(T)
+	return r, ok
+}
+
+// FlashValues gets typed values from flash then delete the key,
+// values that are not type T are skipped
+func FlashValues[T any](f *Flash, key string) []T {
+	vs := f.Values(key)
+	r := make([]T, 0, len(vs))
+	for _, v := range vs {
+		if x, ok := v.(T); ok {
+			r = append(r, x)
+		}
+	}
+	return r
+}
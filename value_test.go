@@ -0,0 +1,57 @@
+package session_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/moonrhythm/session"
+)
+
+func TestValue(t *testing.T) {
+	t.Parallel()
+
+	s := session.Session{}
+
+	v, ok := session.Value[int](&s, "a")
+	assert.False(t, ok)
+	assert.Equal(t, 0, v)
+
+	session.SetValue(&s, "a", 1)
+	assert.True(t, s.Changed())
+	v, ok = session.Value[int](&s, "a")
+	assert.True(t, ok)
+	assert.Equal(t, 1, v)
+
+	str, ok := session.Value[string](&s, "a")
+	assert.False(t, ok, "expected type drift returns false")
+	assert.Empty(t, str)
+
+	type user struct{ Name string }
+	session.SetValue(&s, "u", user{Name: "u1"})
+	u, ok := session.Value[user](&s, "u")
+	assert.True(t, ok)
+	assert.Equal(t, "u1", u.Name)
+}
+
+func TestFlashValue(t *testing.T) {
+	t.Parallel()
+
+	f := new(session.Flash)
+
+	v, ok := session.FlashValue[string](f, "a")
+	assert.False(t, ok)
+	assert.Empty(t, v)
+
+	f.Set("a", "hello")
+	v, ok = session.FlashValue[string](f, "a")
+	assert.True(t, ok)
+	assert.Equal(t, "hello", v)
+	assert.False(t, f.Has("a"), "expected value consumed")
+
+	f.Add(session.FlashError, "e1")
+	f.Add(session.FlashError, 2)
+	f.Add(session.FlashError, "e2")
+	assert.Equal(t, []string{"e1", "e2"}, session.FlashValues[string](f, session.FlashError))
+	assert.Empty(t, session.FlashValues[string](f, session.FlashError))
+}
@@ -2,9 +2,13 @@ package session
 
 import (
 	"bufio"
+	"context"
 	"net"
 	"net/http"
+	"net/http/httptest"
 	"testing"
+
+	"github.com/stretchr/testify/assert"
 )
 
 type fakeWriter struct{}
@@ -33,7 +37,10 @@ func TestWriter(t *testing.T) {
 	t.Parallel()
 
 	// empty response writer
-	w := scopedManager{}
+	w := scopedManager{
+		Manager: &Manager{},
+		r:       httptest.NewRequest(http.MethodGet, "/", nil),
+	}
 	w.Push("", nil)
 	w.Flush()
 	w.Hijack()
@@ -43,3 +50,44 @@ func TestWriter(t *testing.T) {
 	w.Flush()
 	w.Hijack()
 }
+
+func TestWriterFlushSaveSession(t *testing.T) {
+	t.Parallel()
+
+	rec := httptest.NewRecorder()
+	m := New(Config{Store: new(writerTestStore)})
+	w := &scopedManager{
+		Manager:        m,
+		ResponseWriter: rec,
+		r:              httptest.NewRequest(http.MethodGet, "/", nil),
+		storage:        make(map[string]*Session),
+	}
+	s, _ := w.get("sess")
+	s.Set("a", 1)
+
+	w.Flush()
+	assert.True(t, rec.Flushed)
+	assert.Len(t, rec.Result().Cookies(), 1, "expected session saved before flush")
+}
+
+type writerTestStore struct{}
+
+func (writerTestStore) Get(ctx context.Context, key string) (Data, error) {
+	return nil, ErrNotFound
+}
+
+func (writerTestStore) Set(ctx context.Context, key string, value Data, opt StoreOption) error {
+	return nil
+}
+
+func (writerTestStore) Del(ctx context.Context, key string) error {
+	return nil
+}
+
+func TestWriterUnwrap(t *testing.T) {
+	t.Parallel()
+
+	rec := httptest.NewRecorder()
+	w := &scopedManager{ResponseWriter: rec}
+	assert.Equal(t, rec, w.Unwrap())
+}